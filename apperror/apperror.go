@@ -0,0 +1,88 @@
+package apperror
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/bmdavis419/fiber-mongo-example/validation"
+)
+
+// Error is an error that should reach the client, Detail is safe to show while Err holds the
+// internal cause, which is only logged
+type Error struct {
+	Status     int
+	Detail     string
+	Violations []validation.Violation
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Status, e.Detail, e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.Detail)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Title is the standard text of the status
+func (e *Error) Title() string {
+	return http.StatusText(e.Status)
+}
+
+// Kind is what an error of a lower package, like the repository, means for the client
+type Kind int
+
+const (
+	KindBadRequest Kind = iota + 1
+	KindNotFound
+	KindConflict
+)
+
+// kindError is a sentinel error created by New
+type kindError struct {
+	kind Kind
+	text string
+}
+
+func (e *kindError) Error() string {
+	return e.text
+}
+
+// New creates a sentinel error of kind for packages that don't answer requests themselves, like repository.ErrNotFound.
+// From answers it with the status of kind and its text as detail, apart from not found errors that get a generic one.
+func New(kind Kind, text string) error {
+	return &kindError{kind: kind, text: text}
+}
+
+func BadRequest(detail string) *Error {
+	return &Error{Status: http.StatusBadRequest, Detail: detail}
+}
+
+func Unauthorized(detail string) *Error {
+	return &Error{Status: http.StatusUnauthorized, Detail: detail}
+}
+
+func Forbidden(detail string) *Error {
+	return &Error{Status: http.StatusForbidden, Detail: detail}
+}
+
+func NotFound(detail string) *Error {
+	return &Error{Status: http.StatusNotFound, Detail: detail}
+}
+
+func Conflict(detail string) *Error {
+	return &Error{Status: http.StatusConflict, Detail: detail}
+}
+
+// Validation lists every broken rule so clients can show them next to their inputs
+func Validation(detail string, violations []validation.Violation) *Error {
+	return &Error{Status: http.StatusUnprocessableEntity, Detail: detail, Violations: violations}
+}
+
+// Internal hides err from the client behind a generic message
+func Internal(err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Detail: "An unexpected error occurred", Err: err}
+}
@@ -0,0 +1,93 @@
+package apperror
+
+import (
+	"errors"
+
+	"github.com/bmdavis419/fiber-mongo-example/requestid"
+	"github.com/bmdavis419/fiber-mongo-example/validation"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/exp/slog"
+)
+
+// Problem is an RFC 7807 problem details body, RequestID is the X-Request-ID of the request to quote when reporting it
+type Problem struct {
+	Type       string                 `json:"type"`
+	Title      string                 `json:"title"`
+	Status     int                    `json:"status"`
+	Detail     string                 `json:"detail,omitempty"`
+	Instance   string                 `json:"instance,omitempty"`
+	Violations []validation.Violation `json:"violations,omitempty"`
+	RequestID  string                 `json:"requestId,omitempty"`
+}
+
+const MIMEProblemJSON = "application/problem+json"
+
+// errorKey holds the error Middleware wrote
+const errorKey = "error"
+
+// Handler is the fiber.Config ErrorHandler, it turns every error returned by a handler into a problem+json response
+func Handler(c *fiber.Ctx, err error) error {
+	e := From(err)
+	if e.Status >= 500 {
+		slog.ErrorCtx(c.UserContext(), "request failed", "method", c.Method(), "path", c.Path(), "err", err)
+	}
+
+	err = c.Status(e.Status).JSON(Problem{
+		Type:       "about:blank",
+		Title:      e.Title(),
+		Status:     e.Status,
+		Detail:     e.Detail,
+		Instance:   c.OriginalURL(),
+		Violations: e.Violations,
+		RequestID:  requestid.From(c.UserContext()),
+	})
+	c.Set(fiber.HeaderContentType, MIMEProblemJSON)
+	return err
+}
+
+// Middleware writes the errors of the handlers after it with the error handler of the app, so the middleware before
+// it sees the status that is sent. It goes after the logging, metrics and tracing middleware, which find the error
+// with Written.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		if err == nil {
+			return nil
+		}
+
+		c.Locals(errorKey, err)
+		if err := c.App().ErrorHandler(c, err); err != nil {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		return nil
+	}
+}
+
+// Written returns the error of the handlers Middleware wrote, nil when there was none
+func Written(c *fiber.Ctx) error {
+	err, _ := c.Locals(errorKey).(error)
+	return err
+}
+
+// From converts any error into an *Error, the errors created with New are mapped to the status of their kind
+func From(err error) *Error {
+	var e *Error
+	var fe *fiber.Error
+	var ke *kindError
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.As(err, &fe):
+		return &Error{Status: fe.Code, Detail: fe.Message}
+	case errors.As(err, &ke):
+		switch ke.kind {
+		case KindNotFound:
+			return NotFound("The requested resource does not exist")
+		case KindConflict:
+			return Conflict(ke.text)
+		case KindBadRequest:
+			return BadRequest(ke.text)
+		}
+	}
+	return Internal(err)
+}
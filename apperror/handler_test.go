@@ -0,0 +1,39 @@
+package apperror_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/bmdavis419/fiber-mongo-example/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		detail string
+	}{
+		{"app error", apperror.Forbidden("not yours"), 403, "not yours"},
+		{"fiber error", fiber.ErrMethodNotAllowed, 405, "Method Not Allowed"},
+		{"not found", repository.ErrNotFound, 404, "The requested resource does not exist"},
+		{"wrapped", fmt.Errorf("get product: %w", repository.ErrNotFound), 404, "The requested resource does not exist"},
+		{"object not found", storage.ErrNotFound, 404, "The requested resource does not exist"},
+		{"invalid id", repository.ErrInvalidID, 400, "invalid id"},
+		{"invalid cursor", repository.ErrInvalidCursor, 400, "invalid cursor"},
+		{"duplicate", repository.ErrDuplicate, 409, "document already exists"},
+		{"fully booked", repository.ErrFullyBooked, 409, repository.ErrFullyBooked.Error()},
+		{"anything else", errors.New("connection reset"), 500, "An unexpected error occurred"},
+	}
+
+	for _, tt := range tests {
+		got := apperror.From(tt.err)
+		if got.Status != tt.status || got.Detail != tt.detail {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, got.Status, got.Detail, tt.status, tt.detail)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Package audit records the changes made through the api to the audit log: who made them, what they changed and the
+// request they were made by.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/auth"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/bmdavis419/fiber-mongo-example/requestid"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/exp/slog"
+)
+
+// Log appends the entries of the audit log to repo
+type Log struct {
+	repo repository.AuditRepository
+}
+
+func New(repo repository.AuditRepository) *Log {
+	return &Log{repo: repo}
+}
+
+// record appends the change of a resource, before and after are the resource as the api returns it, nil when it
+// didn't exist. The change is already made, so an entry that can't be written is logged and not returned, and an
+// update that changed nothing isn't recorded.
+func (l *Log) record(ctx context.Context, action models.AuditAction, resource string, id string, before interface{}, after interface{}) {
+	changes, err := diff(before, after)
+	if err != nil {
+		slog.ErrorCtx(ctx, "audit entry lost", "resource", resource, "resourceId", id, "action", action, "err", err)
+		return
+	}
+	if action == models.AuditUpdate && len(changes) == 0 {
+		return
+	}
+	if id == "" {
+		id = idOf(changes)
+	}
+
+	entry := &models.AuditEntry{
+		Time:       time.Now().UTC(),
+		Actor:      models.AuditAnonymous,
+		Action:     action,
+		Resource:   resource,
+		ResourceID: id,
+		Changes:    changes,
+		RequestID:  requestid.From(ctx),
+	}
+	if user := auth.UserFrom(ctx); user != nil {
+		entry.Actor = user.UserID()
+		entry.ActorRole = user.Role
+	}
+
+	if err := l.repo.Create(ctx, entry); err != nil {
+		slog.ErrorCtx(ctx, "audit entry lost", "resource", resource, "resourceId", id, "action", action, "err", err)
+	}
+}
+
+// diff lists the fields whose value differs between before and after. The fields are the ones of the JSON api, so
+// the entries use the names clients know and leave out what the api hides, like password hashes.
+func diff(before interface{}, after interface{}) (bson.M, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := bson.M{}
+	for key, value := range b {
+		if other, ok := a[key]; !ok || !reflect.DeepEqual(value, other) {
+			changes[key] = bson.M{"before": value, "after": other}
+		}
+	}
+	for key, value := range a {
+		if _, ok := b[key]; !ok {
+			changes[key] = bson.M{"before": nil, "after": value}
+		}
+	}
+	return changes, nil
+}
+
+func fields(v interface{}) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	if v == nil {
+		return m, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return m, json.Unmarshal(data, &m)
+}
+
+// idOf finds the id of a created resource in its changes, the resources name it "id" or "_id"
+func idOf(changes bson.M) string {
+	for _, key := range []string{"id", "_id"} {
+		if change, ok := changes[key].(bson.M); ok {
+			if id, ok := change["after"].(string); ok {
+				return id
+			}
+		}
+	}
+	return ""
+}
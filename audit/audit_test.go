@@ -0,0 +1,240 @@
+package audit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// entries lists the audit log of repos, oldest first
+func entries(t *testing.T, repos *repository.Repositories) []models.AuditEntry {
+	t.Helper()
+	page, err := repos.Audit.List(context.Background(), repository.ListOptions{Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page.Items
+}
+
+func TestDiff(t *testing.T) {
+	book := models.Book{ID: "1", Title: "Go", Author: "Rob", Year: "2015"}
+	renamed := book
+	renamed.Title = "Go 2"
+
+	tests := []struct {
+		name   string
+		before interface{}
+		after  interface{}
+		want   bson.M
+	}{
+		{"nothing changed", book, book, bson.M{}},
+		{"one field", book, renamed, bson.M{"title": bson.M{"before": "Go", "after": "Go 2"}}},
+		{"created", nil, models.Book{ID: "1"}, bson.M{
+			"id":     bson.M{"before": nil, "after": "1"},
+			"title":  bson.M{"before": nil, "after": ""},
+			"author": bson.M{"before": nil, "after": ""},
+			"year":   bson.M{"before": nil, "after": ""},
+		}},
+		{"deleted", models.Book{ID: "1"}, nil, bson.M{
+			"id":     bson.M{"before": "1", "after": nil},
+			"title":  bson.M{"before": "", "after": nil},
+			"author": bson.M{"before": "", "after": nil},
+			"year":   bson.M{"before": "", "after": nil},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diff(tt.before, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLeavesOutHiddenFields(t *testing.T) {
+	created := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	before := models.User{ID: "1", Email: "a@b.io", PasswordHash: "old", Role: models.RoleBuyer, CreatedAt: created}
+	after := before
+	after.PasswordHash = "new"
+
+	changes, err := diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("a password change is recorded as %v", changes)
+	}
+
+	changes, err = diff(nil, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key := range changes {
+		if key == "passwordHash" || key == "PasswordHash" {
+			t.Errorf("the created user is recorded with %s", key)
+		}
+	}
+	if _, ok := changes["email"]; !ok {
+		t.Errorf("the created user is recorded without its email: %v", changes)
+	}
+}
+
+func TestIdOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes bson.M
+		want    string
+	}{
+		{"id", bson.M{"id": bson.M{"before": nil, "after": "1"}}, "1"},
+		{"_id", bson.M{"_id": bson.M{"before": nil, "after": "2"}}, "2"},
+		{"id first", bson.M{"id": bson.M{"after": "1"}, "_id": bson.M{"after": "2"}}, "1"},
+		{"not a string", bson.M{"id": bson.M{"after": 1.0}}, ""},
+		{"no id", bson.M{"title": bson.M{"after": "Go"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := idOf(tt.changes); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrack(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemory()
+	Track(repos, New(repos.Audit))
+
+	book := models.Book{Title: "Go", Author: "Rob", Year: "2015"}
+	if err := repos.Books.Create(ctx, &book); err != nil {
+		t.Fatal(err)
+	}
+
+	// the same title again changes nothing, so only the create and the new year are recorded
+	if _, err := repos.Books.Update(ctx, book.ID, &models.BookUpdate{Title: "Go"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Books.Update(ctx, book.ID, &models.BookUpdate{Year: "2016"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Books.Delete(ctx, book.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	got := entries(t, repos)
+	want := []models.AuditAction{models.AuditCreate, models.AuditUpdate, models.AuditDelete}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i, entry := range got {
+		if entry.Action != want[i] || entry.Resource != "books" || entry.ResourceID != book.ID {
+			t.Errorf("entry %d is %s %s/%s, want %s books/%s", i, entry.Action, entry.Resource, entry.ResourceID, want[i], book.ID)
+		}
+		if entry.Actor != models.AuditAnonymous {
+			t.Errorf("entry %d is by %s, want %s", i, entry.Actor, models.AuditAnonymous)
+		}
+	}
+	if len(got) == 3 {
+		if _, ok := got[1].Changes["year"]; !ok || len(got[1].Changes) != 1 {
+			t.Errorf("the update records %v, want only the year", got[1].Changes)
+		}
+	}
+}
+
+// racing writes the author of a book just before each update, like another request changing it at the same time
+type racing struct {
+	repository.BookRepository
+}
+
+func (r *racing) Update(ctx context.Context, id string, update *models.BookUpdate) (models.Book, error) {
+	if _, err := r.BookRepository.Update(context.Background(), id, &models.BookUpdate{Author: "Ken"}); err != nil {
+		return models.Book{}, err
+	}
+	return r.BookRepository.Update(ctx, id, update)
+}
+
+func TestTrackRacingUpdate(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemory()
+	repos.Books = &racing{repos.Books}
+	Track(repos, New(repos.Audit))
+
+	book := models.Book{Title: "Go", Author: "Rob", Year: "2015"}
+	if err := repos.Books.Create(ctx, &book); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Books.Update(ctx, book.ID, &models.BookUpdate{Year: "2016"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the author was changed by the other request, the entry of this one only holds the year
+	got := entries(t, repos)
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+	want := bson.M{"year": bson.M{"before": "2015", "after": "2016"}}
+	if !reflect.DeepEqual(got[1].Changes, want) {
+		t.Errorf("the update records %v, want %v", got[1].Changes, want)
+	}
+}
+
+func TestTrackTransition(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemory()
+	Track(repos, New(repos.Audit))
+
+	enquiry := models.GenerateEnquiry{Status: models.StatusRequested}
+	if err := repos.Enquiries.Create(ctx, &enquiry); err != nil {
+		t.Fatal(err)
+	}
+	change := models.StatusChange{From: models.StatusRequested, To: models.StatusRejected, By: "admin", At: time.Now().UTC()}
+	if err := repos.Enquiries.Transition(ctx, enquiry.ID, change); err != nil {
+		t.Fatal(err)
+	}
+
+	got := entries(t, repos)
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+	status, ok := got[1].Changes["status"].(bson.M)
+	if !ok || status["before"] != string(models.StatusRequested) || status["after"] != string(models.StatusRejected) {
+		t.Errorf("the transition records %v", got[1].Changes)
+	}
+	if _, ok := got[1].Changes["statusHistory"]; !ok {
+		t.Errorf("the transition records %v, want the history as well", got[1].Changes)
+	}
+}
+
+func TestTrackUserWithoutPassword(t *testing.T) {
+	repos := repository.NewMemory()
+	Track(repos, New(repos.Audit))
+
+	user := models.User{Email: "a@b.io", PasswordHash: "hash", Role: models.RoleBuyer}
+	if err := repos.Users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+
+	got := entries(t, repos)
+	if len(got) != 1 {
+		t.Fatalf("got %d entries, want 1", len(got))
+	}
+	if got[0].ResourceID != user.ID {
+		t.Errorf("the entry is for %q, want %q", got[0].ResourceID, user.ID)
+	}
+	for key := range got[0].Changes {
+		if key == "passwordHash" || key == "PasswordHash" {
+			t.Errorf("the entry records %s", key)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+)
+
+// Track wraps the repositories of repos so the changes made through them are recorded to log. The writes that follow
+// from another change, like the rating of a product after a review or the bookings of the calendar after an
+// enquiry is booked, aren't recorded themselves, the change causing them is.
+func Track(repos *repository.Repositories, log *Log) {
+	repos.Books = &bookRepository{repos.Books, tracked[models.Book]{log, "books"}}
+	repos.Products = &productRepository{repos.Products, tracked[models.Product]{log, "products"}}
+	repos.Transports = &transportRepository{repos.Transports, tracked[models.Transport]{log, "transports"}}
+	repos.Enquiries = &enquiryRepository{repos.Enquiries, tracked[models.GenerateEnquiry]{log, "enquiries"}}
+	repos.Queries = &queryRepository{repos.Queries, tracked[models.Query]{log, "queries"}}
+	repos.Users = &userRepository{repos.Users, tracked[models.User]{log, "users"}}
+	repos.Reviews = &reviewRepository{repos.Reviews, tracked[models.Review]{log, "reviews"}}
+	repos.Calendar = &calendarRepository{repos.Calendar, tracked[models.CalendarDay]{log, "calendar"}}
+}
+
+// tracked records the changes of one resource. The repositories pass the resource as it was before and after a
+// change from the operation making it, so the entry of a change never holds the changes of another request.
+type tracked[T any] struct {
+	log      *Log
+	resource string
+}
+
+func (t tracked[T]) create(ctx context.Context, item *T, create func() error) error {
+	if err := create(); err != nil {
+		return err
+	}
+	t.log.record(ctx, models.AuditCreate, t.resource, "", nil, item)
+	return nil
+}
+
+func (t tracked[T]) update(ctx context.Context, id string, update func(ctx context.Context) (T, error)) (T, error) {
+	var after T
+	err := t.change(ctx, id, func(ctx context.Context) error {
+		var err error
+		after, err = update(ctx)
+		return err
+	})
+	return after, err
+}
+
+// change is update for the changes that don't return the resource
+func (t tracked[T]) change(ctx context.Context, id string, change func(ctx context.Context) error) error {
+	var before, after interface{}
+	err := change(repository.OnChange(ctx, func(b interface{}, a interface{}) { before, after = b, a }))
+	if err != nil {
+		return err
+	}
+	t.log.record(ctx, models.AuditUpdate, t.resource, id, before, after)
+	return nil
+}
+
+func (t tracked[T]) delete(ctx context.Context, id string, del func(ctx context.Context) error) error {
+	var before interface{}
+	err := del(repository.OnChange(ctx, func(b interface{}, _ interface{}) { before = b }))
+	if err != nil {
+		return err
+	}
+	t.log.record(ctx, models.AuditDelete, t.resource, id, before, nil)
+	return nil
+}
+
+type bookRepository struct {
+	repository.BookRepository
+	tracked tracked[models.Book]
+}
+
+func (r *bookRepository) Create(ctx context.Context, book *models.Book) error {
+	return r.tracked.create(ctx, book, func() error { return r.BookRepository.Create(ctx, book) })
+}
+
+func (r *bookRepository) Update(ctx context.Context, id string, update *models.BookUpdate) (models.Book, error) {
+	return r.tracked.update(ctx, id, func(ctx context.Context) (models.Book, error) { return r.BookRepository.Update(ctx, id, update) })
+}
+
+func (r *bookRepository) Delete(ctx context.Context, id string) error {
+	return r.tracked.delete(ctx, id, func(ctx context.Context) error { return r.BookRepository.Delete(ctx, id) })
+}
+
+type productRepository struct {
+	repository.ProductRepository
+	tracked tracked[models.Product]
+}
+
+func (r *productRepository) Create(ctx context.Context, product *models.Product) error {
+	return r.tracked.create(ctx, product, func() error { return r.ProductRepository.Create(ctx, product) })
+}
+
+func (r *productRepository) Update(ctx context.Context, id string, update *models.UpdatePTO) (models.Product, error) {
+	return r.tracked.update(ctx, id, func(ctx context.Context) (models.Product, error) { return r.ProductRepository.Update(ctx, id, update) })
+}
+
+func (r *productRepository) Delete(ctx context.Context, id string) error {
+	return r.tracked.delete(ctx, id, func(ctx context.Context) error { return r.ProductRepository.Delete(ctx, id) })
+}
+
+type transportRepository struct {
+	repository.TransportRepository
+	tracked tracked[models.Transport]
+}
+
+func (r *transportRepository) Create(ctx context.Context, transport *models.Transport) error {
+	return r.tracked.create(ctx, transport, func() error { return r.TransportRepository.Create(ctx, transport) })
+}
+
+func (r *transportRepository) Update(ctx context.Context, id string, update *models.TransportUpdate) (models.Transport, error) {
+	return r.tracked.update(ctx, id, func(ctx context.Context) (models.Transport, error) {
+		return r.TransportRepository.Update(ctx, id, update)
+	})
+}
+
+func (r *transportRepository) Delete(ctx context.Context, id string) error {
+	return r.tracked.delete(ctx, id, func(ctx context.Context) error { return r.TransportRepository.Delete(ctx, id) })
+}
+
+type enquiryRepository struct {
+	repository.EnquiryRepository
+	tracked tracked[models.GenerateEnquiry]
+}
+
+func (r *enquiryRepository) Create(ctx context.Context, enquiry *models.GenerateEnquiry) error {
+	return r.tracked.create(ctx, enquiry, func() error { return r.EnquiryRepository.Create(ctx, enquiry) })
+}
+
+func (r *enquiryRepository) Update(ctx context.Context, id string, update *models.EnquiryUpdate) (models.GenerateEnquiry, error) {
+	return r.tracked.update(ctx, id, func(ctx context.Context) (models.GenerateEnquiry, error) {
+		return r.EnquiryRepository.Update(ctx, id, update)
+	})
+}
+
+func (r *enquiryRepository) UpdateWhile(ctx context.Context, id string, statuses []models.EnquiryStatus, update *models.EnquiryUpdate) (models.GenerateEnquiry, error) {
+	return r.tracked.update(ctx, id, func(ctx context.Context) (models.GenerateEnquiry, error) {
+		return r.EnquiryRepository.UpdateWhile(ctx, id, statuses, update)
+	})
+}
+
+func (r *enquiryRepository) Delete(ctx context.Context, id string) error {
+	return r.tracked.delete(ctx, id, func(ctx context.Context) error { return r.EnquiryRepository.Delete(ctx, id) })
+}
+
+func (r *enquiryRepository) Transition(ctx context.Context, id string, change models.StatusChange) error {
+	return r.tracked.change(ctx, id, func(ctx context.Context) error { return r.EnquiryRepository.Transition(ctx, id, change) })
+}
+
+func (r *enquiryRepository) AddQuote(ctx context.Context, id string, quote models.Quote, change models.StatusChange) error {
+	return r.tracked.change(ctx, id, func(ctx context.Context) error { return r.EnquiryRepository.AddQuote(ctx, id, quote, change) })
+}
+
+func (r *enquiryRepository) AcceptQuote(ctx context.Context, id string, version int, change models.StatusChange) error {
+	return r.tracked.change(ctx, id, func(ctx context.Context) error { return r.EnquiryRepository.AcceptQuote(ctx, id, version, change) })
+}
+
+type queryRepository struct {
+	repository.QueryRepository
+	tracked tracked[models.Query]
+}
+
+func (r *queryRepository) Create(ctx context.Context, query *models.Query) error {
+	return r.tracked.create(ctx, query, func() error { return r.QueryRepository.Create(ctx, query) })
+}
+
+func (r *queryRepository) Delete(ctx context.Context, id string) error {
+	return r.tracked.delete(ctx, id, func(ctx context.Context) error { return r.QueryRepository.Delete(ctx, id) })
+}
+
+type userRepository struct {
+	repository.UserRepository
+	tracked tracked[models.User]
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	return r.tracked.create(ctx, user, func() error { return r.UserRepository.Create(ctx, user) })
+}
+
+type reviewRepository struct {
+	repository.ReviewRepository
+	tracked tracked[models.Review]
+}
+
+func (r *reviewRepository) Create(ctx context.Context, review *models.Review) error {
+	return r.tracked.create(ctx, review, func() error { return r.ReviewRepository.Create(ctx, review) })
+}
+
+type calendarRepository struct {
+	repository.CalendarRepository
+	tracked tracked[models.CalendarDay]
+}
+
+func (r *calendarRepository) SetDay(ctx context.Context, transportId string, date string, update *models.CalendarDayUpdate) (models.CalendarDay, error) {
+	// a day is identified by its transport and date, like "6390b0c6f1d7a1b2c3d4e5f6/2024-05-01"
+	return r.tracked.update(ctx, transportId+"/"+date, func(ctx context.Context) (models.CalendarDay, error) {
+		return r.CalendarRepository.SetDay(ctx, transportId, date, update)
+	})
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+)
+
+// EnsureAdmin creates the admin account on first start, an existing user with that email is left alone
+func EnsureAdmin(ctx context.Context, users repository.UserRepository, email string, password string) error {
+	if email == "" {
+		return nil
+	}
+	if len(password) < 8 {
+		return errors.New("'ADMIN_PASSWORD' must be at least 8 characters")
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	err = users.Create(ctx, &models.User{
+		Email:        email,
+		PasswordHash: hash,
+		Role:         models.RoleAdmin,
+		CreatedAt:    time.Now().UTC().Truncate(time.Millisecond),
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+const userKey = "user"
+
+type claimsKey struct{}
+
+// Protect only lets requests with a valid access token through, when roles are given the user must have one of them.
+// The claims are also put in the user context of the request for the code that only gets a context.
+func Protect(tokens *Tokens, roles ...models.Role) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		header := c.Get(fiber.HeaderAuthorization)
+		token := strings.TrimPrefix(header, "Bearer ")
+		if header == "" || token == header {
+			return apperror.Unauthorized("missing bearer token")
+		}
+
+		claims, err := tokens.ParseAccess(token)
+		if err != nil {
+			return apperror.Unauthorized(err.Error())
+		}
+
+		if len(roles) > 0 && !hasRole(claims.Role, roles) {
+			return apperror.Forbidden("your role is not allowed to do this")
+		}
+
+		c.Locals(userKey, claims)
+		c.SetUserContext(context.WithValue(c.UserContext(), claimsKey{}, claims))
+		return c.Next()
+	}
+}
+
+func hasRole(role models.Role, roles []models.Role) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentUser returns the claims of the user authenticated by Protect, or nil on unprotected routes
+func CurrentUser(c *fiber.Ctx) *Claims {
+	claims, _ := c.Locals(userKey).(*Claims)
+	return claims
+}
+
+// UserFrom returns the claims of the user authenticated by Protect from the context of the request, or nil
+func UserFrom(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*Claims)
+	return claims
+}
+
+// CanModify reports whether the current user owns a resource, admins can modify everything
+func CanModify(c *fiber.Ctx, ownerID string) bool {
+	user := CurrentUser(c)
+	if user == nil {
+		return false
+	}
+	return user.Role == models.RoleAdmin || (ownerID != "" && user.UserID() == ownerID)
+}
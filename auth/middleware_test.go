@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// request sends GET path with header as Authorization and returns the status
+func request(t *testing.T, app *fiber.App, path string, header string) int {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func bearer(t *testing.T, tokens *Tokens, id string, role models.Role) (string, string) {
+	t.Helper()
+	pair, err := tokens.Issue(models.User{ID: id, Role: role})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + pair.AccessToken, "Bearer " + pair.RefreshToken
+}
+
+func TestProtect(t *testing.T) {
+	tokens := newTokens(t, secret)
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.Handler})
+	app.Get("/any", Protect(tokens), func(c *fiber.Ctx) error {
+		if CurrentUser(c) == nil || UserFrom(c.UserContext()) != CurrentUser(c) {
+			return c.SendStatus(500)
+		}
+		return c.SendStatus(200)
+	})
+	app.Get("/sellers", Protect(tokens, models.RoleSeller, models.RoleAdmin), func(c *fiber.Ctx) error {
+		return c.SendStatus(200)
+	})
+
+	seller, sellerRefresh := bearer(t, tokens, "1", models.RoleSeller)
+	buyer, _ := bearer(t, tokens, "2", models.RoleBuyer)
+	admin, _ := bearer(t, tokens, "3", models.RoleAdmin)
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		status int
+	}{
+		{"no header", "/any", "", 401},
+		{"not bearer", "/any", "Basic dXNlcjpwYXNz", 401},
+		{"bearer without token", "/any", "Bearer ", 401},
+		{"garbage", "/any", "Bearer abc", 401},
+		{"refresh token", "/any", sellerRefresh, 401},
+		{"any role", "/any", buyer, 200},
+		{"allowed role", "/sellers", seller, 200},
+		{"admin", "/sellers", admin, 200},
+		{"other role", "/sellers", buyer, 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := request(t, app, tt.path, tt.header); status != tt.status {
+				t.Errorf("got %d, want %d", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestCanModify(t *testing.T) {
+	tokens := newTokens(t, secret)
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.Handler})
+	canModify := func(c *fiber.Ctx) error {
+		return c.SendString(strconv.FormatBool(CanModify(c, c.Query("owner"))))
+	}
+	app.Get("/open", canModify)
+	app.Get("/protected", Protect(tokens), canModify)
+
+	owner, _ := bearer(t, tokens, "1", models.RoleSeller)
+	admin, _ := bearer(t, tokens, "3", models.RoleAdmin)
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   string
+	}{
+		{"owner", "/protected?owner=1", owner, "true"},
+		{"someone else", "/protected?owner=2", owner, "false"},
+		{"no owner", "/protected", owner, "false"},
+		{"admin", "/protected?owner=2", admin, "true"},
+		{"admin without owner", "/protected", admin, "true"},
+		{"not logged in", "/open?owner=1", owner, "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Authorization", tt.header)
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(body); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
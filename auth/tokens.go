@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+const (
+	accessToken  = "access"
+	refreshToken = "refresh"
+)
+
+// ErrInvalidToken is returned for tokens that are malformed, expired, badly signed or of the wrong type
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims is the content of both access and refresh tokens, the subject is the user id
+type Claims struct {
+	Role models.Role `json:"role"`
+	Type string      `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the id of the user the token was issued to
+func (c *Claims) UserID() string {
+	return c.Subject
+}
+
+// TokenPair is returned by login and refresh
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"`
+}
+
+// Tokens signs and verifies HS256 tokens with a shared secret
+type Tokens struct {
+	secret []byte
+}
+
+func NewTokens(secret string) (*Tokens, error) {
+	if len(secret) < 32 {
+		return nil, errors.New("you must set your 'JWT_SECRET' environmental variable to at least 32 characters")
+	}
+	return &Tokens{secret: []byte(secret)}, nil
+}
+
+// Issue creates a new access and refresh token for user
+func (t *Tokens) Issue(user models.User) (TokenPair, error) {
+	access, err := t.sign(user, accessToken, AccessTokenTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := t.sign(user, refreshToken, RefreshTokenTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+func (t *Tokens) sign(user models.User, typ string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		Role: user.Role,
+		Type: typ,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret)
+}
+
+// ParseAccess verifies an access token
+func (t *Tokens) ParseAccess(token string) (*Claims, error) {
+	return t.parse(token, accessToken)
+}
+
+// ParseRefresh verifies a refresh token
+func (t *Tokens) ParseRefresh(token string) (*Claims, error) {
+	return t.parse(token, refreshToken)
+}
+
+func (t *Tokens) parse(token string, typ string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return t.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || claims.Type != typ || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const secret = "a-secret-of-at-least-32-characters!"
+
+func newTokens(t *testing.T, secret string) *Tokens {
+	t.Helper()
+	tokens, err := NewTokens(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+// signed makes a token of claims with method and key, for the tokens Issue wouldn't make
+func signed(t *testing.T, method jwt.SigningMethod, key interface{}, claims Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func claimsOf(typ string, subject string, expires time.Time) Claims {
+	return Claims{
+		Role: models.RoleBuyer,
+		Type: typ,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(expires.Add(-time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}
+}
+
+func TestNewTokensNeedsALongSecret(t *testing.T) {
+	if _, err := NewTokens("too-short"); err == nil {
+		t.Error("a secret of 9 characters was accepted")
+	}
+}
+
+func TestIssue(t *testing.T) {
+	tokens := newTokens(t, secret)
+	user := models.User{ID: "6390b0c6f1d7a1b2c3d4e5f6", Role: models.RoleTransporter}
+
+	pair, err := tokens.Issue(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.ExpiresIn != int(AccessTokenTTL.Seconds()) {
+		t.Errorf("expiresIn is %d", pair.ExpiresIn)
+	}
+
+	access, err := tokens.ParseAccess(pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if access.UserID() != user.ID || access.Role != user.Role {
+		t.Errorf("access token is for %s %s", access.UserID(), access.Role)
+	}
+	if ttl := access.ExpiresAt.Sub(access.IssuedAt.Time); ttl != AccessTokenTTL {
+		t.Errorf("access token lasts %s, want %s", ttl, AccessTokenTTL)
+	}
+
+	refresh, err := tokens.ParseRefresh(pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := refresh.ExpiresAt.Sub(refresh.IssuedAt.Time); ttl != RefreshTokenTTL {
+		t.Errorf("refresh token lasts %s, want %s", ttl, RefreshTokenTTL)
+	}
+}
+
+func TestTokenTypes(t *testing.T) {
+	tokens := newTokens(t, secret)
+	pair, err := tokens.Issue(models.User{ID: "1", Role: models.RoleBuyer})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tokens.ParseAccess(pair.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("refresh token used as access token: got %v", err)
+	}
+	if _, err := tokens.ParseRefresh(pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("access token used as refresh token: got %v", err)
+	}
+}
+
+func TestInvalidTokens(t *testing.T) {
+	tokens := newTokens(t, secret)
+	later := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expired", signed(t, jwt.SigningMethodHS256, []byte(secret), claimsOf(accessToken, "1", time.Now().Add(-time.Second)))},
+		{"other key", signed(t, jwt.SigningMethodHS256, []byte("another-secret-of-32-characters!!"), claimsOf(accessToken, "1", later))},
+		{"HS512", signed(t, jwt.SigningMethodHS512, []byte(secret), claimsOf(accessToken, "1", later))},
+		{"none", signed(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claimsOf(accessToken, "1", later))},
+		{"no type", signed(t, jwt.SigningMethodHS256, []byte(secret), claimsOf("", "1", later))},
+		{"no subject", signed(t, jwt.SigningMethodHS256, []byte(secret), claimsOf(accessToken, "", later))},
+		{"malformed", "not.a.token"},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if claims, err := tokens.ParseAccess(tt.token); !errors.Is(err, ErrInvalidToken) || claims != nil {
+				t.Errorf("got %+v, %v, want ErrInvalidToken", claims, err)
+			}
+		})
+	}
+
+	// the same claims signed properly are fine
+	if _, err := tokens.ParseAccess(signed(t, jwt.SigningMethodHS256, []byte(secret), claimsOf(accessToken, "1", later))); err != nil {
+		t.Errorf("valid token: %v", err)
+	}
+}
+
+func TestPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CheckPassword(hash, "correct horse") {
+		t.Error("the password doesn't match its hash")
+	}
+	if CheckPassword(hash, "wrong horse") {
+		t.Error("another password matches the hash")
+	}
+}
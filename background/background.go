@@ -0,0 +1,75 @@
+// Package background runs work that outlives the request that started it, like removing files from the object store,
+// so the response doesn't wait for it, and lets the server wait for that work before it stops.
+package background
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/exp/slog"
+)
+
+// Tasks tracks the running background work
+type Tasks struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+func New() *Tasks {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Tasks{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in the background, errors are logged with name. The context of fn is not the one of the request, which
+// ends with the response, it is cancelled when Wait gives up. Once Wait was called fn runs before Go returns so the
+// work of the requests still draining isn't lost.
+func (t *Tasks) Go(name string, fn func(ctx context.Context) error) {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		run(t.ctx, name, fn)
+		return
+	}
+	t.wg.Add(1)
+	t.mu.Unlock()
+
+	go func() {
+		defer t.wg.Done()
+		run(t.ctx, name, fn)
+	}()
+}
+
+func run(ctx context.Context, name string, fn func(ctx context.Context) error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("background task panicked", "task", name, "panic", r)
+		}
+	}()
+	if err := fn(ctx); err != nil {
+		slog.Error("background task failed", "task", name, "err", err)
+	}
+}
+
+// Wait waits for the running tasks until ctx is done, then cancels the context of the ones left and returns ctx.Err()
+func (t *Tasks) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	t.stopped = true
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		t.cancel()
+		return ctx.Err()
+	}
+}
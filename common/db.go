@@ -2,36 +2,48 @@ package common
 
 import (
 	"context"
-	"errors"
-	"os"
 
+	"github.com/bmdavis419/fiber-mongo-example/config"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 var db *mongo.Database
 
+// GetDB returns the database opened by InitDB
+func GetDB() *mongo.Database {
+	return db
+}
+
 // GetDBCollection returns a collection from the database with the given name (col)
 func GetDBCollection(col string) *mongo.Collection {
 	return db.Collection(col)
 }
 
-func InitDB() error {
-	uri := os.Getenv("MONGODB_URI")
-	if uri == "" {
-		return errors.New("you must set your 'MONGODB_URI' environmental variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/#environment-variable")
+// InitDB connects to the database of cfg, opts are applied after the URI, e.g. to add monitors
+func InitDB(cfg config.Mongo, opts ...*options.ClientOptions) error {
+	if err := cfg.Validate(); err != nil {
+		return err
 	}
-	// connect to the database using the uri provided by the user (or the default) context.Background() is used to provide a context for the connection
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+
+	// connect to the database using the uri provided by the user, context.Background() is used to provide a context for the connection
+	opts = append([]*options.ClientOptions{options.Client().ApplyURI(cfg.URI)}, opts...)
+	client, err := mongo.Connect(context.Background(), opts...)
 	if err != nil {
 		return err
 	}
 
-	db = client.Database("go_demo")
+	db = client.Database(cfg.Database)
 
 	return nil
 }
 
+// PingDB checks that the primary of the database opened by InitDB answers
+func PingDB(ctx context.Context) error {
+	return db.Client().Ping(ctx, readpref.Primary())
+}
+
 func CloseDB() error {
 	return db.Client().Disconnect(context.Background())
 }
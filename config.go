@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bmdavis419/fiber-mongo-example/config"
+)
+
+// printConfig prints the effective settings with the secrets redacted, then what is wrong with them, e.g.
+//
+//	CONFIG_FILE=config.yaml go run . config
+func printConfig() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range cfg.Settings() {
+		fmt.Printf("%s=%s\n", s[0], s[1])
+	}
+
+	return cfg.Validate()
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// field is a setting of Config found by each
+type field struct {
+	value  reflect.Value
+	env    []string
+	def    string
+	secret bool
+}
+
+func (f field) name() string {
+	return f.env[0]
+}
+
+func (f field) set(s string) error {
+	if f.value.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil && s != "" {
+			return fmt.Errorf("'%s' is not a duration like 30s or 2m", s)
+		}
+		f.value.SetInt(int64(d))
+		return nil
+	}
+
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			f.value.SetBool(false)
+			return nil
+		}
+		b, err := parseBool(s)
+		if err != nil {
+			return err
+		}
+		f.value.SetBool(b)
+	case reflect.Int:
+		if s == "" {
+			f.value.SetInt(0)
+			return nil
+		}
+		i, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("'%s' is not a whole number", s)
+		}
+		f.value.SetInt(int64(i))
+	case reflect.Float64:
+		if s == "" {
+			f.value.SetFloat(0)
+			return nil
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a number", s)
+		}
+		f.value.SetFloat(n)
+	default:
+		panic("config: unsupported setting type " + f.value.Type().String())
+	}
+	return nil
+}
+
+func (f field) String() string {
+	return fmt.Sprint(f.value.Interface())
+}
+
+// each calls fn with every setting of cfg, nested structs included
+func each(cfg interface{}, fn func(field)) {
+	walk(reflect.ValueOf(cfg).Elem(), fn)
+}
+
+func walk(v reflect.Value, fn func(field)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Type.Kind() == reflect.Struct {
+			walk(v.Field(i), fn)
+			continue
+		}
+		env, ok := sf.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		fn(field{
+			value:  v.Field(i),
+			env:    strings.Split(env, ","),
+			def:    sf.Tag.Get("default"),
+			secret: sf.Tag.Get("secret") == "true",
+		})
+	}
+}
+
+// loadFile reads the YAML file over cfg, settings missing from it keep their value and unknown ones are rejected
+func loadFile(path string, cfg *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
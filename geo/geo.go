@@ -0,0 +1,181 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// earthRadiusKm is the mean radius of the earth
+const earthRadiusKm = 6371.0088
+
+// Point is a GeoJSON point, Coordinates are [longitude, latitude] like MongoDB expects
+type Point struct {
+	Type        string     `json:"type" bson:"type"`
+	Coordinates [2]float64 `json:"coordinates" bson:"coordinates"`
+}
+
+// NewPoint creates a point from a latitude and a longitude, note the order differs from Coordinates
+func NewPoint(lat float64, lng float64) Point {
+	return Point{Type: "Point", Coordinates: [2]float64{lng, lat}}
+}
+
+func (p Point) Lng() float64 { return p.Coordinates[0] }
+func (p Point) Lat() float64 { return p.Coordinates[1] }
+
+// Validate returns an error phrased like a validation message when p isn't a valid GeoJSON point
+func (p Point) Validate() error {
+	switch {
+	case p.Type != "Point":
+		return errors.New(`must be a GeoJSON point with type "Point"`)
+	case p.Lng() < -180 || p.Lng() > 180:
+		return errors.New("must have a longitude between -180 and 180")
+	case p.Lat() < -90 || p.Lat() > 90:
+		return errors.New("must have a latitude between -90 and 90")
+	}
+	return nil
+}
+
+// Polygon is a GeoJSON polygon, the first ring is the outline and the others are holes
+type Polygon struct {
+	Type        string         `json:"type" bson:"type"`
+	Coordinates [][][2]float64 `json:"coordinates" bson:"coordinates"`
+}
+
+// Validate returns an error phrased like a validation message when p isn't a valid GeoJSON polygon
+func (p Polygon) Validate() error {
+	if p.Type != "Polygon" {
+		return errors.New(`must be a GeoJSON polygon with type "Polygon"`)
+	}
+	if len(p.Coordinates) == 0 {
+		return errors.New("must have at least one ring")
+	}
+	for _, ring := range p.Coordinates {
+		if len(ring) < 4 || ring[0] != ring[len(ring)-1] {
+			return errors.New("must have closed rings of at least 4 positions")
+		}
+		for _, pos := range ring {
+			if err := (Point{Type: "Point", Coordinates: pos}).Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Contains reports whether pt is inside the outline and outside the holes of p
+func (p Polygon) Contains(pt Point) bool {
+	if len(p.Coordinates) == 0 || !inRing(p.Coordinates[0], pt) {
+		return false
+	}
+	for _, hole := range p.Coordinates[1:] {
+		if inRing(hole, pt) {
+			return false
+		}
+	}
+	return true
+}
+
+// inRing casts a ray from pt and counts the edges it crosses, areas are small enough to treat lng/lat as flat
+func inRing(ring [][2]float64, pt Point) bool {
+	x, y := pt.Lng(), pt.Lat()
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi, xj, yj := ring[i][0], ring[i][1], ring[j][0], ring[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// DistanceKm is the great-circle distance between a and b
+func DistanceKm(a Point, b Point) float64 {
+	lat1, lat2 := radians(a.Lat()), radians(b.Lat())
+	dLat, dLng := lat2-lat1, radians(b.Lng()-a.Lng())
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// Area is a place a transport serves: a named place matched against addresses, a polygon, or a circle of RadiusKm around Center
+type Area struct {
+	Name     string   `json:"name,omitempty" bson:"name,omitempty"`
+	Polygon  *Polygon `json:"polygon,omitempty" bson:"polygon,omitempty"`
+	Center   *Point   `json:"center,omitempty" bson:"center,omitempty"`
+	RadiusKm float64  `json:"radiusKm,omitempty" bson:"radiusKm,omitempty"`
+}
+
+// Validate returns an error phrased like a validation message when a isn't a usable area
+func (a Area) Validate() error {
+	if a.Name == "" && a.Polygon == nil && a.Center == nil {
+		return errors.New("must have a name, a polygon or a center")
+	}
+	if len(a.Name) > 100 {
+		return errors.New("must have a name of at most 100 characters")
+	}
+	if a.Polygon != nil {
+		if err := a.Polygon.Validate(); err != nil {
+			return fmt.Errorf("polygon %w", err)
+		}
+	}
+	if a.Center != nil {
+		if err := a.Center.Validate(); err != nil {
+			return fmt.Errorf("center %w", err)
+		}
+		if a.RadiusKm <= 0 || a.RadiusKm > 2000 {
+			return errors.New("must have a radiusKm between 0 and 2000 with a center")
+		}
+	}
+	return nil
+}
+
+// Contains reports whether the delivery at address, located at pt when it is known, is inside a
+func (a Area) Contains(pt *Point, address string) bool {
+	if pt != nil && a.Polygon != nil && a.Polygon.Contains(*pt) {
+		return true
+	}
+	if pt != nil && a.Center != nil && DistanceKm(*a.Center, *pt) <= a.RadiusKm {
+		return true
+	}
+	return a.Name != "" && containsWords(address, a.Name)
+}
+
+// containsWords reports whether the words of phrase follow each other in text as whole words, ignoring case and
+// punctuation, so "Pune" is found in "Baner, Pune 411045" but not in "Punekar Road"
+func containsWords(text string, phrase string) bool {
+	words, want := wordsOf(text), wordsOf(phrase)
+	if len(want) == 0 {
+		return false
+	}
+	for i := 0; i+len(want) <= len(words); i++ {
+		found := true
+		for j := range want {
+			if words[i+j] != want[j] {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// wordsOf splits s into lower case runs of letters and digits
+func wordsOf(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Geometric reports whether a can only be matched with a location
+func (a Area) Geometric() bool {
+	return a.Name == "" && (a.Polygon != nil || a.Center != nil)
+}
@@ -0,0 +1,106 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+// square is a polygon of about 11km a side around the center of Pune
+var square = &Polygon{Type: "Polygon", Coordinates: [][][2]float64{{
+	{73.80, 18.47}, {73.90, 18.47}, {73.90, 18.57}, {73.80, 18.57}, {73.80, 18.47},
+}}}
+
+func TestDistanceKm(t *testing.T) {
+	// Mumbai to Pune is about 120km as the crow flies
+	if d := DistanceKm(Cities["mumbai"], Cities["pune"]); math.Abs(d-120) > 5 {
+		t.Errorf("got %.1fkm, want about 120km", d)
+	}
+	if d := DistanceKm(Cities["pune"], Cities["pune"]); d != 0 {
+		t.Errorf("got %v to itself", d)
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	holed := &Polygon{Type: "Polygon", Coordinates: [][][2]float64{
+		square.Coordinates[0],
+		{{73.84, 18.51}, {73.86, 18.51}, {73.86, 18.53}, {73.84, 18.53}, {73.84, 18.51}},
+	}}
+
+	tests := []struct {
+		name    string
+		polygon *Polygon
+		point   Point
+		want    bool
+	}{
+		{"inside", square, NewPoint(18.50, 73.82), true},
+		{"outside", square, NewPoint(18.60, 73.82), false},
+		{"lat and lng swapped", square, NewPoint(73.82, 18.50), false},
+		{"inside the outline, outside the hole", holed, NewPoint(18.50, 73.82), true},
+		{"in the hole", holed, NewPoint(18.52, 73.85), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.polygon.Contains(tt.point); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAreaContains(t *testing.T) {
+	pune := Cities["pune"]
+	mumbai := Cities["mumbai"]
+
+	tests := []struct {
+		name    string
+		area    Area
+		point   *Point
+		address string
+		want    bool
+	}{
+		{"in the polygon", Area{Polygon: square}, &pune, "", true},
+		{"out of the polygon", Area{Polygon: square}, &mumbai, "", false},
+		{"polygon without a location", Area{Polygon: square}, nil, "Baner, Pune", false},
+		{"in the circle", Area{Center: &mumbai, RadiusKm: 150}, &pune, "", true},
+		{"out of the circle", Area{Center: &mumbai, RadiusKm: 100}, &pune, "", false},
+		{"name", Area{Name: "Pune"}, nil, "Baner, pune 411045", true},
+		{"name of several words", Area{Name: "Navi Mumbai"}, nil, "Sector 17, Navi Mumbai", true},
+		{"words of the name apart", Area{Name: "Navi Mumbai"}, nil, "Navi Peth, Mumbai", false},
+		{"name inside a word", Area{Name: "Pune"}, nil, "Punekar Road, Delhi", false},
+		{"name without the address", Area{Name: "Pune"}, &pune, "", false},
+		{"name or polygon", Area{Name: "Mumbai", Polygon: square}, &pune, "Baner", true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.area.Contains(tt.point, tt.address); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStaticGeocode(t *testing.T) {
+	g := NewStatic(map[string]Point{
+		"Pune":        Cities["pune"],
+		"Mumbai":      Cities["mumbai"],
+		"Navi Mumbai": NewPoint(19.0330, 73.0297),
+	})
+
+	tests := []struct {
+		address string
+		want    Point
+		err     error
+	}{
+		{"Baner, PUNE", Cities["pune"], nil},
+		{"Sector 17, Navi Mumbai", NewPoint(19.0330, 73.0297), nil},
+		{"Andheri, Mumbai", Cities["mumbai"], nil},
+		{"Punekar Road, Delhi", Point{}, ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		got, err := g.Geocode(context.Background(), tt.address)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("%q: got %v, %v, want %v, %v", tt.address, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+)
+
+// ErrNotFound is returned when an address can't be located
+var ErrNotFound = errors.New("address not found")
+
+// Geocoder locates addresses
+type Geocoder interface {
+	Geocode(ctx context.Context, address string) (Point, error)
+}
+
+// Static locates addresses with a fixed table of place names, the longest name found in the address as whole words wins
+type Static struct {
+	places map[string]Point
+}
+
+// NewStatic creates a geocoder that knows the given places, names are matched case-insensitively
+func NewStatic(places map[string]Point) *Static {
+	s := &Static{places: map[string]Point{}}
+	for name, p := range places {
+		s.places[strings.ToLower(strings.TrimSpace(name))] = p
+	}
+	return s
+}
+
+// LoadStatic reads a table of place names to [latitude, longitude] from a JSON file, e.g. {"pune": [18.5204, 73.8567]}
+func LoadStatic(path string) (*Static, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	table := map[string][2]float64{}
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, err
+	}
+
+	places := map[string]Point{}
+	for name, latLng := range table {
+		places[name] = NewPoint(latLng[0], latLng[1])
+	}
+	return NewStatic(places), nil
+}
+
+func (s *Static) Geocode(ctx context.Context, address string) (Point, error) {
+	best, found := "", false
+	for name := range s.places {
+		if containsWords(address, name) && len(name) > len(best) {
+			best, found = name, true
+		}
+	}
+	if !found {
+		return Point{}, ErrNotFound
+	}
+
+	return s.places[best], nil
+}
+
+// Cities is the table used when GEOCODER_TABLE isn't set
+var Cities = map[string]Point{
+	"mumbai":    NewPoint(19.0760, 72.8777),
+	"delhi":     NewPoint(28.7041, 77.1025),
+	"bengaluru": NewPoint(12.9716, 77.5946),
+	"bangalore": NewPoint(12.9716, 77.5946),
+	"hyderabad": NewPoint(17.3850, 78.4867),
+	"ahmedabad": NewPoint(23.0225, 72.5714),
+	"chennai":   NewPoint(13.0827, 80.2707),
+	"kolkata":   NewPoint(22.5726, 88.3639),
+	"pune":      NewPoint(18.5204, 73.8567),
+	"jaipur":    NewPoint(26.9124, 75.7873),
+	"lucknow":   NewPoint(26.8467, 80.9462),
+	"kanpur":    NewPoint(26.4499, 80.3319),
+	"nagpur":    NewPoint(21.1458, 79.0882),
+	"indore":    NewPoint(22.7196, 75.8577),
+	"bhopal":    NewPoint(23.2599, 77.4126),
+	"ludhiana":  NewPoint(30.9010, 75.8573),
+	"nashik":    NewPoint(19.9975, 73.7898),
+}
+
+// New returns the static geocoder of the table file, or of Cities when there is none
+func New(table string) (Geocoder, error) {
+	if table != "" {
+		return LoadStatic(table)
+	}
+	return NewStatic(Cities), nil
+}
@@ -0,0 +1,104 @@
+// Package health checks the dependencies of the api for the readiness probe of the orchestrator
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Timeout is how long a check may take before its dependency is reported down
+const Timeout = 2 * time.Second
+
+// Status values of a check and of the whole report
+const (
+	StatusUp           = "up"
+	StatusDown         = "down"
+	StatusShuttingDown = "shutting_down"
+)
+
+// CheckResult is the outcome of checking one dependency
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of every check, Status is up when all of them are and the server isn't shutting down
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Ready reports whether the server can take traffic
+func (r Report) Ready() bool {
+	return r.Status == StatusUp
+}
+
+type check struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Health runs the checks of the dependencies, it is ready until Drain is called
+type Health struct {
+	checks   []check
+	draining atomic.Bool
+}
+
+func New() *Health {
+	return &Health{}
+}
+
+// Add registers the check of a dependency, fn returns an error when it can't be used
+func (h *Health) Add(name string, fn func(ctx context.Context) error) {
+	h.checks = append(h.checks, check{name: name, fn: fn})
+}
+
+// Drain makes the server report not ready from now on, so no new traffic is sent while it shuts down
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Check runs every check at once, each with its own Timeout
+func (h *Health) Check(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckResult, len(h.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+			result := run(ctx, c)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	if h.draining.Load() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+func run(ctx context.Context, c check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+	result := CheckResult{Status: StatusUp, LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/exp/slog"
+)
+
+// Middleware logs every request once it is answered, 5xx as errors, 4xx as warnings and the others as info. It goes
+// before apperror.Middleware so the status logged is the one sent. Only the path is logged, query strings can hold
+// emails and phone numbers.
+func Middleware(logger *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		} else if status >= fiber.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+
+		// the record is written before the handler returns, the strings fiber reuses don't need copies
+		logger.LogAttrs(c.UserContext(), level, "request",
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.String("route", c.Route().Path),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("ip", c.IP()),
+		)
+		return err
+	}
+}
@@ -0,0 +1,107 @@
+// Package logging writes the logs of the api as structured records with levels, JSON lines by default. Records
+// logged with the context of a request carry its request id and the trace it belongs to.
+package logging
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/bmdavis419/fiber-mongo-example/config"
+	"github.com/bmdavis419/fiber-mongo-example/redact"
+	"github.com/bmdavis419/fiber-mongo-example/requestid"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+)
+
+// New creates the logger of cfg, it writes to stderr
+func New(cfg config.Logging) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, err
+	}
+
+	opts := slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if cfg.Format == "text" {
+		handler = opts.NewTextHandler(os.Stderr)
+	} else {
+		handler = opts.NewJSONHandler(os.Stderr)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// Setup makes the logger of cfg the default one. What is still written with the log package, like the messages of
+// libraries, is logged as errors.
+func Setup(cfg config.Logging) error {
+	logger, err := New(cfg)
+	if err != nil {
+		return err
+	}
+
+	slog.SetDefault(logger)
+	log.SetOutput(slog.NewLogLogger(logger.Handler(), slog.LevelError).Writer())
+	return nil
+}
+
+// sensitive attributes are redacted whatever logs them, models log themselves redacted with LogValue
+var sensitive = map[string]func(string) string{
+	"email": redact.Email,
+	"phone": redact.Phone,
+}
+
+// redactAttr redacts a and the attributes of the groups it holds
+func redactAttr(a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if f, ok := sensitive[a.Key]; ok {
+			return slog.String(a.Key, f(a.Value.String()))
+		}
+	case slog.KindGroup:
+		group := a.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, g := range group {
+			redacted[i] = redactAttr(g)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	}
+	return a
+}
+
+// contextHandler redacts sensitive attributes and adds the request id and the trace of the context to the records
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) {
+		redacted.AddAttrs(redactAttr(a))
+	})
+	r = redacted
+
+	// records logged without a context, like with slog.Info, have a nil one
+	if ctx == nil {
+		return h.Handler.Handle(ctx, r)
+	}
+
+	if id := requestid.From(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		r.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return contextHandler{h.Handler.WithAttrs(redacted)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/requestid"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/exp/slog"
+)
+
+func capture(t *testing.T, log func(logger *slog.Logger)) map[string]interface{} {
+	t.Helper()
+	var buf bytes.Buffer
+	log(slog.New(contextHandler{slog.HandlerOptions{}.NewJSONHandler(&buf)}))
+
+	record := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("%v in %q", err, buf.String())
+	}
+	return record
+}
+
+func TestSensitiveAttributesAreRedacted(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(logger *slog.Logger)
+	}{
+		{"record", func(logger *slog.Logger) {
+			logger.Info("login", "email", "asha@example.com", "phone", "+91 98765 43221")
+		}},
+		{"with", func(logger *slog.Logger) {
+			logger.With("email", "asha@example.com", "phone", "+91 98765 43221").Info("login")
+		}},
+		{"with a context", func(logger *slog.Logger) {
+			logger.InfoCtx(context.Background(), "login", "email", "asha@example.com", "phone", "+91 98765 43221")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := capture(t, tt.log)
+			if record["email"] != "a***@example.com" || record["phone"] != "**********21" {
+				t.Errorf("got email %v and phone %v", record["email"], record["phone"])
+			}
+		})
+	}
+}
+
+func TestGroupsAreRedacted(t *testing.T) {
+	record := capture(t, func(logger *slog.Logger) {
+		logger.Info("login", slog.Group("user", slog.String("email", "asha@example.com"),
+			slog.Group("contact", slog.String("phone", "+91 98765 43221"), slog.Int("id", 7))))
+	})
+
+	user, _ := record["user"].(map[string]interface{})
+	contact, _ := user["contact"].(map[string]interface{})
+	if user["email"] != "a***@example.com" || contact["phone"] != "**********21" || contact["id"] != 7.0 {
+		t.Errorf("got %v", record["user"])
+	}
+
+	with := capture(t, func(logger *slog.Logger) {
+		logger.With(slog.Group("user", slog.String("email", "asha@example.com"))).Info("login")
+	})
+	if user, _ := with["user"].(map[string]interface{}); user["email"] != "a***@example.com" {
+		t.Errorf("with: got %v", with["user"])
+	}
+}
+
+func TestModelsLogRedacted(t *testing.T) {
+	query := models.Query{ID: "q1", Email: "asha@example.com", Phone: "+91 98765 43221", Message: "call me"}
+	record := capture(t, func(logger *slog.Logger) { logger.Info("query", "query", query) })
+
+	got, _ := record["query"].(map[string]interface{})
+	if got["email"] != "a***@example.com" || got["phone"] != "**********21" || got["message"] != nil {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestContextAttributes(t *testing.T) {
+	ctx := requestid.With(context.Background(), "req-1")
+	record := capture(t, func(logger *slog.Logger) { logger.InfoCtx(ctx, "hello", "count", 2) })
+
+	if record["request_id"] != "req-1" || record["count"] != 2.0 || record["msg"] != "hello" {
+		t.Errorf("got %v", record)
+	}
+}
+
+func TestMiddlewareLogsTheStatusSent(t *testing.T) {
+	var buf bytes.Buffer
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.Handler})
+	app.Use(Middleware(slog.New(slog.HandlerOptions{}.NewJSONHandler(&buf))))
+	app.Use(apperror.Middleware())
+	app.Get("/products/:id", func(c *fiber.Ctx) error {
+		return apperror.NotFound("no such product")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/products/1", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 404 || resp.Header.Get(fiber.HeaderContentType) != apperror.MIMEProblemJSON {
+		t.Fatalf("got %d %s", resp.StatusCode, resp.Header.Get(fiber.HeaderContentType))
+	}
+
+	record := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("%v in %q", err, buf.String())
+	}
+	if record["status"] != 404.0 || record["level"] != "WARN" || record["route"] != "/products/:id" {
+		t.Errorf("got %v", record)
+	}
+}
@@ -1,61 +1,200 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"log"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/audit"
+	"github.com/bmdavis419/fiber-mongo-example/auth"
+	"github.com/bmdavis419/fiber-mongo-example/background"
 	"github.com/bmdavis419/fiber-mongo-example/common"
+	"github.com/bmdavis419/fiber-mongo-example/config"
+	"github.com/bmdavis419/fiber-mongo-example/geo"
+	"github.com/bmdavis419/fiber-mongo-example/health"
+	"github.com/bmdavis419/fiber-mongo-example/logging"
+	"github.com/bmdavis419/fiber-mongo-example/metrics"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/bmdavis419/fiber-mongo-example/requestid"
 	"github.com/bmdavis419/fiber-mongo-example/router"
+	"github.com/bmdavis419/fiber-mongo-example/search"
+	"github.com/bmdavis419/fiber-mongo-example/storage"
+	"github.com/bmdavis419/fiber-mongo-example/tracing"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/exp/slog"
 )
 
 func main() {
-	err := run()
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = migrate(os.Args[2:])
+	} else if len(os.Args) > 1 && os.Args[1] == "seed" {
+		err = seedDB(os.Args[2:])
+	} else if len(os.Args) > 1 && os.Args[1] == "config" {
+		err = printConfig()
+	} else {
+		err = run()
+	}
 
+	// a failure is one line on stderr and a non-zero exit, not a stack trace
 	if err != nil {
-		panic(err)
+		log.Fatal(err)
 	}
 }
 
 func run() error {
-	// init env
-	err := common.LoadEnv()
+	// init config, every invalid setting is reported at once
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	err = cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	// init logging, the log lines are JSON unless LOG_FORMAT=text
+	err = logging.Setup(cfg.Logging)
+	if err != nil {
+		return err
+	}
+
+	// init tracing, TRACING_EXPORTER picks where the spans go
+	stopTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return err
+	}
+
+	// init repositories and search, STORAGE=memory runs the api without MongoDB
+	var repos *repository.Repositories
+	var searcher search.Searcher
+	if cfg.Storage == "memory" {
+		repos = repository.NewMemory()
+
+		// the in-memory index follows the changes made through the repositories
+		index := search.NewMemory()
+		repos.Products = search.IndexProducts(repos.Products, index)
+		repos.Transports = search.IndexTransports(repos.Transports, index)
+		searcher = index
+	} else {
+		// init db
+		err = common.InitDB(cfg.Mongo, options.Client().
+			SetMonitor(tracing.CommandMonitor(metrics.CommandMonitor())).
+			SetPoolMonitor(metrics.PoolMonitor()))
+		if err != nil {
+			return err
+		}
+
+		// defer closing db
+		defer common.CloseDB()
+
+		// apply the pending migrations, the repositories rely on their indexes
+		err = migrateOnStart(context.Background(), cfg.MigrateOnStart)
+		if err != nil {
+			return err
+		}
+
+		repos = repository.NewMongo(common.GetDB())
+		searcher = search.NewMongo(common.GetDB())
+	}
+
+	// init object store, created once and shared by every upload
+	store, err := storage.New(context.Background(), cfg.ObjectStore)
 	if err != nil {
 		return err
 	}
+	store = tracing.ObjectStore(store, cfg.ObjectStore.Backend)
 
-	// init db
-	err = common.InitDB()
+	// init geocoder, GEOCODER_TABLE is a JSON file of place names to coordinates
+	geocoder, err := geo.New(cfg.GeocoderTable)
 	if err != nil {
 		return err
 	}
 
-	// defer closing db
-	defer common.CloseDB()
+	// what to do with open enquiries when their product or transport is deleted
+	policy, err := router.ParseDeletePolicy(cfg.EnquiryDeletePolicy)
+	if err != nil {
+		return err
+	}
 
-	// create app
-	app := fiber.New()
+	// init auth, tokens are signed with JWT_SECRET
+	tokens, err := auth.NewTokens(cfg.Auth.JWTSecret)
+	if err != nil {
+		return err
+	}
+	err = auth.EnsureAdmin(context.Background(), repos.Users, cfg.Auth.AdminEmail, cfg.Auth.AdminPassword)
+	if err != nil {
+		return err
+	}
+
+	// record the changes made through the api from here on to the audit log
+	audit.Track(repos, audit.New(repos.Audit))
 
-	// add basic middleware
-	app.Use(logger.New())  // logger.New() is a middleware function that returns a function that can be used by the app to handle requests and responses (log requests)
+	// background work of the handlers, waited for on shutdown
+	tasks := background.New()
+
+	// dependencies checked by /readyz
+	checks := health.New()
+	if cfg.Storage == "mongo" {
+		checks.Add("mongo", common.PingDB)
+	}
+	checks.Add("objectStore", store.Ping)
+
+	// create app, errors returned by handlers are written as problem+json, the banner would break the JSON log lines
+	app := fiber.New(fiber.Config{
+		ErrorHandler:          apperror.Handler,
+		DisableStartupMessage: true,
+	})
+
+	// add the probes and metrics first so the middleware below doesn't log or count every scrape
+	router.AddHealthGroup(app, checks)
+	app.Get("/metrics", metrics.Handler())
+
+	// add basic middleware, the request id first so every log line of a request has it
+	app.Use(requestid.Middleware())
+	app.Use(logging.Middleware(slog.Default()))
+	app.Use(metrics.Middleware())
+	app.Use(tracing.Middleware())
+	app.Use(apperror.Middleware())
 	app.Use(recover.New()) // recover.New() is a middleware function that returns a function that can be used by the app to handle requests and responses (recover from panics)
 	app.Use(cors.New())    // cors.New() is a middleware function that returns a function that can be used by the app to handle requests and responses (allow cross-origin requests)
 
 	// add routes
-	router.AddBookGroup(app)
-	router.AddProductGroup(app)
-	router.AddTransportGroup(app)
-	router.AddEnquiryGroup(app)
-	router.AddQueryGroup(app)
+	router.AddAuthGroup(app, repos.Users, tokens)
+	router.AddBookGroup(app, repos.Books, tokens)
+	router.AddProductGroup(app, repos.Products, repos.Enquiries, repos.Calendar, store, tasks, policy, tokens)
+	router.AddTransportGroup(app, repos.Transports, repos.Enquiries, repos.Calendar, policy, tokens)
+	router.AddEnquiryGroup(app, repos.Enquiries, repos.Products, repos.Transports, repos.Calendar, geocoder, tokens)
+	router.AddReviewGroup(app, repos.Reviews, repos.Enquiries, repos.Products, repos.Transports, tokens)
+	router.AddSearchGroup(app, searcher)
+	router.AddQueryGroup(app, repos.Queries, tokens)
+	router.AddMediaGroup(app, store)
+	router.AddAdminGroup(app, repos.Audit, tokens)
+
+	// start server, it runs until listening fails or the process is asked to stop
+	listenErr := make(chan error, 1)
+	slog.Info("listening", "port", cfg.Port)
+	go func() {
+		listenErr <- app.Listen(":" + strconv.Itoa(cfg.Port))
+	}()
 
-	// start server
-	var port string
-	if port = os.Getenv("PORT"); port == "" {
-		port = "8080"
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-listenErr:
+		return fmt.Errorf("listening on port %d failed: %w", cfg.Port, err)
+	case sig := <-stop:
+		slog.Info("shutting down", "signal", sig.String())
 	}
-	app.Listen(":" + port)
 
-	return nil
+	// the deferred CloseDB disconnects MongoDB once the requests and tasks are done
+	return shutdown(app, tasks, checks, stopTracing, cfg.ShutdownDelay, cfg.ShutdownTimeout)
 }
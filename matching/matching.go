@@ -0,0 +1,137 @@
+package matching
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/bmdavis419/fiber-mongo-example/geo"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+)
+
+// Weights of the parts of a score, they add up to 1
+const (
+	PriceWeight    = 0.5
+	RatingWeight   = 0.3
+	CapacityWeight = 0.2
+)
+
+// Request is what a buyer wants carried, Services lists the services the transport must all offer.
+// Calendar holds the days of the delivery date by transport id, it is nil when the date doesn't matter.
+type Request struct {
+	Product          models.Product
+	Quantity         int
+	DeliveryAddress  string
+	DeliveryLocation *geo.Point
+	Services         []string
+	Calendar         map[string]models.CalendarDay
+}
+
+// Breakdown holds the parts of a score, each between 0 and 1
+type Breakdown struct {
+	Price    float64 `json:"price"`
+	Rating   float64 `json:"rating"`
+	Capacity float64 `json:"capacity"`
+}
+
+// Match is a transport that can carry the request, Cost is its price for the whole quantity
+type Match struct {
+	Transport models.Transport `json:"transport"`
+	Score     float64          `json:"score"`
+	Breakdown Breakdown        `json:"breakdown"`
+	Cost      money.Money      `json:"cost"`
+}
+
+// Eligible reports whether t can carry the request: it is available, the quantity is between its minimum and its capacity,
+// it offers the services, delivers to the address, is priced in the currency of the product and has room on the day
+func Eligible(t models.Transport, r Request) bool {
+	switch {
+	case !t.Available:
+		return false
+	case r.Calendar != nil && !r.Calendar[t.ID].Availability(t).Fits(r.Quantity):
+		return false
+	case r.Quantity < t.MinQuantity:
+		return false
+	case t.Capacity > 0 && r.Quantity > t.Capacity:
+		return false
+	case t.Price.IsZero() || t.Price.Currency != r.Product.Price.Currency:
+		return false
+	}
+	return offers(t.Sevices, r.Services) && t.Serves(r.DeliveryLocation, r.DeliveryAddress)
+}
+
+// Rank scores the eligible transports and returns them best first:
+//   - price is 1 for the cheapest and falls with the ratio to the cheapest
+//   - rating is the rating out of 5
+//   - capacity is how full the request makes the transport, 0.5 when its capacity is unknown
+func Rank(transports []models.Transport, r Request) []Match {
+	matches := make([]Match, 0)
+	for _, t := range transports {
+		if Eligible(t, r) {
+			matches = append(matches, Match{Transport: t, Cost: t.Price.Mul(int64(r.Quantity))})
+		}
+	}
+
+	cheapest := int64(math.MaxInt64)
+	for _, m := range matches {
+		if m.Cost.Amount < cheapest {
+			cheapest = m.Cost.Amount
+		}
+	}
+
+	for i := range matches {
+		m := &matches[i]
+		m.Breakdown = Breakdown{
+			Price:    priceScore(cheapest, m.Cost.Amount),
+			Rating:   math.Min(math.Max(m.Transport.Rating/5, 0), 1),
+			Capacity: capacityScore(m.Transport.Capacity, r.Quantity),
+		}
+		m.Score = round(PriceWeight*m.Breakdown.Price + RatingWeight*m.Breakdown.Rating + CapacityWeight*m.Breakdown.Capacity)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Cost.Amount < matches[j].Cost.Amount
+	})
+
+	return matches
+}
+
+func priceScore(cheapest int64, cost int64) float64 {
+	if cost <= 0 {
+		return 1
+	}
+	return round(float64(cheapest) / float64(cost))
+}
+
+func capacityScore(capacity int, quantity int) float64 {
+	if capacity <= 0 {
+		return 0.5
+	}
+	return round(float64(quantity) / float64(capacity))
+}
+
+// offers reports whether services contains every wanted service
+func offers(services []string, wanted []string) bool {
+	for _, w := range wanted {
+		found := false
+		for _, s := range services {
+			if strings.EqualFold(s, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// round keeps 4 decimals so scores are readable
+func round(f float64) float64 {
+	return math.Round(f*10000) / 10000
+}
@@ -0,0 +1,127 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/geo"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+)
+
+func price(t *testing.T, amount string, currency string) money.Money {
+	t.Helper()
+	m, err := money.Parse(amount, currency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func request(t *testing.T) Request {
+	return Request{
+		Product:         models.Product{Name: "rice", Price: price(t, "40", "INR")},
+		Quantity:        10,
+		DeliveryAddress: "Baner, Pune",
+	}
+}
+
+func TestEligible(t *testing.T) {
+	full := 10
+	pune := geo.Cities["pune"]
+	base := models.Transport{ID: "t1", Available: true, Price: price(t, "5", "INR"), Sevices: []string{"Cold Storage"}}
+
+	tests := []struct {
+		name   string
+		change func(*models.Transport, *Request)
+		want   bool
+	}{
+		{"eligible", func(*models.Transport, *Request) {}, true},
+		{"unavailable", func(tr *models.Transport, _ *Request) { tr.Available = false }, false},
+		{"below the minimum", func(tr *models.Transport, _ *Request) { tr.MinQuantity = 11 }, false},
+		{"at the capacity", func(tr *models.Transport, _ *Request) { tr.Capacity = 10 }, true},
+		{"over the capacity", func(tr *models.Transport, _ *Request) { tr.Capacity = 9 }, false},
+		{"other currency", func(tr *models.Transport, _ *Request) { tr.Price = price(t, "5", "USD") }, false},
+		{"no price", func(tr *models.Transport, _ *Request) { tr.Price = money.Money{} }, false},
+		{"offers the service", func(_ *models.Transport, r *Request) { r.Services = []string{"cold storage"} }, true},
+		{"missing a service", func(_ *models.Transport, r *Request) { r.Services = []string{"cold storage", "loading"} }, false},
+		{"serves the area", func(tr *models.Transport, _ *Request) { tr.ServiceAreas = []geo.Area{{Name: "Pune"}} }, true},
+		{"serves elsewhere", func(tr *models.Transport, _ *Request) { tr.ServiceAreas = []geo.Area{{Name: "Mumbai"}} }, false},
+		{"serves around the location", func(tr *models.Transport, r *Request) {
+			tr.ServiceAreas = []geo.Area{{Center: &pune, RadiusKm: 20}}
+			r.DeliveryAddress, r.DeliveryLocation = "Hinjewadi", &pune
+		}, true},
+		{"room on the day", func(_ *models.Transport, r *Request) {
+			r.Calendar = map[string]models.CalendarDay{"t1": {Capacity: &full}}
+		}, true},
+		{"day not in the calendar", func(_ *models.Transport, r *Request) { r.Calendar = map[string]models.CalendarDay{} }, true},
+		{"full day", func(_ *models.Transport, r *Request) {
+			r.Calendar = map[string]models.CalendarDay{"t1": {Capacity: &full, Bookings: []models.Booking{{Quantity: 1}}}}
+		}, false},
+		{"closed day", func(_ *models.Transport, r *Request) {
+			r.Calendar = map[string]models.CalendarDay{"t1": {Closed: true}}
+		}, false},
+	}
+
+	for _, tt := range tests {
+		tr, r := base, request(t)
+		tt.change(&tr, &r)
+		if got := Eligible(tr, r); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	transports := []models.Transport{
+		{ID: "pricey", Available: true, Price: price(t, "10", "INR"), Rating: 5, Capacity: 100},
+		{ID: "cheap", Available: true, Price: price(t, "5", "INR")},
+		{ID: "rated", Available: true, Price: price(t, "5", "INR"), Rating: 4, Capacity: 20},
+		{ID: "unavailable", Price: price(t, "1", "INR"), Rating: 5},
+		{ID: "dollars", Available: true, Price: price(t, "1", "USD"), Rating: 5},
+	}
+
+	matches := Rank(transports, request(t))
+
+	want := []struct {
+		id        string
+		score     float64
+		breakdown Breakdown
+		cost      string
+	}{
+		{"rated", 0.84, Breakdown{Price: 1, Rating: 0.8, Capacity: 0.5}, "50"},
+		{"cheap", 0.6, Breakdown{Price: 1, Rating: 0, Capacity: 0.5}, "50"},
+		{"pricey", 0.57, Breakdown{Price: 0.5, Rating: 1, Capacity: 0.1}, "100"},
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("got %d matches, want %d", len(matches), len(want))
+	}
+	for i, w := range want {
+		m := matches[i]
+		if m.Transport.ID != w.id || m.Score != w.score || m.Breakdown != w.breakdown || m.Cost != price(t, w.cost, "INR") {
+			t.Errorf("%d: got %s %v %+v %v, want %s %v %+v %s", i, m.Transport.ID, m.Score, m.Breakdown, m.Cost, w.id, w.score, w.breakdown, w.cost)
+		}
+	}
+}
+
+func TestRankTiesGoToTheCheapest(t *testing.T) {
+	// the same score, the cheaper one scores more on price and less on rating
+	transports := []models.Transport{
+		{ID: "rated", Available: true, Price: price(t, "10", "INR"), Rating: 5},
+		{ID: "cheap", Available: true, Price: price(t, "5", "INR"), Rating: 0.8333333},
+	}
+
+	matches := Rank(transports, request(t))
+	if len(matches) != 2 || matches[0].Score != matches[1].Score {
+		t.Fatalf("got %+v, want two matches of the same score", matches)
+	}
+	if matches[0].Transport.ID != "cheap" {
+		t.Errorf("got %s first, want the cheapest", matches[0].Transport.ID)
+	}
+}
+
+func TestRankWithoutMatches(t *testing.T) {
+	matches := Rank(nil, request(t))
+	if matches == nil || len(matches) != 0 {
+		t.Errorf("got %#v, want an empty list", matches)
+	}
+}
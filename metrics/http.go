@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by route group, method and status.",
+	}, []string{"group", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time to answer HTTP requests by route group, method and status.",
+		Buckets: buckets,
+	}, []string{"group", "method", "status"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests being answered.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(httpRequests, httpDuration, httpInFlight)
+}
+
+// Middleware counts and times the requests, it goes before apperror.Middleware so the status counted is the one sent
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+
+		err := c.Next()
+
+		// the method points into a buffer fiber reuses, the label keeps a copy
+		labels := prometheus.Labels{
+			"group":  group(c),
+			"method": utils.CopyString(c.Method()),
+			"status": strconv.Itoa(c.Response().StatusCode()),
+		}
+		httpRequests.With(labels).Inc()
+		httpDuration.With(labels).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// group is the first segment of the route that answered, like "products" for /products/:id/reviews. It is taken
+// from the route and not the path so the ids don't make a label value each, requests no route matched are "other".
+func group(c *fiber.Ctx) string {
+	path := strings.TrimPrefix(c.Route().Path, "/")
+	if path == "" || strings.HasPrefix(path, "*") {
+		return "other"
+	}
+	segment, _, _ := strings.Cut(path, "/")
+	return segment
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() fiber.Handler {
+	handler := fasthttpadaptor.NewFastHTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	return func(c *fiber.Ctx) error {
+		handler(c.Context())
+		return nil
+	}
+}
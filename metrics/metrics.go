@@ -0,0 +1,21 @@
+// Package metrics collects the Prometheus metrics of the api, served on /metrics: HTTP requests, MongoDB commands and
+// connections, and uploads to the object store
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Registry holds every metric of the api, plus the ones of the Go runtime and the process
+var Registry = prometheus.NewRegistry()
+
+// buckets of the latency histograms in seconds, from 1ms to 10s
+var buckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+var (
+	mongoDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mongo_command_duration_seconds",
+		Help:    "Time MongoDB commands took by command and outcome.",
+		Buckets: buckets,
+	}, []string{"command", "outcome"})
+
+	mongoErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mongo_command_errors_total",
+		Help: "MongoDB commands that failed by command.",
+	}, []string{"command"})
+
+	mongoConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "mongo_pool_connections",
+		Help: "Open connections of the MongoDB pool.",
+	})
+
+	mongoConnectionsInUse = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "mongo_pool_connections_in_use",
+		Help: "Connections of the MongoDB pool checked out by a command.",
+	})
+
+	mongoPoolEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mongo_pool_events_total",
+		Help: "MongoDB pool events by type, like ConnectionCheckOutFailed or ConnectionPoolCleared.",
+	}, []string{"type"})
+)
+
+func init() {
+	Registry.MustRegister(mongoDuration, mongoErrors, mongoConnections, mongoConnectionsInUse, mongoPoolEvents)
+}
+
+// CommandMonitor times the commands sent to MongoDB, give it to the client options
+func CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			mongoDuration.WithLabelValues(e.CommandName, "success").Observe(seconds(e.DurationNanos))
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			mongoDuration.WithLabelValues(e.CommandName, "failure").Observe(seconds(e.DurationNanos))
+			mongoErrors.WithLabelValues(e.CommandName).Inc()
+		},
+	}
+}
+
+// PoolMonitor follows the connections of the MongoDB pool, give it to the client options
+func PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			mongoPoolEvents.WithLabelValues(e.Type).Inc()
+			switch e.Type {
+			case event.ConnectionCreated:
+				mongoConnections.Inc()
+			case event.ConnectionClosed:
+				mongoConnections.Dec()
+			case event.GetSucceeded:
+				mongoConnectionsInUse.Inc()
+			case event.ConnectionReturned:
+				mongoConnectionsInUse.Dec()
+			}
+		},
+	}
+}
+
+func seconds(nanos int64) float64 {
+	return time.Duration(nanos).Seconds()
+}
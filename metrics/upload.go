@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	uploadBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "object_store_upload_bytes_total",
+		Help: "Bytes uploaded to the object store.",
+	})
+
+	uploadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "object_store_upload_duration_seconds",
+		Help:    "Time uploads to the object store took by outcome.",
+		Buckets: buckets,
+	}, []string{"outcome"})
+
+	uploadFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "object_store_upload_failures_total",
+		Help: "Uploads to the object store that failed.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(uploadBytes, uploadDuration, uploadFailures)
+}
+
+// ObserveUpload records an upload of size bytes that started at start, err is what the upload returned
+func ObserveUpload(size int64, start time.Time, err error) {
+	if err != nil {
+		uploadFailures.Inc()
+		uploadDuration.WithLabelValues("failure").Observe(time.Since(start).Seconds())
+		return
+	}
+	uploadBytes.Add(float64(size))
+	uploadDuration.WithLabelValues("success").Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/common"
+	"github.com/bmdavis419/fiber-mongo-example/config"
+	"github.com/bmdavis419/fiber-mongo-example/logging"
+	"github.com/bmdavis419/fiber-mongo-example/migrations"
+	"golang.org/x/exp/slog"
+)
+
+// migrate applies, reverts or lists the schema migrations, e.g.
+//
+//	go run . migrate up -dry-run
+//	go run . migrate down -steps 2
+//	go run . migrate status
+func migrate(args []string) error {
+	if len(args) == 0 || (args[0] != "up" && args[0] != "down" && args[0] != "status") {
+		return fmt.Errorf("usage: migrate up|down|status [flags]")
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only list the migrations that would run")
+	to := flags.Int("to", 0, "up: stop after this version, 0 applies every pending migration")
+	steps := flags.Int("steps", 1, "down: how many applied migrations to revert")
+	flags.Parse(args[1:])
+
+	// init config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// init logging like the api does
+	err = logging.Setup(cfg.Logging)
+	if err != nil {
+		return err
+	}
+
+	// init db
+	err = common.InitDB(cfg.Mongo)
+	if err != nil {
+		return err
+	}
+	defer common.CloseDB()
+
+	ctx := context.Background()
+	migrator := migrations.New(common.GetDB())
+
+	verb := "applied"
+	if *dryRun {
+		verb = "would apply"
+	}
+
+	var done []migrations.Migration
+	switch args[0] {
+	case "up":
+		done, err = migrator.Up(ctx, *to, *dryRun)
+	case "down":
+		if *steps < 1 {
+			return fmt.Errorf("steps must be at least 1")
+		}
+		verb = "reverted"
+		if *dryRun {
+			verb = "would revert"
+		}
+		done, err = migrator.Down(ctx, *steps, *dryRun)
+	default:
+		return printStatus(ctx, migrator)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(done) == 0 {
+		slog.Info("nothing to migrate")
+	}
+	for _, m := range done {
+		slog.Info("migration "+verb, "version", m.Version, "name", m.Name, "description", m.Description)
+	}
+	return nil
+}
+
+func printStatus(ctx context.Context, migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.Format(time.RFC3339)
+		}
+		if s.Unknown {
+			applied += " (unknown to this build)"
+		}
+		fmt.Fprintf(os.Stdout, "%04d  %-30s %s\n", s.Version, s.Name, applied)
+	}
+	return nil
+}
+
+// migrateOnStart applies the pending migrations when the api starts, MIGRATE_ON_START=false only warns about them
+// so they can be run with the migrate command during a deploy instead
+func migrateOnStart(ctx context.Context, enabled bool) error {
+	migrator := migrations.New(common.GetDB())
+
+	if !enabled {
+		pending, err := migrator.Up(ctx, 0, true)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			slog.Warn("migrations are pending, run: go run . migrate up", "pending", len(pending))
+		}
+		return nil
+	}
+
+	_, err := migrator.Up(ctx, 0, false)
+	return err
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The indexes keep the default names MongoDB gives them, so Down can drop them by name
+func init() {
+	register(Migration{
+		Version:     1,
+		Name:        "lookup_indexes",
+		Description: "indexes for listing by owner, enquiries by transport, product and status, and unique emails",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createIndexes(ctx, db, "products", mongo.IndexModel{Keys: bson.D{{Key: "sellerId", Value: 1}}}); err != nil {
+				return err
+			}
+			if err := createIndexes(ctx, db, "transports", mongo.IndexModel{Keys: bson.D{{Key: "ownerId", Value: 1}}}); err != nil {
+				return err
+			}
+			err := createIndexes(ctx, db, "enquiries",
+				mongo.IndexModel{Keys: bson.D{{Key: "buyerId", Value: 1}}},
+				// open enquiries of a transport or product are looked up when it is deleted or booked
+				mongo.IndexModel{Keys: bson.D{{Key: "transportId", Value: 1}, {Key: "status", Value: 1}}},
+				mongo.IndexModel{Keys: bson.D{{Key: "productId", Value: 1}, {Key: "status", Value: 1}}},
+				mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}}},
+			)
+			if err != nil {
+				return err
+			}
+			// fails when two users already share an email, they have to be merged by hand first
+			return createIndexes(ctx, db, "users", mongo.IndexModel{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db, "products", "sellerId_1"); err != nil {
+				return err
+			}
+			if err := dropIndexes(ctx, db, "transports", "ownerId_1"); err != nil {
+				return err
+			}
+			if err := dropIndexes(ctx, db, "enquiries", "buyerId_1", "transportId_1_status_1", "productId_1_status_1", "status_1"); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db, "users", "email_1")
+		},
+	})
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	register(Migration{
+		Version:     2,
+		Name:        "geo_and_search_indexes",
+		Description: "2dsphere index for transports near a point and the text indexes of search.Mongo",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// $geoNear fails without it
+			if err := createIndexes(ctx, db, "transports", mongo.IndexModel{Keys: bson.D{{Key: "location", Value: "2dsphere"}}}); err != nil {
+				return err
+			}
+
+			// a collection can only have one text index so they cover every searched field
+			err := createIndexes(ctx, db, "products", mongo.IndexModel{
+				Keys:    bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}},
+				Options: options.Index().SetName("search").SetWeights(bson.M{"name": 3, "description": 1}),
+			})
+			if err != nil {
+				return err
+			}
+			return createIndexes(ctx, db, "transports", mongo.IndexModel{
+				Keys:    bson.D{{Key: "name", Value: "text"}, {Key: "services", Value: "text"}, {Key: "address", Value: "text"}},
+				Options: options.Index().SetName("search").SetWeights(bson.M{"name": 3, "services": 2, "address": 1}),
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db, "transports", "location_2dsphere", "search"); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db, "products", "search")
+		},
+	})
+}
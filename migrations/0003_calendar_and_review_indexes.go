@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	register(Migration{
+		Version:     3,
+		Name:        "calendar_and_review_indexes",
+		Description: "one calendar day per transport and date, one review per enquiry and subject",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// the unique index is what makes the upsert of a day in Reserve safe, days are also read by date when matching
+			err := createIndexes(ctx, db, "calendar",
+				mongo.IndexModel{Keys: bson.D{{Key: "transportId", Value: 1}, {Key: "date", Value: 1}}, Options: options.Index().SetUnique(true)},
+				mongo.IndexModel{Keys: bson.D{{Key: "date", Value: 1}}},
+			)
+			if err != nil {
+				return err
+			}
+			return createIndexes(ctx, db, "reviews",
+				mongo.IndexModel{Keys: bson.D{{Key: "enquiryId", Value: 1}, {Key: "subject", Value: 1}}, Options: options.Index().SetUnique(true)},
+				mongo.IndexModel{Keys: bson.D{{Key: "subject", Value: 1}, {Key: "subjectId", Value: 1}}},
+			)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db, "calendar", "transportId_1_date_1", "date_1"); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db, "reviews", "enquiryId_1_subject_1", "subject_1_subjectId_1")
+		},
+	})
+}
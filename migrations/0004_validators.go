@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The schemas only check the fields the api relies on and allow any other field, they are frozen copies of the
+// models at this version so a later change to models doesn't change what this migration does.
+var validators = map[string]bson.M{
+	"products": {
+		"bsonType": "object",
+		"required": bson.A{"name", "sellerId"},
+		"properties": bson.M{
+			"name":        bson.M{"bsonType": "string", "maxLength": 100},
+			"sellerId":    bson.M{"bsonType": "string"},
+			"minQuantity": bson.M{"bsonType": "number", "minimum": 0},
+			"price":       moneySchema,
+		},
+	},
+	"transports": {
+		"bsonType": "object",
+		"required": bson.A{"name", "ownerId"},
+		"properties": bson.M{
+			"name":        bson.M{"bsonType": "string", "maxLength": 100},
+			"ownerId":     bson.M{"bsonType": "string"},
+			"services":    bson.M{"bsonType": bson.A{"array", "null"}, "items": bson.M{"bsonType": "string"}},
+			"minQuantity": bson.M{"bsonType": "number", "minimum": 0},
+			"capacity":    bson.M{"bsonType": "number", "minimum": 0},
+			"price":       moneySchema,
+		},
+	},
+	"enquiries": {
+		"bsonType": "object",
+		"required": bson.A{"buyerId", "transportId", "productId", "quantity", "status"},
+		"properties": bson.M{
+			"buyerId":     bson.M{"bsonType": "string"},
+			"transportId": bson.M{"bsonType": "string"},
+			"productId":   bson.M{"bsonType": "string"},
+			"quantity":    bson.M{"bsonType": "number", "minimum": 1},
+			"status": bson.M{"enum": bson.A{
+				"requested", "quoted", "accepted", "scheduled", "in_transit", "delivered", "rejected", "cancelled",
+			}},
+		},
+	},
+	"users": {
+		"bsonType": "object",
+		"required": bson.A{"email", "passwordHash", "role"},
+		"properties": bson.M{
+			"email":        bson.M{"bsonType": "string"},
+			"passwordHash": bson.M{"bsonType": "string"},
+			"role":         bson.M{"enum": bson.A{"buyer", "seller", "transporter", "admin"}},
+		},
+	},
+}
+
+// moneySchema is money.Money, stored as null when it is zero
+var moneySchema = bson.M{
+	"bsonType": bson.A{"object", "null"},
+	"required": bson.A{"amount", "currency"},
+	"properties": bson.M{
+		"amount":   bson.M{"bsonType": "number", "minimum": 0},
+		"currency": bson.M{"bsonType": "string", "minLength": 3, "maxLength": 3},
+	},
+}
+
+func init() {
+	register(Migration{
+		Version:     4,
+		Name:        "validators",
+		Description: "$jsonSchema validators on products, transports, enquiries and users",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, coll := range []string{"products", "transports", "enquiries", "users"} {
+				if err := setValidator(ctx, db, coll, validators[coll]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			for _, coll := range []string{"products", "transports", "enquiries", "users"} {
+				if err := setValidator(ctx, db, coll, nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
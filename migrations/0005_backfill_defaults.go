@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/exp/slog"
+)
+
+// Enquiries made before statuses and quotes existed and resources made before reviews get the defaults new documents
+// are created with. It can't be reverted, the defaults can't be told apart from values set afterwards.
+func init() {
+	register(Migration{
+		Version:     5,
+		Name:        "backfill_defaults",
+		Description: "status, history and quotes of old enquiries, rating fields of old products and transports",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			backfills := []struct {
+				coll  string
+				field string
+				value interface{}
+			}{
+				{"enquiries", "status", "requested"},
+				{"enquiries", "statusHistory", bson.A{}},
+				{"enquiries", "quotes", bson.A{}},
+				{"products", "rating", 0.0},
+				{"products", "ratingCount", 0},
+				{"products", "ratingDistribution", bson.A{0, 0, 0, 0, 0}},
+				{"transports", "rating", 0.0},
+				{"transports", "ratingCount", 0},
+				{"transports", "ratingDistribution", bson.A{0, 0, 0, 0, 0}},
+			}
+
+			for _, b := range backfills {
+				res, err := db.Collection(b.coll).UpdateMany(ctx,
+					bson.M{b.field: bson.M{"$exists": false}},
+					bson.M{"$set": bson.M{b.field: b.value}},
+				)
+				if err != nil {
+					return err
+				}
+				if res.ModifiedCount > 0 {
+					slog.Info("backfilled", "field", b.field, "collection", b.coll, "modified", res.ModifiedCount)
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	register(Migration{
+		Version:     6,
+		Name:        "audit_log_indexes",
+		Description: "audit log read newest first, by resource or by actor",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// _id ends the sort of every list so the pages of a cursor are stable
+			return createIndexes(ctx, db, "audit_log",
+				mongo.IndexModel{Keys: bson.D{{Key: "time", Value: -1}, {Key: "_id", Value: 1}}},
+				mongo.IndexModel{Keys: bson.D{{Key: "resource", Value: 1}, {Key: "resourceId", Value: 1}, {Key: "time", Value: -1}}},
+				mongo.IndexModel{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "time", Value: -1}}},
+			)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db, "audit_log", "time_-1__id_1", "resource_1_resourceId_1_time_-1", "actor_1_time_-1")
+		},
+	})
+}
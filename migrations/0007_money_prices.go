@@ -0,0 +1,124 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bmdavis419/fiber-mongo-example/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/exp/slog"
+)
+
+// thousands matches amounts written with thousands separators, e.g. 1,200.50
+var thousands = regexp.MustCompile(`^[0-9]{1,3}(,[0-9]{3})+(\.[0-9]+)?$`)
+
+// Prices used to be strings on products and numbers on transports, they become money in the default currency.
+// Money can't read a price like "1,200.50" or "abc", so a product or transport holding one fails to load. The
+// migration doesn't convert anything while such prices are left and fails with their ids, it stays pending until
+// they are fixed by hand. It can't be reverted, the old formatting of the prices is gone.
+func init() {
+	register(Migration{
+		Version:     7,
+		Name:        "money_prices",
+		Description: "string and number prices of products and transports to money in " + money.DefaultCurrency,
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			names := []string{"products", "transports"}
+			updates := make([][]mongo.WriteModel, len(names))
+			unreadable := make([]string, 0)
+			for i, name := range names {
+				writes, failed, err := convertPrices(ctx, db.Collection(name), money.DefaultCurrency)
+				if err != nil {
+					return err
+				}
+				updates[i] = writes
+				unreadable = append(unreadable, failed...)
+			}
+			if len(unreadable) > 0 {
+				return fmt.Errorf("%d prices can't be read, fix them and run the migration again: %s", len(unreadable), strings.Join(unreadable, ", "))
+			}
+
+			for i, name := range names {
+				if len(updates[i]) == 0 {
+					continue
+				}
+				result, err := db.Collection(name).BulkWrite(ctx, updates[i])
+				if err != nil {
+					return err
+				}
+				slog.Info("prices converted", "collection", name, "modified", result.ModifiedCount)
+			}
+			return nil
+		},
+	})
+}
+
+// convertPrices returns the updates converting the legacy prices of coll, and the prices it can't read as
+// collection/id: reason
+func convertPrices(ctx context.Context, coll *mongo.Collection, currency string) ([]mongo.WriteModel, []string, error) {
+	// prices that are already money are embedded documents, missing and null prices are left alone
+	filter := bson.M{"price": bson.M{"$type": bson.A{"string", "double", "int", "long", "decimal"}}}
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	updates := make([]mongo.WriteModel, 0)
+	unreadable := make([]string, 0)
+	for cursor.Next(ctx) {
+		id := cursor.Current.Lookup("_id")
+		raw := cursor.Current.Lookup("price")
+
+		price, err := legacyPrice(raw, currency)
+		if err != nil {
+			unreadable = append(unreadable, fmt.Sprintf("%s/%s: %s", coll.Name(), idString(id), err))
+			continue
+		}
+
+		// only replace the price if nobody changed it in the meantime
+		updates = append(updates, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "_id", Value: id}, {Key: "price", Value: raw}}).
+			SetUpdate(bson.M{"$set": bson.M{"price": price}}))
+	}
+	return updates, unreadable, cursor.Err()
+}
+
+// idString prints an ObjectID as its hex, like the api does
+func idString(id bson.RawValue) string {
+	if oid, ok := id.ObjectIDOK(); ok {
+		return oid.Hex()
+	}
+	return id.String()
+}
+
+// legacyPrice reads a price stored before money existed, strings may use thousands separators
+func legacyPrice(raw bson.RawValue, currency string) (money.Money, error) {
+	price, err := parseLegacyPrice(raw, currency)
+	if err == nil && price.Amount < 0 {
+		return money.Money{}, fmt.Errorf("prices can't be negative")
+	}
+	return price, err
+}
+
+func parseLegacyPrice(raw bson.RawValue, currency string) (money.Money, error) {
+	switch raw.Type {
+	case bsontype.String:
+		s := strings.TrimSpace(raw.StringValue())
+		if thousands.MatchString(s) {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+		return money.Parse(s, currency)
+	case bsontype.Double:
+		return money.Parse(strconv.FormatFloat(raw.Double(), 'f', -1, 64), currency)
+	case bsontype.Int32, bsontype.Int64:
+		return money.Parse(strconv.FormatInt(raw.AsInt64(), 10), currency)
+	case bsontype.Decimal128:
+		return money.FromDecimal(raw.Decimal128(), currency)
+	}
+	return money.Money{}, fmt.Errorf("cannot read a price from %s", raw.Type)
+}
@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func rawValue(t *testing.T, v interface{}) bson.RawValue {
+	t.Helper()
+	typ, data, err := bson.MarshalValue(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bson.RawValue{Type: typ, Value: data}
+}
+
+func TestLegacyPrice(t *testing.T) {
+	decimal, _ := primitive.ParseDecimal128("99.9")
+
+	tests := []struct {
+		name  string
+		price interface{}
+		want  int64
+		ok    bool
+	}{
+		{"plain string", "12.50", 1250, true},
+		{"padded string", " 12 ", 1200, true},
+		{"thousands", "1,200.50", 120050, true},
+		{"millions", "1,200,000", 120000000, true},
+		{"double", 10.5, 1050, true},
+		{"int32", int32(7), 700, true},
+		{"int64", int64(8), 800, true},
+		{"decimal", decimal, 9990, true},
+		{"zero", "0", 0, true},
+		{"negative string", "-5", 0, false},
+		{"negative double", -0.5, 0, false},
+		{"misplaced separator", "12,00.50", 0, false},
+		{"decimal comma", "12,50", 0, false},
+		{"too many decimals", "1.005", 0, false},
+		{"garbage", "abc", 0, false},
+		{"empty", "", 0, false},
+		{"currency sign", "₹12", 0, false},
+		{"boolean", true, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := legacyPrice(rawValue(t, tt.price), money.DefaultCurrency)
+			if tt.ok != (err == nil) {
+				t.Fatalf("got error %v, want ok %v", err, tt.ok)
+			}
+			if tt.ok && got != (money.Money{Amount: tt.want, Currency: money.DefaultCurrency}) {
+				t.Errorf("got %+v, want %d %s", got, tt.want, money.DefaultCurrency)
+			}
+		})
+	}
+}
+
+func TestIdString(t *testing.T) {
+	oid := primitive.NewObjectID()
+	if got := idString(rawValue(t, oid)); got != oid.Hex() {
+		t.Errorf("got %q, want %q", got, oid.Hex())
+	}
+	if got := idString(rawValue(t, "legacy")); got != `"legacy"` {
+		t.Errorf("got %q, want the quoted string", got)
+	}
+}
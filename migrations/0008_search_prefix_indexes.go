@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	register(Migration{
+		Version:     8,
+		Name:        "search_prefix_indexes",
+		Description: "name indexes for the anchored prefix search of search.Mongo",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, coll := range []string{"products", "transports"} {
+				if err := createIndexes(ctx, db, coll, mongo.IndexModel{Keys: bson.D{{Key: "name", Value: 1}}}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			for _, coll := range []string{"products", "transports"} {
+				if err := dropIndexes(ctx, db, coll, "name_1"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,74 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Server error codes the migrations treat as already done
+const (
+	codeNamespaceNotFound = 26
+	codeIndexNotFound     = 27
+)
+
+// createIndexes creates indexes on coll, an index that already exists with the same keys and options is left alone
+func createIndexes(ctx context.Context, db *mongo.Database, coll string, indexes ...mongo.IndexModel) error {
+	_, err := db.Collection(coll).Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// dropIndexes drops the indexes of coll by name, indexes or collections that don't exist are skipped
+func dropIndexes(ctx context.Context, db *mongo.Database, coll string, names ...string) error {
+	for _, name := range names {
+		_, err := db.Collection(coll).Indexes().DropOne(ctx, name)
+		if err != nil && !isCode(err, codeIndexNotFound, codeNamespaceNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// setValidator replaces the $jsonSchema validator of coll, creating the collection when it doesn't exist yet.
+// A nil schema removes the validator.
+func setValidator(ctx context.Context, db *mongo.Database, coll string, schema bson.M) error {
+	validator := bson.M{}
+	if schema != nil {
+		validator = bson.M{"$jsonSchema": schema}
+	}
+
+	names, err := db.ListCollectionNames(ctx, bson.M{"name": coll})
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		if schema == nil {
+			return nil
+		}
+		opts := options.CreateCollection().SetValidator(validator).SetValidationLevel("moderate")
+		return db.CreateCollection(ctx, coll, opts)
+	}
+
+	// moderate only checks inserts and updates of documents that are already valid, old documents stay writable
+	return db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: coll},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+	}).Err()
+}
+
+func isCode(err error, codes ...int32) bool {
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	for _, code := range codes {
+		if cmdErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
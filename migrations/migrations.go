@@ -0,0 +1,285 @@
+// Package migrations keeps the schema of the database up to date: indexes, validators and data backfills.
+// Every migration lives in its own file named after its version and registers itself in init, the versions
+// that were applied are recorded in the schema_migrations collection.
+package migrations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/exp/slog"
+)
+
+// Collection records the applied migrations, one document per version plus the lock
+const Collection = "schema_migrations"
+
+// ErrLocked is returned when another process is running migrations
+var ErrLocked = errors.New("migrations are locked by another process")
+
+// LockTimeout is how long a lock can go without being refreshed before another process takes it over,
+// the process holding it refreshes it every quarter of that
+const LockTimeout = 2 * time.Minute
+
+// Migration changes the database from the previous version to Version
+type Migration struct {
+	Version     int
+	Name        string
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+	// Down reverts Up, it is nil when the migration can't be reverted like most data backfills
+	Down func(ctx context.Context, db *mongo.Database) error
+}
+
+var registered []Migration
+
+func register(m Migration) {
+	for _, r := range registered {
+		if r.Version == m.Version {
+			panic(fmt.Sprintf("migrations: version %d is used by %s and %s", m.Version, r.Name, m.Name))
+		}
+	}
+	registered = append(registered, m)
+	sort.Slice(registered, func(i, j int) bool { return registered[i].Version < registered[j].Version })
+}
+
+// All returns the registered migrations in order
+func All() []Migration {
+	return append([]Migration{}, registered...)
+}
+
+// Record is the document stored for an applied migration
+type Record struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"appliedAt"`
+}
+
+// Status is a migration and when it was applied, AppliedAt is nil while it is pending
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt"`
+	// Unknown is set for a version that was applied but isn't registered, e.g. by a newer build
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// Migrator applies and reverts migrations on a database
+type Migrator struct {
+	db          *mongo.Database
+	migrations  []Migration
+	store       store
+	lockTimeout time.Duration
+}
+
+// New creates a migrator for the registered migrations
+func New(db *mongo.Database) *Migrator {
+	return &Migrator{db: db, migrations: All(), store: &mongoStore{coll: db.Collection(Collection)}, lockTimeout: LockTimeout}
+}
+
+func (m *Migrator) records(ctx context.Context) (map[int]Record, error) {
+	records, err := m.store.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int]Record{}
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// Status lists every registered or applied migration by version
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if r, ok := applied[mig.Version]; ok {
+			s.AppliedAt = &r.AppliedAt
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, r := range applied {
+		r := r
+		statuses = append(statuses, Status{Version: r.Version, Name: r.Name, AppliedAt: &r.AppliedAt, Unknown: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// Up applies the pending migrations up to version to, every one when to is 0, and returns them.
+// With dryRun nothing is applied and the migrations that would be are returned.
+func (m *Migrator) Up(ctx context.Context, to int, dryRun bool) ([]Migration, error) {
+	applied, err := m.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]Migration, 0)
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok && (to == 0 || mig.Version <= to) {
+			pending = append(pending, mig)
+		}
+	}
+	if dryRun || len(pending) == 0 {
+		return pending, nil
+	}
+
+	return pending, m.locked(ctx, func(ctx context.Context) error {
+		for _, mig := range pending {
+			start := time.Now()
+			if err := mig.Up(ctx, m.db); err != nil {
+				return fmt.Errorf("migration %d %s: %w", mig.Version, mig.Name, err)
+			}
+			record := Record{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now().UTC().Truncate(time.Millisecond)}
+			if err := m.store.insert(ctx, record); err != nil {
+				return err
+			}
+			slog.Info("migration applied", "version", mig.Version, "name", mig.Name, "duration", time.Since(start).Round(time.Millisecond))
+		}
+		return nil
+	})
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns them.
+// With dryRun nothing is reverted and the migrations that would be are returned.
+func (m *Migrator) Down(ctx context.Context, steps int, dryRun bool) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := make([]Migration, 0)
+	for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+		s := statuses[i]
+		if s.AppliedAt == nil {
+			continue
+		}
+		if s.Unknown {
+			return nil, fmt.Errorf("migration %d %s is not known to this build and can't be reverted", s.Version, s.Name)
+		}
+		mig := m.find(s.Version)
+		if mig.Down == nil {
+			return nil, fmt.Errorf("migration %d %s can't be reverted", mig.Version, mig.Name)
+		}
+		reverted = append(reverted, mig)
+	}
+	if dryRun || len(reverted) == 0 {
+		return reverted, nil
+	}
+
+	return reverted, m.locked(ctx, func(ctx context.Context) error {
+		for _, mig := range reverted {
+			if err := mig.Down(ctx, m.db); err != nil {
+				return fmt.Errorf("reverting migration %d %s: %w", mig.Version, mig.Name, err)
+			}
+			if err := m.store.remove(ctx, mig.Version); err != nil {
+				return err
+			}
+			slog.Info("migration reverted", "version", mig.Version, "name", mig.Name)
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) find(version int) Migration {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig
+		}
+	}
+	return Migration{}
+}
+
+// locked runs fn while holding the lock document, so two processes starting together don't run the same migration.
+// A lock that wasn't refreshed for lockTimeout was left by a process that is gone and is taken over, ctx of fn is
+// cancelled when the lock is taken over from this process.
+func (m *Migrator) locked(ctx context.Context, fn func(ctx context.Context) error) error {
+	host, _ := os.Hostname()
+	owner := make([]byte, 8)
+	if _, err := rand.Read(owner); err != nil {
+		return err
+	}
+	l := lock{Owner: hex.EncodeToString(owner), Host: host, Pid: os.Getpid(), At: now()}
+
+	if err := m.acquire(ctx, l); err != nil {
+		return err
+	}
+	defer m.store.release(context.Background(), l.Owner)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := make(chan struct{})
+	defer close(stop)
+	go m.refresh(ctx, l.Owner, cancel, stop)
+
+	return fn(ctx)
+}
+
+// acquire takes the lock for l or takes over a stale one, a lock that is released or taken over between two steps
+// is tried again a few times
+func (m *Migrator) acquire(ctx context.Context, l lock) error {
+	for attempt := 0; attempt < 3; attempt++ {
+		held, ok, err := m.store.acquire(ctx, l)
+		if err != nil || ok {
+			return err
+		}
+		if time.Since(held.At) < m.lockTimeout {
+			return fmt.Errorf("%w (%s pid %d since %s), it is taken over once it isn't refreshed for %s",
+				ErrLocked, held.Host, held.Pid, held.At.Format(time.RFC3339), m.lockTimeout)
+		}
+
+		taken, err := m.store.takeOver(ctx, held, l)
+		if err != nil {
+			return err
+		}
+		if taken {
+			slog.Warn("took over a stale migration lock", "host", held.Host, "pid", held.Pid, "at", held.At)
+			return nil
+		}
+	}
+	return ErrLocked
+}
+
+// refresh keeps the lock of owner from going stale until stop is closed, it calls cancel when the lock is lost
+func (m *Migrator) refresh(ctx context.Context, owner string, cancel context.CancelFunc, stop chan struct{}) {
+	ticker := time.NewTicker(m.lockTimeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			held, err := m.store.refresh(ctx, owner, now())
+			if err != nil {
+				slog.Error("refreshing the migration lock failed", "err", err)
+				continue
+			}
+			if !held {
+				slog.Error("the migration lock was taken over by another process, stopping")
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// now is the time stored in the lock, MongoDB keeps milliseconds so the lock compares equal once read back
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
@@ -0,0 +1,314 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// memoryStore keeps the records and the lock like the schema_migrations collection does
+type memoryStore struct {
+	mu      sync.Mutex
+	applied map[int]Record
+	lock    *lock
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{applied: map[int]Record{}}
+}
+
+func (s *memoryStore) records(ctx context.Context) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := make([]Record, 0, len(s.applied))
+	for _, r := range s.applied {
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+func (s *memoryStore) insert(ctx context.Context, r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.applied[r.Version]; ok {
+		return errors.New("duplicate version")
+	}
+	s.applied[r.Version] = r
+	return nil
+}
+
+func (s *memoryStore) remove(ctx context.Context, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.applied, version)
+	return nil
+}
+
+func (s *memoryStore) acquire(ctx context.Context, l lock) (lock, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lock != nil {
+		return *s.lock, false, nil
+	}
+	s.lock = &l
+	return l, true, nil
+}
+
+func (s *memoryStore) takeOver(ctx context.Context, held lock, l lock) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lock == nil || *s.lock != held {
+		return false, nil
+	}
+	s.lock = &l
+	return true, nil
+}
+
+func (s *memoryStore) refresh(ctx context.Context, owner string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lock == nil || s.lock.Owner != owner {
+		return false, nil
+	}
+	s.lock.At = at
+	return true, nil
+}
+
+func (s *memoryStore) release(ctx context.Context, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lock != nil && s.lock.Owner == owner {
+		s.lock = nil
+	}
+	return nil
+}
+
+func (s *memoryStore) held() *lock {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lock
+}
+
+// testMigrator runs migrations of the versions that log what they do to ran, the failing version fails
+type testMigrator struct {
+	*Migrator
+	mem *memoryStore
+	mu  sync.Mutex
+	ran []int
+}
+
+func newTestMigrator(versions []int, failing int) *testMigrator {
+	tm := &testMigrator{mem: newMemoryStore()}
+	tm.Migrator = &Migrator{store: tm.mem, lockTimeout: time.Minute}
+	for _, v := range versions {
+		v := v
+		step := func(sign int) func(ctx context.Context, _ *mongo.Database) error {
+			return func(ctx context.Context, _ *mongo.Database) error {
+				if v == failing {
+					return errors.New("failed")
+				}
+				tm.mu.Lock()
+				defer tm.mu.Unlock()
+				tm.ran = append(tm.ran, sign*v)
+				return nil
+			}
+		}
+		tm.migrations = append(tm.migrations, Migration{Version: v, Name: "m", Up: step(1), Down: step(-1)})
+	}
+	return tm
+}
+
+func (tm *testMigrator) applied(t *testing.T) []int {
+	t.Helper()
+	statuses, err := tm.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := []int{}
+	for _, s := range statuses {
+		if s.AppliedAt != nil {
+			versions = append(versions, s.Version)
+		}
+	}
+	return versions
+}
+
+func equal(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRegisteredInOrder(t *testing.T) {
+	all := All()
+	if len(all) == 0 {
+		t.Fatal("no migrations are registered")
+	}
+	versions := make([]int, len(all))
+	for i, m := range all {
+		versions[i] = m.Version
+		if m.Name == "" || m.Up == nil {
+			t.Errorf("migration %d has no name or no Up", m.Version)
+		}
+	}
+	if !sort.IntsAreSorted(versions) {
+		t.Errorf("versions are out of order: %v", versions)
+	}
+	for i := 1; i < len(versions); i++ {
+		if versions[i] == versions[i-1] {
+			t.Errorf("version %d is registered twice", versions[i])
+		}
+	}
+}
+
+func TestUp(t *testing.T) {
+	tm := newTestMigrator([]int{1, 2, 3, 4}, 0)
+	tm.mem.applied[2] = Record{Version: 2, Name: "m", AppliedAt: time.Now()}
+	tm.mem.applied[9] = Record{Version: 9, Name: "newer"}
+
+	pending, err := tm.Up(context.Background(), 3, true)
+	if err != nil || len(pending) != 2 || len(tm.ran) != 0 {
+		t.Fatalf("dry run: got %d pending, ran %v, %v", len(pending), tm.ran, err)
+	}
+
+	before := time.Now().UTC().Truncate(time.Millisecond)
+	if _, err := tm.Up(context.Background(), 3, false); err != nil {
+		t.Fatal(err)
+	}
+	if !equal(tm.ran, []int{1, 3}) {
+		t.Errorf("ran %v, want 1 and 3 in order", tm.ran)
+	}
+	if r := tm.mem.applied[3]; r.Name != "m" || r.AppliedAt.Before(before) {
+		t.Errorf("record of 3 is %+v", r)
+	}
+
+	if _, err := tm.Up(context.Background(), 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if !equal(tm.applied(t), []int{1, 2, 3, 4, 9}) || !equal(tm.ran, []int{1, 3, 4}) {
+		t.Errorf("applied %v after running %v", tm.applied(t), tm.ran)
+	}
+	if tm.mem.held() != nil {
+		t.Errorf("the lock is still held")
+	}
+}
+
+func TestUpStopsAtAFailure(t *testing.T) {
+	tm := newTestMigrator([]int{1, 2, 3}, 2)
+
+	if _, err := tm.Up(context.Background(), 0, false); err == nil {
+		t.Fatal("got no error")
+	}
+	if !equal(tm.applied(t), []int{1}) || !equal(tm.ran, []int{1}) {
+		t.Errorf("applied %v after running %v, want only 1", tm.applied(t), tm.ran)
+	}
+	if tm.mem.held() != nil {
+		t.Errorf("the lock is still held")
+	}
+}
+
+func TestDown(t *testing.T) {
+	tm := newTestMigrator([]int{1, 2, 3}, 0)
+	if _, err := tm.Up(context.Background(), 0, false); err != nil {
+		t.Fatal(err)
+	}
+	tm.ran = nil
+
+	if _, err := tm.Down(context.Background(), 2, false); err != nil {
+		t.Fatal(err)
+	}
+	if !equal(tm.ran, []int{-3, -2}) || !equal(tm.applied(t), []int{1}) {
+		t.Errorf("reverted %v, applied %v", tm.ran, tm.applied(t))
+	}
+
+	// backfills can't be reverted
+	tm.migrations[0].Down = nil
+	if _, err := tm.Down(context.Background(), 1, false); err == nil || !equal(tm.applied(t), []int{1}) {
+		t.Errorf("got %v, applied %v", err, tm.applied(t))
+	}
+}
+
+func TestLocked(t *testing.T) {
+	tm := newTestMigrator([]int{1}, 0)
+	other := lock{Owner: "other", Host: "elsewhere", Pid: 1, At: now()}
+	tm.mem.lock = &other
+
+	if _, err := tm.Up(context.Background(), 0, false); !errors.Is(err, ErrLocked) {
+		t.Fatalf("held lock: got %v, want ErrLocked", err)
+	}
+	if len(tm.ran) != 0 || *tm.mem.held() != other {
+		t.Fatalf("ran %v with the lock of another process", tm.ran)
+	}
+
+	// the other process is gone and its lock went stale
+	other.At = other.At.Add(-2 * time.Minute)
+	tm.mem.lock = &other
+	if _, err := tm.Up(context.Background(), 0, false); err != nil {
+		t.Fatalf("stale lock: %v", err)
+	}
+	if !equal(tm.ran, []int{1}) || tm.mem.held() != nil {
+		t.Errorf("ran %v, lock %+v", tm.ran, tm.mem.held())
+	}
+}
+
+func TestLockIsRefreshed(t *testing.T) {
+	tm := newTestMigrator(nil, 0)
+	tm.lockTimeout = 40 * time.Millisecond
+
+	// a second migrator waits for more than the timeout and still finds the lock held
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- tm.locked(context.Background(), func(ctx context.Context) error {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+			return ctx.Err()
+		})
+	}()
+	<-started
+	time.Sleep(100 * time.Millisecond)
+
+	second := &Migrator{store: tm.mem, lockTimeout: tm.lockTimeout}
+	if err := second.locked(context.Background(), func(ctx context.Context) error { return nil }); !errors.Is(err, ErrLocked) {
+		t.Errorf("got %v, want ErrLocked", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("the holder: %v", err)
+	}
+}
+
+func TestLockTakenOverCancels(t *testing.T) {
+	tm := newTestMigrator(nil, 0)
+	tm.lockTimeout = 40 * time.Millisecond
+
+	err := tm.locked(context.Background(), func(ctx context.Context) error {
+		// another process takes the lock as if this one had hung
+		tm.mem.mu.Lock()
+		tm.mem.lock = &lock{Owner: "other", At: now()}
+		tm.mem.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want the context cancelled", err)
+	}
+	if l := tm.mem.held(); l == nil || l.Owner != "other" {
+		t.Errorf("the lock of the other process was released: %+v", l)
+	}
+}
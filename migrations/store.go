@@ -0,0 +1,107 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// lockID is the _id of the lock document, the records use the version as _id
+const lockID = "lock"
+
+// lock is held by the process running migrations, Owner tells its holders apart and At is refreshed while it runs
+type lock struct {
+	Owner string    `bson:"owner"`
+	Host  string    `bson:"host"`
+	Pid   int       `bson:"pid"`
+	At    time.Time `bson:"at"`
+}
+
+// store keeps the records of the applied migrations and the lock
+type store interface {
+	records(ctx context.Context) ([]Record, error)
+	insert(ctx context.Context, r Record) error
+	remove(ctx context.Context, version int) error
+	// acquire creates the lock, when another one is held it returns that one and false
+	acquire(ctx context.Context, l lock) (lock, bool, error)
+	// takeOver replaces held by l, it returns false when held was refreshed, released or taken over meanwhile
+	takeOver(ctx context.Context, held lock, l lock) (bool, error)
+	// refresh sets the time of the lock of owner, it returns false when owner no longer holds it
+	refresh(ctx context.Context, owner string, at time.Time) (bool, error)
+	// release removes the lock of owner, a lock taken over by another process is left alone
+	release(ctx context.Context, owner string) error
+}
+
+type mongoStore struct {
+	coll *mongo.Collection
+}
+
+func (s *mongoStore) records(ctx context.Context) ([]Record, error) {
+	cursor, err := s.coll.Find(ctx, bson.M{"_id": bson.M{"$type": "number"}})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (s *mongoStore) insert(ctx context.Context, r Record) error {
+	_, err := s.coll.InsertOne(ctx, r)
+	return err
+}
+
+func (s *mongoStore) remove(ctx context.Context, version int) error {
+	_, err := s.coll.DeleteOne(ctx, bson.M{"_id": version})
+	return err
+}
+
+func (s *mongoStore) acquire(ctx context.Context, l lock) (lock, bool, error) {
+	_, err := s.coll.InsertOne(ctx, bson.M{"_id": lockID, "owner": l.Owner, "host": l.Host, "pid": l.Pid, "at": l.At})
+	if err == nil {
+		return l, true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return lock{}, false, err
+	}
+
+	var held lock
+	err = s.coll.FindOne(ctx, bson.M{"_id": lockID}).Decode(&held)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// released since the insert, the zero lock is stale and taking it over fails so the caller tries again
+		return lock{}, false, nil
+	}
+	return held, false, err
+}
+
+func (s *mongoStore) takeOver(ctx context.Context, held lock, l lock) (bool, error) {
+	// locks of older builds have no owner, the time alone tells whether they changed
+	filter := bson.M{"_id": lockID, "at": held.At}
+	if held.Owner != "" {
+		filter["owner"] = held.Owner
+	}
+	result, err := s.coll.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"owner": l.Owner, "host": l.Host, "pid": l.Pid, "at": l.At}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (s *mongoStore) refresh(ctx context.Context, owner string, at time.Time) (bool, error) {
+	result, err := s.coll.UpdateOne(ctx, bson.M{"_id": lockID, "owner": owner}, bson.M{"$set": bson.M{"at": at}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (s *mongoStore) release(ctx context.Context, owner string) error {
+	_, err := s.coll.DeleteOne(ctx, bson.M{"_id": lockID, "owner": owner})
+	return err
+}
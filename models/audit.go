@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// AuditAction is what a change did to its resource
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
+
+// AuditAnonymous is the actor of the changes made on the routes open to everyone, like registering
+const AuditAnonymous = "anonymous"
+
+// AuditEntry records one change made through the api
+type AuditEntry struct {
+	ID   string    `json:"id" bson:"_id,omitempty"`
+	Time time.Time `json:"time" bson:"time"`
+	// Actor is the id of the user who made the change, or AuditAnonymous
+	Actor      string      `json:"actor" bson:"actor"`
+	ActorRole  Role        `json:"actorRole,omitempty" bson:"actorRole,omitempty"`
+	Action     AuditAction `json:"action" bson:"action"`
+	Resource   string      `json:"resource" bson:"resource"`
+	ResourceID string      `json:"resourceId" bson:"resourceId"`
+	// Changes maps every field that changed to {"before": ..., "after": ...}, a create has no before and a delete no
+	// after. It is a bson.M so the nested values are read back as objects and not as lists of keys and values.
+	Changes   bson.M `json:"changes" bson:"changes"`
+	RequestID string `json:"requestId,omitempty" bson:"requestId,omitempty"`
+}
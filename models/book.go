@@ -1,8 +1,14 @@
 package models
 
 type Book struct {
-	ID     string `json:"id" bson:"_id"`
+	ID     string `json:"id" bson:"_id,omitempty"`
 	Title  string `json:"title" bson:"title"`
 	Author string `json:"author" bson:"author"`
 	Year   string `json:"year" bson:"year"`
 }
+
+type BookUpdate struct {
+	Title  string `json:"title,omitempty" bson:"title,omitempty" validate:"max=200"`
+	Author string `json:"author,omitempty" bson:"author,omitempty" validate:"max=100"`
+	Year   string `json:"year,omitempty" bson:"year,omitempty" validate:"len=4,digits"`
+}
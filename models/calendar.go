@@ -0,0 +1,93 @@
+package models
+
+import "time"
+
+// CalendarDay is one day of the calendar of a transport, Capacity and Slots replace the daily limits of the transport when set
+type CalendarDay struct {
+	TransportId string    `json:"transportId" bson:"transportId"`
+	Date        string    `json:"date" bson:"date"`
+	Capacity    *int      `json:"capacity,omitempty" bson:"capacity,omitempty"`
+	Slots       *int      `json:"slots,omitempty" bson:"slots,omitempty"`
+	Closed      bool      `json:"closed" bson:"closed"`
+	Bookings    []Booking `json:"bookings" bson:"bookings"`
+}
+
+// Booking is the quantity an accepted enquiry reserves on the day it is delivered
+type Booking struct {
+	EnquiryId string    `json:"enquiryId" bson:"enquiryId"`
+	Quantity  int       `json:"quantity" bson:"quantity"`
+	At        time.Time `json:"at" bson:"at"`
+}
+
+// CalendarDayUpdate replaces the limits of a day, a nil Capacity or Slots goes back to the limits of the transport
+type CalendarDayUpdate struct {
+	Capacity *int `json:"capacity" validate:"min=1"`
+	Slots    *int `json:"slots" validate:"min=1"`
+	Closed   bool `json:"closed"`
+}
+
+// Reserved is the quantity booked on the day
+func (d CalendarDay) Reserved() int {
+	reserved := 0
+	for _, b := range d.Bookings {
+		reserved += b.Quantity
+	}
+	return reserved
+}
+
+// DayAvailability is what a transport can still take on a day, a Capacity or Slots of 0 is no limit and
+// Remaining is nil when the capacity has no limit
+type DayAvailability struct {
+	Date      string `json:"date"`
+	Capacity  int    `json:"capacity"`
+	Slots     int    `json:"slots"`
+	Reserved  int    `json:"reserved"`
+	Booked    int    `json:"booked"`
+	Remaining *int   `json:"remaining"`
+	Closed    bool   `json:"closed"`
+	Available bool   `json:"available"`
+}
+
+// Availability applies the limits of t, or the ones set on the day, to the bookings of the day.
+// The daily capacity of t is its DailyCapacity, or its Capacity for a single trip when that isn't set.
+func (d CalendarDay) Availability(t Transport) DayAvailability {
+	a := DayAvailability{
+		Date:     d.Date,
+		Capacity: t.DailyCapacity,
+		Slots:    t.DailySlots,
+		Reserved: d.Reserved(),
+		Booked:   len(d.Bookings),
+		Closed:   d.Closed,
+	}
+	if a.Capacity == 0 {
+		a.Capacity = t.Capacity
+	}
+	if d.Capacity != nil {
+		a.Capacity = *d.Capacity
+	}
+	if d.Slots != nil {
+		a.Slots = *d.Slots
+	}
+	if a.Capacity > 0 {
+		remaining := a.Capacity - a.Reserved
+		if remaining < 0 {
+			remaining = 0
+		}
+		a.Remaining = &remaining
+	}
+	a.Available = a.Fits(1)
+	return a
+}
+
+// Fits reports whether one more enquiry of quantity can be booked on the day
+func (a DayAvailability) Fits(quantity int) bool {
+	switch {
+	case a.Closed:
+		return false
+	case a.Slots > 0 && a.Booked >= a.Slots:
+		return false
+	case a.Remaining != nil && quantity > *a.Remaining:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package models
+
+import "testing"
+
+func TestDayAvailability(t *testing.T) {
+	ten, two := 10, 2
+	booked := []Booking{{EnquiryId: "e1", Quantity: 6}}
+
+	tests := []struct {
+		name      string
+		transport Transport
+		day       CalendarDay
+		quantity  int
+		remaining int // -1 for no limit
+		fits      bool
+	}{
+		{"no limits", Transport{}, CalendarDay{Bookings: booked}, 1000, -1, true},
+		{"trip capacity", Transport{Capacity: 8}, CalendarDay{Bookings: booked}, 3, 2, false},
+		{"daily capacity wins over the trip", Transport{Capacity: 8, DailyCapacity: 20}, CalendarDay{Bookings: booked}, 14, 14, true},
+		{"capacity of the day wins", Transport{DailyCapacity: 20}, CalendarDay{Capacity: &ten, Bookings: booked}, 5, 4, false},
+		{"overbooked day", Transport{Capacity: 4}, CalendarDay{Bookings: booked}, 1, 0, false},
+		{"slots taken", Transport{DailySlots: 1}, CalendarDay{Bookings: booked}, 1, -1, false},
+		{"slots of the day win", Transport{DailySlots: 1}, CalendarDay{Slots: &two, Bookings: booked}, 1, -1, true},
+		{"closed", Transport{}, CalendarDay{Closed: true}, 1, -1, false},
+	}
+
+	for _, tt := range tests {
+		a := tt.day.Availability(tt.transport)
+		remaining := -1
+		if a.Remaining != nil {
+			remaining = *a.Remaining
+		}
+		if remaining != tt.remaining {
+			t.Errorf("%s: remaining %d, want %d", tt.name, remaining, tt.remaining)
+		}
+		if got := a.Fits(tt.quantity); got != tt.fits {
+			t.Errorf("%s: fits %d is %v, want %v", tt.name, tt.quantity, got, tt.fits)
+		}
+	}
+}
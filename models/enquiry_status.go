@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+type EnquiryStatus string
+
+const (
+	StatusRequested EnquiryStatus = "requested"
+	StatusQuoted    EnquiryStatus = "quoted"
+	StatusAccepted  EnquiryStatus = "accepted"
+	StatusScheduled EnquiryStatus = "scheduled"
+	StatusInTransit EnquiryStatus = "in_transit"
+	StatusDelivered EnquiryStatus = "delivered"
+	StatusRejected  EnquiryStatus = "rejected"
+	StatusCancelled EnquiryStatus = "cancelled"
+)
+
+// OpenStatuses are the statuses an enquiry can still be edited in, UnbookedStatuses the ones its transport, quantity
+// and date can still change in
+var (
+	OpenStatuses     = []EnquiryStatus{StatusRequested, StatusQuoted, StatusAccepted, StatusScheduled, StatusInTransit}
+	UnbookedStatuses = []EnquiryStatus{StatusRequested, StatusQuoted}
+)
+
+// EnquiryParty is a side of an enquiry, the buyer who made it or the transporter owning its transport
+type EnquiryParty string
+
+const (
+	PartyBuyer       EnquiryParty = "buyer"
+	PartyTransporter EnquiryParty = "transporter"
+)
+
+// EnquiryTransition is an action that moves an enquiry from one of From to To, only the parties in By (and admins)
+// can take it
+type EnquiryTransition struct {
+	Action string
+	From   []EnquiryStatus
+	To     EnquiryStatus
+	By     []EnquiryParty
+}
+
+// EnquiryTransitions is the enquiry lifecycle, delivered, rejected and cancelled are final
+var EnquiryTransitions = []EnquiryTransition{
+	{Action: "quote", From: []EnquiryStatus{StatusRequested, StatusQuoted}, To: StatusQuoted, By: []EnquiryParty{PartyTransporter}},
+	{Action: "accept", From: []EnquiryStatus{StatusQuoted}, To: StatusAccepted, By: []EnquiryParty{PartyBuyer}},
+	{Action: "reject", From: []EnquiryStatus{StatusRequested, StatusQuoted}, To: StatusRejected, By: []EnquiryParty{PartyTransporter}},
+	{Action: "schedule", From: []EnquiryStatus{StatusAccepted}, To: StatusScheduled, By: []EnquiryParty{PartyTransporter}},
+	{Action: "dispatch", From: []EnquiryStatus{StatusScheduled}, To: StatusInTransit, By: []EnquiryParty{PartyTransporter}},
+	{Action: "deliver", From: []EnquiryStatus{StatusInTransit}, To: StatusDelivered, By: []EnquiryParty{PartyTransporter}},
+	{Action: "cancel", From: []EnquiryStatus{StatusRequested, StatusQuoted, StatusAccepted, StatusScheduled}, To: StatusCancelled, By: []EnquiryParty{PartyBuyer, PartyTransporter}},
+}
+
+// FindEnquiryTransition returns the transition named action
+func FindEnquiryTransition(action string) (EnquiryTransition, bool) {
+	for _, t := range EnquiryTransitions {
+		if t.Action == action {
+			return t, true
+		}
+	}
+	return EnquiryTransition{}, false
+}
+
+// Allows reports whether the transition can be taken from status
+func (t EnquiryTransition) Allows(status EnquiryStatus) bool {
+	for _, from := range t.From {
+		if from == status {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusChange is one entry of the append-only status history of an enquiry
+type StatusChange struct {
+	From EnquiryStatus `json:"from,omitempty" bson:"from,omitempty"`
+	To   EnquiryStatus `json:"to" bson:"to"`
+	By   string        `json:"by" bson:"by"`
+	At   time.Time     `json:"at" bson:"at"`
+	Note string        `json:"note,omitempty" bson:"note,omitempty"`
+}
+
+// Booked reports whether the enquiry holds a booking on the calendar of its transport, which it does from accepted on unless cancelled
+func (e GenerateEnquiry) Booked() bool {
+	switch e.Status {
+	case StatusAccepted, StatusScheduled, StatusInTransit, StatusDelivered:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package models
+
+import "testing"
+
+var allStatuses = []EnquiryStatus{
+	StatusRequested, StatusQuoted, StatusAccepted, StatusScheduled,
+	StatusInTransit, StatusDelivered, StatusRejected, StatusCancelled,
+}
+
+func TestEnquiryTransitions(t *testing.T) {
+	// every from → to pair that is allowed, anything else must be refused
+	allowed := map[string]map[EnquiryStatus]EnquiryStatus{
+		"quote":    {StatusRequested: StatusQuoted, StatusQuoted: StatusQuoted},
+		"accept":   {StatusQuoted: StatusAccepted},
+		"reject":   {StatusRequested: StatusRejected, StatusQuoted: StatusRejected},
+		"schedule": {StatusAccepted: StatusScheduled},
+		"dispatch": {StatusScheduled: StatusInTransit},
+		"deliver":  {StatusInTransit: StatusDelivered},
+		"cancel": {
+			StatusRequested: StatusCancelled, StatusQuoted: StatusCancelled,
+			StatusAccepted: StatusCancelled, StatusScheduled: StatusCancelled,
+		},
+	}
+
+	if len(EnquiryTransitions) != len(allowed) {
+		t.Errorf("there are %d transitions, want %d", len(EnquiryTransitions), len(allowed))
+	}
+
+	for action, pairs := range allowed {
+		tr, ok := FindEnquiryTransition(action)
+		if !ok {
+			t.Errorf("%s: not found", action)
+			continue
+		}
+		for _, from := range allStatuses {
+			to, want := pairs[from]
+			if got := tr.Allows(from); got != want {
+				t.Errorf("%s from %s: allowed is %v, want %v", action, from, got, want)
+			}
+			if want && tr.To != to {
+				t.Errorf("%s from %s goes to %s, want %s", action, from, tr.To, to)
+			}
+		}
+	}
+}
+
+func TestFinalStatusesAllowNothing(t *testing.T) {
+	for _, status := range []EnquiryStatus{StatusDelivered, StatusRejected, StatusCancelled} {
+		for _, tr := range EnquiryTransitions {
+			if tr.Allows(status) {
+				t.Errorf("%s is allowed from %s", tr.Action, status)
+			}
+		}
+	}
+}
+
+func TestEnquiryTransitionParties(t *testing.T) {
+	want := map[string][]EnquiryParty{
+		"quote":    {PartyTransporter},
+		"accept":   {PartyBuyer},
+		"reject":   {PartyTransporter},
+		"schedule": {PartyTransporter},
+		"dispatch": {PartyTransporter},
+		"deliver":  {PartyTransporter},
+		"cancel":   {PartyBuyer, PartyTransporter},
+	}
+
+	for _, tr := range EnquiryTransitions {
+		if len(tr.By) != len(want[tr.Action]) {
+			t.Errorf("%s is taken by %v, want %v", tr.Action, tr.By, want[tr.Action])
+			continue
+		}
+		for i := range tr.By {
+			if tr.By[i] != want[tr.Action][i] {
+				t.Errorf("%s is taken by %v, want %v", tr.Action, tr.By, want[tr.Action])
+			}
+		}
+	}
+}
+
+func TestFindUnknownTransition(t *testing.T) {
+	if _, ok := FindEnquiryTransition("teleport"); ok {
+		t.Error("found a transition for an unknown action")
+	}
+}
@@ -1,29 +1,26 @@
 package models
 
-type Product struct {
-	ID          string `json:"_id" bson:"_id"`
-	Name        string `json:"name" bson:"name"`
-	Image       string `json:"image" bson:"image"`
-	Description string `json:"description" bson:"description"`
-	Price       string `json:"price" bson:"price"`
-	MinQuantity int    `json:"minQuantity" bson:"minQuantity"`
-	SellerId    string `json:"sellerId" bson:"sellerId"`
-}
+import "github.com/bmdavis419/fiber-mongo-example/money"
 
-type CreatePDB struct {
-	Name        string `json:"name" bson:"name"`
-	Image       string `json:"image" bson:"image"`
-	Description string `json:"description" bson:"description"`
-	Price       string `json:"price" bson:"price"`
-	MinQuantity int    `json:"minQuantity" bson:"minQuantity"`
-	SellerId    string `json:"sellerId" bson:"sellerId"`
+// Product is sold by a seller, Rating is the average of its reviews
+type Product struct {
+	ID                 string      `json:"_id" bson:"_id,omitempty"`
+	Name               string      `json:"name" bson:"name"`
+	Image              string      `json:"image" bson:"image"`
+	Description        string      `json:"description" bson:"description"`
+	Price              money.Money `json:"price" bson:"price"`
+	MinQuantity        int         `json:"minQuantity" bson:"minQuantity"`
+	SellerId           string      `json:"sellerId" bson:"sellerId"`
+	Rating             float64     `json:"rating" bson:"rating"`
+	RatingCount        int         `json:"ratingCount" bson:"ratingCount"`
+	RatingDistribution [5]int      `json:"ratingDistribution" bson:"ratingDistribution"`
 }
 
+// UpdatePTO holds the fields to change, MinQuantity is a pointer so 0 can be set
 type UpdatePTO struct {
-	Name        string `json:"name,omitempty" bson:"name,omitempty"`
-	Image       string `json:"image,omitempty" bson:"image,omitempty"`
-	Description string `json:"description,omitempty" bson:"description,omitempty"`
-	Price       string `json:"price,omitempty" bson:"price,omitempty"`
-	MinQuantity int    `json:"minQuantity,omitempty" bson:"minQuantity,omitempty"`
-	SellerId    string `json:"sellerId,omitempty" bson:"sellerId,omitempty"`
+	Name        string       `json:"name,omitempty" bson:"name,omitempty" validate:"max=100"`
+	Image       string       `json:"image,omitempty" bson:"image,omitempty" validate:"max=2048"`
+	Description string       `json:"description,omitempty" bson:"description,omitempty" validate:"max=2000"`
+	Price       *money.Money `json:"price,omitempty" bson:"price,omitempty" validate:"money"`
+	MinQuantity *int         `json:"minQuantity,omitempty" bson:"minQuantity,omitempty" validate:"min=0"`
 }
@@ -1,9 +1,23 @@
 package models
 
+import (
+	"github.com/bmdavis419/fiber-mongo-example/redact"
+	"golang.org/x/exp/slog"
+)
+
 type Query struct {
-	ID      string `json:"id" bson:"_id"`
+	ID      string `json:"id" bson:"_id,omitempty"`
 	Name    string `json:"name" bson:"name"`
 	Email   string `json:"email" bson:"email"`
 	Phone   string `json:"phone" bson:"phone"`
 	Message string `json:"message" bson:"message"`
 }
+
+// LogValue logs a query without its message and with the email and phone redacted
+func (q Query) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", q.ID),
+		slog.String("email", redact.Email(q.Email)),
+		slog.String("phone", redact.Phone(q.Phone)),
+	)
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/money"
+)
+
+// Quote is one priced offer for an enquiry, every new quote gets the next version and older ones stay on the enquiry
+type Quote struct {
+	Version   int         `json:"version" bson:"version"`
+	Lines     []QuoteLine `json:"lines" bson:"lines"`
+	Total     money.Money `json:"total" bson:"total"`
+	By        string      `json:"by" bson:"by"`
+	CreatedAt time.Time   `json:"createdAt" bson:"createdAt"`
+	ExpiresAt time.Time   `json:"expiresAt" bson:"expiresAt"`
+	Note      string      `json:"note,omitempty" bson:"note,omitempty"`
+}
+
+// Expired reports whether the quote can no longer be accepted at now
+func (q Quote) Expired(now time.Time) bool {
+	return !now.Before(q.ExpiresAt)
+}
+
+type QuoteLineKind string
+
+const (
+	LineProduct   QuoteLineKind = "product"
+	LineTransport QuoteLineKind = "transport"
+	LineDiscount  QuoteLineKind = "discount"
+	LineDistance  QuoteLineKind = "distance"
+	LineSurcharge QuoteLineKind = "surcharge"
+)
+
+// QuoteLine is one charge of a quote, Amount is negative for discounts
+type QuoteLine struct {
+	Kind        QuoteLineKind `json:"kind" bson:"kind"`
+	Description string        `json:"description" bson:"description"`
+	Quantity    int           `json:"quantity,omitempty" bson:"quantity,omitempty"`
+	UnitPrice   *money.Money  `json:"unitPrice,omitempty" bson:"unitPrice,omitempty"`
+	Amount      money.Money   `json:"amount" bson:"amount"`
+}
+
+// Quote returns the quote of the enquiry with the given version
+func (e GenerateEnquiry) Quote(version int) (Quote, bool) {
+	for _, q := range e.Quotes {
+		if q.Version == version {
+			return q, true
+		}
+	}
+	return Quote{}, false
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// ReviewSubject is what a review is about
+type ReviewSubject string
+
+const (
+	ReviewTransport ReviewSubject = "transport"
+	ReviewProduct   ReviewSubject = "product"
+)
+
+// Review is the rating a buyer gave the transport or the product of one of their delivered enquiries,
+// an enquiry gets at most one review per subject
+type Review struct {
+	ID        string        `json:"_id" bson:"_id,omitempty"`
+	Subject   ReviewSubject `json:"subject" bson:"subject"`
+	SubjectId string        `json:"subjectId" bson:"subjectId"`
+	EnquiryId string        `json:"enquiryId" bson:"enquiryId"`
+	BuyerId   string        `json:"buyerId" bson:"buyerId"`
+	Rating    int           `json:"rating" bson:"rating"`
+	Comment   string        `json:"comment,omitempty" bson:"comment,omitempty"`
+	CreatedAt time.Time     `json:"createdAt" bson:"createdAt"`
+}
+
+// RatingSummary aggregates the reviews of a subject, Distribution[0] counts the 1 star reviews and Distribution[4] the 5 star ones
+type RatingSummary struct {
+	Average      float64
+	Count        int
+	Distribution [5]int
+}
+
+// NewRatingSummary counts the reviews of distribution and averages their ratings to 2 decimals
+func NewRatingSummary(distribution [5]int) RatingSummary {
+	s := RatingSummary{Distribution: distribution}
+	total := 0
+	for i, n := range distribution {
+		s.Count += n
+		total += (i + 1) * n
+	}
+	if s.Count > 0 {
+		s.Average = math.Round(float64(total)/float64(s.Count)*100) / 100
+	}
+	return s
+}
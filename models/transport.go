@@ -1,24 +1,86 @@
 package models
 
+import (
+	"github.com/bmdavis419/fiber-mongo-example/geo"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+)
+
+// Transport is a carrier, Location is where it is based and it only delivers inside its ServiceAreas when it has any.
+// DailyCapacity and DailySlots limit what can be booked on one day, see CalendarDay. Rating is the average of the reviews
+// of the transport and can't be set directly.
 type Transport struct {
-	ID          string   `json:"_id" bson:"_id"`
-	Name        string   `json:"name" bson:"name"`
-	Logo        string   `json:"logo" bson:"logo"`
-	Phone       string   `json:"phone" bson:"phone"`
-	Sevices     []string `json:"services" bson:"services"`
-	Price       float64  `json:"price" bson:"price"`
-	MinQuantity int      `json:"minQuantity" bson:"minQuantity"`
-	Address     string   `json:"address" bson:"address"`
-	Available   bool     `json:"available" bson:"available"`
-	Rating      float64  `json:"rating" bson:"rating"`
+	ID                 string      `json:"_id" bson:"_id,omitempty"`
+	Name               string      `json:"name" bson:"name"`
+	Logo               string      `json:"logo" bson:"logo"`
+	Phone              string      `json:"phone" bson:"phone"`
+	Sevices            []string    `json:"services" bson:"services"`
+	Price              money.Money `json:"price" bson:"price"`
+	MinQuantity        int         `json:"minQuantity" bson:"minQuantity"`
+	Capacity           int         `json:"capacity" bson:"capacity"`
+	DailyCapacity      int         `json:"dailyCapacity" bson:"dailyCapacity"`
+	DailySlots         int         `json:"dailySlots" bson:"dailySlots"`
+	Address            string      `json:"address" bson:"address"`
+	Location           *geo.Point  `json:"location,omitempty" bson:"location,omitempty"`
+	ServiceAreas       []geo.Area  `json:"serviceAreas" bson:"serviceAreas"`
+	Available          bool        `json:"available" bson:"available"`
+	Rating             float64     `json:"rating" bson:"rating"`
+	RatingCount        int         `json:"ratingCount" bson:"ratingCount"`
+	RatingDistribution [5]int      `json:"ratingDistribution" bson:"ratingDistribution"`
+	OwnerId            string      `json:"ownerId" bson:"ownerId"`
+}
+
+// Serves reports whether the transport delivers to address, located at pt when it is known
+func (t Transport) Serves(pt *geo.Point, address string) bool {
+	if len(t.ServiceAreas) == 0 {
+		return true
+	}
+	for _, area := range t.ServiceAreas {
+		if area.Contains(pt, address) {
+			return true
+		}
+	}
+	return false
 }
 
+// TransportUpdate holds the fields to change, the numbers and available are pointers so 0 and false can be set
+type TransportUpdate struct {
+	Name          string       `json:"name,omitempty" bson:"name,omitempty" validate:"max=100"`
+	Logo          string       `json:"logo,omitempty" bson:"logo,omitempty" validate:"max=2048"`
+	Phone         string       `json:"phone,omitempty" bson:"phone,omitempty" validate:"phone"`
+	Sevices       []string     `json:"services,omitempty" bson:"services,omitempty" validate:"max=20"`
+	Price         *money.Money `json:"price,omitempty" bson:"price,omitempty" validate:"money"`
+	MinQuantity   *int         `json:"minQuantity,omitempty" bson:"minQuantity,omitempty" validate:"min=0"`
+	Capacity      *int         `json:"capacity,omitempty" bson:"capacity,omitempty" validate:"min=0"`
+	DailyCapacity *int         `json:"dailyCapacity,omitempty" bson:"dailyCapacity,omitempty" validate:"min=0"`
+	DailySlots    *int         `json:"dailySlots,omitempty" bson:"dailySlots,omitempty" validate:"min=0"`
+	Address       string       `json:"address,omitempty" bson:"address,omitempty" validate:"max=500"`
+	Location      *geo.Point   `json:"location,omitempty" bson:"location,omitempty" validate:"point"`
+	ServiceAreas  []geo.Area   `json:"serviceAreas,omitempty" bson:"serviceAreas,omitempty" validate:"max=50,areas"`
+	Available     *bool        `json:"available,omitempty" bson:"available,omitempty"`
+	Rating        float64      `json:"rating,omitempty" bson:"-" validate:"readonly"`
+}
+
+// GenerateEnquiry is a request to carry a product, DeliveryLocation is sent by the buyer or looked up from DeliveryAddress
 type GenerateEnquiry struct {
-	ID              string `json:"id" bson:"_id"`
-	TransportId     string `json:"transportId" bson:"transportId"`
-	ProductId       string `json:"productId" bson:"productId"`
-	Quantity        int    `json:"quantity" bson:"quantity"`
-	DeliveryAddress string `json:"deliveryAddress" bson:"deliveryAddress"`
-	DateOfDelivery  string `json:"dateOfDelivery" bson:"dateOfDelivery"`
-	Status          string `json:"status" bson:"status"`
+	ID               string         `json:"id" bson:"_id,omitempty"`
+	BuyerId          string         `json:"buyerId" bson:"buyerId"`
+	TransportId      string         `json:"transportId" bson:"transportId"`
+	ProductId        string         `json:"productId" bson:"productId"`
+	Quantity         int            `json:"quantity" bson:"quantity"`
+	DeliveryAddress  string         `json:"deliveryAddress" bson:"deliveryAddress"`
+	DeliveryLocation *geo.Point     `json:"deliveryLocation,omitempty" bson:"deliveryLocation,omitempty"`
+	DateOfDelivery   string         `json:"dateOfDelivery" bson:"dateOfDelivery"`
+	Status           EnquiryStatus  `json:"status" bson:"status"`
+	StatusHistory    []StatusChange `json:"statusHistory" bson:"statusHistory"`
+	Quotes           []Quote        `json:"quotes" bson:"quotes"`
+	AcceptedQuote    int            `json:"acceptedQuote,omitempty" bson:"acceptedQuote,omitempty"`
+}
+
+type EnquiryUpdate struct {
+	TransportId      string     `json:"transportId,omitempty" bson:"transportId,omitempty" validate:"objectid"`
+	ProductId        string     `json:"productId,omitempty" bson:"productId,omitempty" validate:"objectid"`
+	Quantity         int        `json:"quantity,omitempty" bson:"quantity,omitempty" validate:"min=1"`
+	DeliveryAddress  string     `json:"deliveryAddress,omitempty" bson:"deliveryAddress,omitempty" validate:"max=500"`
+	DeliveryLocation *geo.Point `json:"deliveryLocation,omitempty" bson:"deliveryLocation,omitempty" validate:"point"`
+	DateOfDelivery   string     `json:"dateOfDelivery,omitempty" bson:"dateOfDelivery,omitempty" validate:"date"`
 }
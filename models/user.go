@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type Role string
+
+const (
+	RoleBuyer       Role = "buyer"
+	RoleSeller      Role = "seller"
+	RoleTransporter Role = "transporter"
+	RoleAdmin       Role = "admin"
+)
+
+type User struct {
+	ID           string    `json:"id" bson:"_id,omitempty"`
+	Email        string    `json:"email" bson:"email"`
+	PasswordHash string    `json:"-" bson:"passwordHash"`
+	Role         Role      `json:"role" bson:"role"`
+	CreatedAt    time.Time `json:"createdAt" bson:"createdAt"`
+}
@@ -0,0 +1,223 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultCurrency is used for prices given without a currency
+const DefaultCurrency = "INR"
+
+// exponents holds the number of decimals of the minor unit of each supported ISO 4217 currency
+var exponents = map[string]int{
+	"AED": 2, "AUD": 2, "BDT": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2,
+	"EUR": 2, "GBP": 2, "HKD": 2, "IDR": 2, "INR": 2, "JPY": 0, "KES": 2, "KRW": 0,
+	"KWD": 3, "LKR": 2, "MXN": 2, "NGN": 2, "NPR": 2, "NZD": 2, "OMR": 3, "PKR": 2,
+	"SAR": 2, "SGD": 2, "THB": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// ErrUnknownCurrency is returned for currencies that are not ISO 4217 codes this package knows
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// ErrCurrencyMismatch is returned when adding amounts of different currencies
+var ErrCurrencyMismatch = errors.New("amounts have different currencies")
+
+// Money is an amount in the minor unit of its currency, e.g. {1050 INR} is ₹10.50.
+// In MongoDB it is stored as {amount: Decimal128("10.50"), currency: "INR"} so it sorts and compares as a number.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// Known reports whether currency is a supported ISO 4217 code
+func Known(currency string) bool {
+	_, ok := exponents[currency]
+	return ok
+}
+
+// Parse reads a decimal amount in the major unit, e.g. "10.50", an empty currency means DefaultCurrency
+func Parse(amount string, currency string) (Money, error) {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	exp, ok := exponents[currency]
+	if !ok {
+		return Money{}, ErrUnknownCurrency
+	}
+
+	amount = strings.TrimSpace(amount)
+	sign := ""
+	if strings.HasPrefix(amount, "-") {
+		sign, amount = "-", amount[1:]
+	}
+	whole, frac, _ := strings.Cut(amount, ".")
+	if whole == "" || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("'%s' is not a decimal amount", amount)
+	}
+	if len(frac) > exp {
+		return Money{}, decimalsError(currency, exp)
+	}
+
+	minor, err := strconv.ParseInt(sign+whole+frac+strings.Repeat("0", exp-len(frac)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("'%s' is out of range", amount)
+	}
+
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// FromDecimal converts an amount in the major unit, the way it is stored in MongoDB
+func FromDecimal(d primitive.Decimal128, currency string) (Money, error) {
+	if d.IsNaN() || d.IsInf() != 0 {
+		return Money{}, fmt.Errorf("'%s' is not a decimal amount", d)
+	}
+	exp, ok := exponents[currency]
+	if !ok {
+		return Money{}, ErrUnknownCurrency
+	}
+
+	// shift the decimal point so the amount is counted in minor units
+	coef, e, err := d.BigInt()
+	if err != nil {
+		return Money{}, err
+	}
+	e += exp
+	ten := big.NewInt(10)
+	for ; e > 0; e-- {
+		coef.Mul(coef, ten)
+	}
+	for ; e < 0; e++ {
+		var rem big.Int
+		coef.QuoRem(coef, ten, &rem)
+		if rem.Sign() != 0 {
+			return Money{}, decimalsError(currency, exp)
+		}
+	}
+	if !coef.IsInt64() {
+		return Money{}, fmt.Errorf("'%s' is out of range", d)
+	}
+
+	return Money{Amount: coef.Int64(), Currency: currency}, nil
+}
+
+// Decimal returns the amount in the major unit
+func (m Money) Decimal() primitive.Decimal128 {
+	d, _ := primitive.ParseDecimal128FromBigInt(big.NewInt(m.Amount), -exponents[m.Currency])
+	return d
+}
+
+// Add returns m + o, the zero Money can be added to any currency
+func (m Money) Add(o Money) (Money, error) {
+	switch {
+	case m.IsZero():
+		return o, nil
+	case o.IsZero():
+		return m, nil
+	case m.Currency != o.Currency:
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Mul returns m times n
+func (m Money) Mul(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.Currency}
+}
+
+// Scale returns m times num/den, den must be positive, rounded half away from zero to the minor unit
+func (m Money) Scale(num int64, den int64) Money {
+	p := m.Amount * num
+	q, r := p/den, p%den
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= den {
+		if p < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Money{Amount: q, Currency: m.Currency}
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// String formats the amount in the major unit followed by the currency, e.g. "10.50 INR"
+func (m Money) String() string {
+	return m.Decimal().String() + " " + m.Currency
+}
+
+// IsZero reports whether m is unset, bson omitempty relies on it
+func (m Money) IsZero() bool {
+	return m.Amount == 0 && m.Currency == ""
+}
+
+type document struct {
+	Amount   primitive.Decimal128 `bson:"amount"`
+	Currency string               `bson:"currency"`
+}
+
+// MarshalBSONValue writes the zero Money as null, it has no currency to store
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if m.IsZero() {
+		return bsontype.Null, nil, nil
+	}
+	data, err := bson.Marshal(document{Amount: m.Decimal(), Currency: m.Currency})
+	return bsontype.EmbeddedDocument, data, err
+}
+
+// UnmarshalBSONValue also reads the plain string and number prices stored before Money existed, in DefaultCurrency
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+	var err error
+
+	switch t {
+	case bsontype.EmbeddedDocument:
+		var doc document
+		if err := raw.Unmarshal(&doc); err != nil {
+			return err
+		}
+		*m, err = FromDecimal(doc.Amount, doc.Currency)
+	case bsontype.String:
+		*m, err = Parse(raw.StringValue(), DefaultCurrency)
+	case bsontype.Double:
+		*m, err = Parse(strconv.FormatFloat(raw.Double(), 'f', -1, 64), DefaultCurrency)
+	case bsontype.Int32, bsontype.Int64:
+		*m, err = Parse(strconv.FormatInt(raw.AsInt64(), 10), DefaultCurrency)
+	case bsontype.Decimal128:
+		*m, err = FromDecimal(raw.Decimal128(), DefaultCurrency)
+	case bsontype.Null, bsontype.Undefined:
+		*m = Money{}
+	default:
+		err = fmt.Errorf("cannot decode %s into Money", t)
+	}
+
+	return err
+}
+
+func decimalsError(currency string, exp int) error {
+	if exp == 0 {
+		return fmt.Errorf("%s amounts have no decimals", currency)
+	}
+	return fmt.Errorf("%s amounts have at most %d decimals", currency, exp)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,254 @@
+package money
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     Money
+		wantErr  bool
+	}{
+		{"10.50", "INR", Money{1050, "INR"}, false},
+		{"10.5", "INR", Money{1050, "INR"}, false},
+		{"10", "", Money{1000, "INR"}, false},
+		{" 7.25 ", "USD", Money{725, "USD"}, false},
+		{"0", "INR", Money{0, "INR"}, false},
+		{"-3.10", "INR", Money{-310, "INR"}, false},
+		{"1500", "JPY", Money{1500, "JPY"}, false},
+		{"1.234", "KWD", Money{1234, "KWD"}, false},
+		{"10.505", "INR", Money{}, true},
+		{"1.5", "JPY", Money{}, true},
+		{"10.", "INR", Money{1000, "INR"}, false},
+		{".50", "INR", Money{}, true},
+		{"1e3", "INR", Money{}, true},
+		{"ten", "INR", Money{}, true},
+		{"--1", "INR", Money{}, true},
+		{"", "INR", Money{}, true},
+		{"99999999999999999999", "INR", Money{}, true},
+		{"10", "XYZ", Money{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			got, err := Parse(tt.amount, tt.currency)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Parse("1", "XYZ"); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("unknown currency: got %v, want ErrUnknownCurrency", err)
+	}
+}
+
+func TestFromDecimal(t *testing.T) {
+	tests := []struct {
+		decimal  string
+		currency string
+		want     Money
+		wantErr  bool
+	}{
+		{"10.50", "INR", Money{1050, "INR"}, false},
+		{"10.500", "INR", Money{1050, "INR"}, false},
+		{"1E+2", "INR", Money{10000, "INR"}, false},
+		{"-0.01", "INR", Money{-1, "INR"}, false},
+		{"1500", "JPY", Money{1500, "JPY"}, false},
+		{"10.505", "INR", Money{}, true},
+		{"0.5", "JPY", Money{}, true},
+		{"NaN", "INR", Money{}, true},
+		{"Infinity", "INR", Money{}, true},
+		{"1E+30", "INR", Money{}, true},
+		{"10", "XYZ", Money{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.decimal+" "+tt.currency, func(t *testing.T) {
+			got, err := FromDecimal(mustDecimal(t, tt.decimal), tt.currency)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScale(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   int64
+		num, den int64
+		want     int64
+	}{
+		{"exact", 1000, 3, 2, 1500},
+		{"rounds down below half", 1001, 1, 4, 250},
+		{"rounds half up", 1002, 1, 4, 251},
+		{"rounds above half up", 1003, 1, 4, 251},
+		{"negative rounds down below half", -1001, 1, 4, -250},
+		{"negative rounds half away from zero", -1002, 1, 4, -251},
+		{"percentage", 12345, 18, 100, 2222},
+		{"zero", 0, 7, 3, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Money{tt.amount, "INR"}.Scale(tt.num, tt.den)
+			if got != (Money{tt.want, "INR"}) {
+				t.Errorf("got %+v, want %d INR", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    Money
+		want    Money
+		wantErr bool
+	}{
+		{"same currency", Money{100, "INR"}, Money{250, "INR"}, Money{350, "INR"}, false},
+		{"negative", Money{100, "INR"}, Money{-250, "INR"}, Money{-150, "INR"}, false},
+		{"zero on the left", Money{}, Money{250, "USD"}, Money{250, "USD"}, false},
+		{"zero on the right", Money{250, "USD"}, Money{}, Money{250, "USD"}, false},
+		{"other currency", Money{100, "INR"}, Money{100, "USD"}, Money{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Add(tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrCurrencyMismatch) {
+				t.Errorf("got %v, want ErrCurrencyMismatch", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{Money{1050, "INR"}, "10.50 INR"},
+		{Money{-5, "INR"}, "-0.05 INR"},
+		{Money{1500, "JPY"}, "1500 JPY"},
+		{Money{1234, "KWD"}, "1.234 KWD"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// wrapped holds a price the way documents do
+type wrapped struct {
+	Price Money `bson:"price"`
+}
+
+func TestBSONRoundTrip(t *testing.T) {
+	for _, m := range []Money{{1050, "INR"}, {-310, "INR"}, {1500, "JPY"}, {1234, "KWD"}, {}} {
+		data, err := bson.Marshal(wrapped{m})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got wrapped
+		if err := bson.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Price != m {
+			t.Errorf("got %+v, want %+v", got.Price, m)
+		}
+	}
+}
+
+func TestMarshalBSONValue(t *testing.T) {
+	data, err := bson.Marshal(wrapped{Money{1050, "INR"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Price struct {
+			Amount   primitive.Decimal128 `bson:"amount"`
+			Currency string               `bson:"currency"`
+		} `bson:"price"`
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Price.Amount.String() != "10.50" || doc.Price.Currency != "INR" {
+		t.Errorf("stored as %s %s, want 10.50 INR", doc.Price.Amount, doc.Price.Currency)
+	}
+
+	// the zero Money has no currency and is stored as null
+	data, _ = bson.Marshal(wrapped{})
+	if v := bson.Raw(data).Lookup("price"); v.Type != bson.TypeNull {
+		t.Errorf("zero Money stored as %s, want null", v.Type)
+	}
+}
+
+func TestUnmarshalLegacyPrices(t *testing.T) {
+	tests := []struct {
+		name    string
+		price   interface{}
+		want    Money
+		wantErr bool
+	}{
+		{"string", "12.50", Money{1250, DefaultCurrency}, false},
+		{"string without decimals", "40", Money{4000, DefaultCurrency}, false},
+		{"double", 12.5, Money{1250, DefaultCurrency}, false},
+		{"int32", int32(12), Money{1200, DefaultCurrency}, false},
+		{"int64", int64(-3), Money{-300, DefaultCurrency}, false},
+		{"decimal", mustDecimal(t, "12.50"), Money{1250, DefaultCurrency}, false},
+		{"null", nil, Money{}, false},
+		{"string with too many decimals", "12.505", Money{}, true},
+		{"double with too many decimals", 12.505, Money{}, true},
+		{"not a number", "free", Money{}, true},
+		{"boolean", true, Money{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := bson.Marshal(bson.M{"price": tt.price})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got wrapped
+			err = bson.Unmarshal(data, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Price != tt.want {
+				t.Errorf("got %+v, want %+v", got.Price, tt.want)
+			}
+		})
+	}
+}
+
+func mustDecimal(t *testing.T, s string) primitive.Decimal128 {
+	t.Helper()
+	d, err := primitive.ParseDecimal128(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
@@ -0,0 +1,129 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+)
+
+// Tier takes DiscountBps (hundredths of a percent) off the transport charge of orders of at least MinQuantity
+type Tier struct {
+	MinQuantity int
+	DiscountBps int64
+}
+
+// DefaultTiers gives 5% off from 100 units, 10% from 500 and 15% from 1000
+var DefaultTiers = []Tier{
+	{MinQuantity: 100, DiscountBps: 500},
+	{MinQuantity: 500, DiscountBps: 1000},
+	{MinQuantity: 1000, DiscountBps: 1500},
+}
+
+// Surcharge is a fixed extra charge, e.g. for loading or tolls
+type Surcharge struct {
+	Name   string
+	Amount money.Money
+}
+
+// Input is everything a quote is calculated from, the distance and surcharges are optional
+type Input struct {
+	Product    models.Product
+	Transport  models.Transport
+	Quantity   int
+	DistanceKm int
+	RatePerKm  money.Money
+	Surcharges []Surcharge
+	Tiers      []Tier
+}
+
+// ErrNoPrice is returned when the product or the transport has no price to quote from
+var ErrNoPrice = errors.New("has no price")
+
+// Calculate prices the product and its transport for the quantity and returns the quote lines and their total:
+// the product and transport unit prices times the quantity, the best matching tier discount on the transport,
+// the distance times the rate per km and the surcharges.
+func Calculate(in Input) ([]models.QuoteLine, money.Money, error) {
+	if in.Product.Price.IsZero() {
+		return nil, money.Money{}, fmt.Errorf("product %w", ErrNoPrice)
+	}
+	if in.Transport.Price.IsZero() {
+		return nil, money.Money{}, fmt.Errorf("transport %w", ErrNoPrice)
+	}
+
+	productPrice, transportPrice := in.Product.Price, in.Transport.Price
+	transport := transportPrice.Mul(int64(in.Quantity))
+	lines := []models.QuoteLine{
+		{
+			Kind:        models.LineProduct,
+			Description: in.Product.Name,
+			Quantity:    in.Quantity,
+			UnitPrice:   &productPrice,
+			Amount:      productPrice.Mul(int64(in.Quantity)),
+		},
+		{
+			Kind:        models.LineTransport,
+			Description: in.Transport.Name,
+			Quantity:    in.Quantity,
+			UnitPrice:   &transportPrice,
+			Amount:      transport,
+		},
+	}
+
+	if tier, ok := bestTier(in.Tiers, in.Quantity); ok {
+		lines = append(lines, models.QuoteLine{
+			Kind:        models.LineDiscount,
+			Description: fmt.Sprintf("%s%% off transport from %d units", percent(tier.DiscountBps), tier.MinQuantity),
+			Amount:      transport.Scale(tier.DiscountBps, 10000).Neg(),
+		})
+	}
+
+	if in.DistanceKm > 0 {
+		rate := in.RatePerKm
+		lines = append(lines, models.QuoteLine{
+			Kind:        models.LineDistance,
+			Description: fmt.Sprintf("%d km", in.DistanceKm),
+			Quantity:    in.DistanceKm,
+			UnitPrice:   &rate,
+			Amount:      rate.Mul(int64(in.DistanceKm)),
+		})
+	}
+
+	for _, s := range in.Surcharges {
+		lines = append(lines, models.QuoteLine{
+			Kind:        models.LineSurcharge,
+			Description: s.Name,
+			Amount:      s.Amount,
+		})
+	}
+
+	// every line has to be in the same currency to be added up
+	total := money.Money{}
+	for _, line := range lines {
+		var err error
+		total, err = total.Add(line.Amount)
+		if err != nil {
+			return nil, money.Money{}, fmt.Errorf("%s: %w", line.Description, err)
+		}
+	}
+
+	return lines, total, nil
+}
+
+// bestTier returns the tier with the highest MinQuantity that quantity reaches
+func bestTier(tiers []Tier, quantity int) (Tier, bool) {
+	best, found := Tier{}, false
+	for _, t := range tiers {
+		if quantity >= t.MinQuantity && (!found || t.MinQuantity > best.MinQuantity) {
+			best, found = t, true
+		}
+	}
+	return best, found
+}
+
+// percent formats basis points as a percentage, e.g. 1250 as 12.5
+func percent(bps int64) string {
+	return strconv.FormatFloat(float64(bps)/100, 'f', -1, 64)
+}
@@ -0,0 +1,198 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+)
+
+func inr(amount int64) money.Money {
+	return money.Money{Amount: amount, Currency: "INR"}
+}
+
+func input(quantity int) Input {
+	return Input{
+		Product:   models.Product{Name: "rice", Price: inr(1250)},
+		Transport: models.Transport{Name: "truck", Price: inr(200)},
+		Quantity:  quantity,
+		Tiers:     DefaultTiers,
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    func() Input
+		kinds []models.QuoteLineKind
+		// amounts of the lines in order and the total, in paise
+		amounts []int64
+		total   int64
+	}{
+		{
+			name:    "below every tier",
+			in:      func() Input { return input(10) },
+			kinds:   []models.QuoteLineKind{models.LineProduct, models.LineTransport},
+			amounts: []int64{12500, 2000},
+			total:   14500,
+		},
+		{
+			name:    "first tier",
+			in:      func() Input { return input(100) },
+			kinds:   []models.QuoteLineKind{models.LineProduct, models.LineTransport, models.LineDiscount},
+			amounts: []int64{125000, 20000, -1000},
+			total:   144000,
+		},
+		{
+			name:    "best tier wins",
+			in:      func() Input { return input(1000) },
+			kinds:   []models.QuoteLineKind{models.LineProduct, models.LineTransport, models.LineDiscount},
+			amounts: []int64{1250000, 200000, -30000},
+			total:   1420000,
+		},
+		{
+			name: "discount rounds half away from zero",
+			in: func() Input {
+				in := input(101)
+				in.Transport.Price = inr(190) // 5% of 191.90 is 9.595
+				return in
+			},
+			kinds:   []models.QuoteLineKind{models.LineProduct, models.LineTransport, models.LineDiscount},
+			amounts: []int64{126250, 19190, -960},
+			total:   144480,
+		},
+		{
+			name: "distance and surcharges",
+			in: func() Input {
+				in := input(10)
+				in.DistanceKm = 150
+				in.RatePerKm = inr(1500)
+				in.Surcharges = []Surcharge{{Name: "loading", Amount: inr(50000)}, {Name: "tolls", Amount: inr(12075)}}
+				return in
+			},
+			kinds:   []models.QuoteLineKind{models.LineProduct, models.LineTransport, models.LineDistance, models.LineSurcharge, models.LineSurcharge},
+			amounts: []int64{12500, 2000, 225000, 50000, 12075},
+			total:   301575,
+		},
+		{
+			name: "negative surcharge",
+			in: func() Input {
+				in := input(10)
+				in.Surcharges = []Surcharge{{Name: "goodwill", Amount: inr(-500)}}
+				return in
+			},
+			kinds:   []models.QuoteLineKind{models.LineProduct, models.LineTransport, models.LineSurcharge},
+			amounts: []int64{12500, 2000, -500},
+			total:   14000,
+		},
+		{
+			name: "no tiers",
+			in: func() Input {
+				in := input(1000)
+				in.Tiers = nil
+				return in
+			},
+			kinds:   []models.QuoteLineKind{models.LineProduct, models.LineTransport},
+			amounts: []int64{1250000, 200000},
+			total:   1450000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, total, err := Calculate(tt.in())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lines) != len(tt.kinds) {
+				t.Fatalf("got %d lines %+v, want %d", len(lines), lines, len(tt.kinds))
+			}
+			for i, line := range lines {
+				if line.Kind != tt.kinds[i] || line.Amount != inr(tt.amounts[i]) {
+					t.Errorf("line %d is %s %+v, want %s %d INR", i, line.Kind, line.Amount, tt.kinds[i], tt.amounts[i])
+				}
+			}
+			if total != inr(tt.total) {
+				t.Errorf("total is %+v, want %d INR", total, tt.total)
+			}
+		})
+	}
+}
+
+func TestCalculateErrors(t *testing.T) {
+	usd := money.Money{Amount: 100, Currency: "USD"}
+
+	tests := []struct {
+		name string
+		in   func() Input
+		want error
+	}{
+		{"product without price", func() Input {
+			in := input(10)
+			in.Product.Price = money.Money{}
+			return in
+		}, ErrNoPrice},
+		{"transport without price", func() Input {
+			in := input(10)
+			in.Transport.Price = money.Money{}
+			return in
+		}, ErrNoPrice},
+		{"transport in another currency", func() Input {
+			in := input(10)
+			in.Transport.Price = usd
+			return in
+		}, money.ErrCurrencyMismatch},
+		{"rate in another currency", func() Input {
+			in := input(10)
+			in.DistanceKm, in.RatePerKm = 10, usd
+			return in
+		}, money.ErrCurrencyMismatch},
+		{"surcharge in another currency", func() Input {
+			in := input(10)
+			in.Surcharges = []Surcharge{{Name: "tolls", Amount: usd}}
+			return in
+		}, money.ErrCurrencyMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, total, err := Calculate(tt.in())
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if lines != nil || !total.IsZero() {
+				t.Errorf("got lines %+v and total %+v with the error", lines, total)
+			}
+		})
+	}
+}
+
+func TestBestTier(t *testing.T) {
+	tests := []struct {
+		quantity int
+		want     int64
+		found    bool
+	}{
+		{99, 0, false},
+		{100, 500, true},
+		{499, 500, true},
+		{500, 1000, true},
+		{5000, 1500, true},
+	}
+
+	for _, tt := range tests {
+		tier, found := bestTier(DefaultTiers, tt.quantity)
+		if found != tt.found || tier.DiscountBps != tt.want {
+			t.Errorf("quantity %d: got %+v %v, want %d bps %v", tt.quantity, tier, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	for bps, want := range map[int64]string{500: "5", 1250: "12.5", 1: "0.01"} {
+		if got := percent(bps); got != want {
+			t.Errorf("percent(%d) = %q, want %q", bps, got, want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// Package redact hides most of the personal data that ends up in logs, like emails and phone numbers
+package redact
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Email keeps the first letter and the domain of an email, like a***@example.com
+func Email(email string) string {
+	user, domain, ok := strings.Cut(email, "@")
+	if !ok || user == "" {
+		return "***"
+	}
+	first, _ := utf8.DecodeRuneInString(user)
+	return string(first) + "***@" + domain
+}
+
+// Phone keeps the last two digits of a phone number, like ********21. The stars of a redacted number count as digits
+// so redacting it again changes nothing.
+func Phone(phone string) string {
+	digits := make([]rune, 0, len(phone))
+	for _, r := range phone {
+		if (r >= '0' && r <= '9') || r == '*' {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) <= 2 {
+		return strings.Repeat("*", len(digits))
+	}
+	return strings.Repeat("*", len(digits)-2) + string(digits[len(digits)-2:])
+}
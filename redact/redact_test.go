@@ -0,0 +1,43 @@
+package redact
+
+import "testing"
+
+func TestEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"asha@example.com", "a***@example.com"},
+		{"a@example.com", "a***@example.com"},
+		{"élodie@example.fr", "é***@example.fr"},
+		{"用户@example.cn", "用***@example.cn"},
+		{"@example.com", "***"},
+		{"not an email", "***"},
+		{"", "***"},
+	}
+
+	for _, tt := range tests {
+		if got := Email(tt.email); got != tt.want {
+			t.Errorf("Email(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestPhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+	}{
+		{"+91 98765 43221", "**********21"},
+		{"12", "**"},
+		{"", ""},
+		{"(+91) ९८", "**"},
+		{"**********21", "**********21"},
+	}
+
+	for _, tt := range tests {
+		if got := Phone(tt.phone); got != tt.want {
+			t.Errorf("Phone(%q) = %q, want %q", tt.phone, got, tt.want)
+		}
+	}
+}
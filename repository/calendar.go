@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoCalendarRepository stores one document per transport and date, unique thanks to the index of migration 3
+type mongoCalendarRepository struct {
+	coll *mongo.Collection
+}
+
+func (r *mongoCalendarRepository) Days(ctx context.Context, transportId string, from string, to string) ([]models.CalendarDay, error) {
+	filter := bson.M{"transportId": transportId, "date": bson.M{"$gte": from, "$lte": to}}
+	return r.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "date", Value: 1}}))
+}
+
+func (r *mongoCalendarRepository) Day(ctx context.Context, transportId string, date string) (models.CalendarDay, error) {
+	var day models.CalendarDay
+	err := r.coll.FindOne(ctx, bson.M{"transportId": transportId, "date": date}, options.FindOne().SetComment(comment(ctx))).Decode(&day)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return emptyDay(transportId, date), nil
+	}
+	return day, err
+}
+
+func (r *mongoCalendarRepository) On(ctx context.Context, date string) ([]models.CalendarDay, error) {
+	return r.find(ctx, bson.M{"date": date})
+}
+
+func (r *mongoCalendarRepository) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]models.CalendarDay, error) {
+	cursor, err := r.coll.Find(ctx, filter, append(opts, options.Find().SetComment(comment(ctx)))...)
+	if err != nil {
+		return nil, err
+	}
+
+	days := make([]models.CalendarDay, 0)
+	if err := cursor.All(ctx, &days); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+func (r *mongoCalendarRepository) SetDay(ctx context.Context, transportId string, date string, update *models.CalendarDayUpdate) (models.CalendarDay, error) {
+	set, unset := bson.M{"closed": update.Closed}, bson.M{}
+	if update.Capacity != nil {
+		set["capacity"] = *update.Capacity
+	} else {
+		unset["capacity"] = ""
+	}
+	if update.Slots != nil {
+		set["slots"] = *update.Slots
+	} else {
+		unset["slots"] = ""
+	}
+	changes := bson.M{"$set": set, "$setOnInsert": bson.M{"bookings": bson.A{}}}
+	if len(unset) > 0 {
+		changes["$unset"] = unset
+	}
+
+	// find-and-modify returns the day as it was, nothing when the upsert created it
+	before := emptyDay(transportId, date)
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before).SetComment(comment(ctx))
+	err := r.coll.FindOneAndUpdate(ctx, bson.M{"transportId": transportId, "date": date}, changes, opts).Decode(&before)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return before, err
+	}
+
+	return setDay(ctx, before, update), nil
+}
+
+func (r *mongoCalendarRepository) Reserve(ctx context.Context, transportId string, date string, booking models.Booking, capacity int, slots int) error {
+	key := bson.M{"transportId": transportId, "date": date}
+
+	// create the day first so the booking only has to match it, losing the race to create it is fine
+	_, err := r.coll.UpdateOne(ctx, key,
+		bson.M{"$setOnInsert": bson.M{"closed": false, "bookings": bson.A{}}},
+		options.Update().SetUpsert(true).SetComment(comment(ctx)),
+	)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	// the limits are part of the filter so concurrent bookings can't go over them
+	filter := bson.M{"transportId": transportId, "date": date, "closed": bson.M{"$ne": true}}
+	limits := bson.A{}
+	if capacity > 0 {
+		reserved := bson.M{"$add": bson.A{bson.M{"$sum": "$bookings.quantity"}, booking.Quantity}}
+		limits = append(limits, bson.M{"$lte": bson.A{reserved, capacity}})
+	}
+	if slots > 0 {
+		limits = append(limits, bson.M{"$lt": bson.A{bson.M{"$size": "$bookings"}, slots}})
+	}
+	if len(limits) > 0 {
+		filter["$expr"] = bson.M{"$and": limits}
+	}
+
+	result, err := r.coll.UpdateOne(ctx, filter, bson.M{"$push": bson.M{"bookings": booking}}, options.Update().SetComment(comment(ctx)))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrFullyBooked
+	}
+
+	return nil
+}
+
+func (r *mongoCalendarRepository) Release(ctx context.Context, transportId string, date string, enquiryId string) error {
+	_, err := r.coll.UpdateOne(ctx,
+		bson.M{"transportId": transportId, "date": date},
+		bson.M{"$pull": bson.M{"bookings": bson.M{"enquiryId": enquiryId}}},
+		options.Update().SetComment(comment(ctx)),
+	)
+	return err
+}
+
+func (r *mongoCalendarRepository) DeleteTransport(ctx context.Context, transportId string) error {
+	_, err := r.coll.DeleteMany(ctx, bson.M{"transportId": transportId}, options.Delete().SetComment(comment(ctx)))
+	return err
+}
+
+// memoryCalendarRepository keys the days by transport and date
+type memoryCalendarRepository struct {
+	mu   sync.RWMutex
+	days map[string]models.CalendarDay
+}
+
+func dayKey(transportId string, date string) string {
+	return transportId + "/" + date
+}
+
+// clone copies s, strings coming from fiber point into buffers it reuses once the request is done
+func clone(s string) string {
+	return string([]byte(s))
+}
+
+func emptyDay(transportId string, date string) models.CalendarDay {
+	return models.CalendarDay{TransportId: transportId, Date: date, Bookings: []models.Booking{}}
+}
+
+// setDay returns before with the limits of update, the way SetDay changes a day
+func setDay(ctx context.Context, before models.CalendarDay, update *models.CalendarDayUpdate) models.CalendarDay {
+	day := copyDay(before)
+	day.Capacity, day.Slots, day.Closed = update.Capacity, update.Slots, update.Closed
+
+	reportChange(ctx, before, day)
+	return day
+}
+
+// copyDay keeps callers from sharing the bookings of a stored day
+func copyDay(day models.CalendarDay) models.CalendarDay {
+	day.Bookings = append([]models.Booking{}, day.Bookings...)
+	return day
+}
+
+func (r *memoryCalendarRepository) Days(ctx context.Context, transportId string, from string, to string) ([]models.CalendarDay, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	days := make([]models.CalendarDay, 0)
+	for _, day := range r.days {
+		if day.TransportId == transportId && day.Date >= from && day.Date <= to {
+			days = append(days, copyDay(day))
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+
+	return days, nil
+}
+
+func (r *memoryCalendarRepository) Day(ctx context.Context, transportId string, date string) (models.CalendarDay, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	day, ok := r.days[dayKey(transportId, date)]
+	if !ok {
+		return emptyDay(transportId, date), nil
+	}
+	return copyDay(day), nil
+}
+
+func (r *memoryCalendarRepository) On(ctx context.Context, date string) ([]models.CalendarDay, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	days := make([]models.CalendarDay, 0)
+	for _, day := range r.days {
+		if day.Date == date {
+			days = append(days, copyDay(day))
+		}
+	}
+	return days, nil
+}
+
+func (r *memoryCalendarRepository) SetDay(ctx context.Context, transportId string, date string, update *models.CalendarDayUpdate) (models.CalendarDay, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := dayKey(transportId, date)
+	day, ok := r.days[key]
+	if !ok {
+		day = emptyDay(clone(transportId), clone(date))
+	}
+	day = setDay(ctx, copyDay(day), update)
+	r.days[key] = day
+
+	return copyDay(day), nil
+}
+
+func (r *memoryCalendarRepository) Reserve(ctx context.Context, transportId string, date string, booking models.Booking, capacity int, slots int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := dayKey(transportId, date)
+	day, ok := r.days[key]
+	if !ok {
+		day = emptyDay(clone(transportId), clone(date))
+	}
+	booking.EnquiryId = clone(booking.EnquiryId)
+
+	switch {
+	case day.Closed:
+		return ErrFullyBooked
+	case capacity > 0 && day.Reserved()+booking.Quantity > capacity:
+		return ErrFullyBooked
+	case slots > 0 && len(day.Bookings) >= slots:
+		return ErrFullyBooked
+	}
+
+	day.Bookings = append(copyDay(day).Bookings, booking)
+	r.days[key] = day
+
+	return nil
+}
+
+func (r *memoryCalendarRepository) Release(ctx context.Context, transportId string, date string, enquiryId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := dayKey(transportId, date)
+	day, ok := r.days[key]
+	if !ok {
+		return nil
+	}
+
+	bookings := make([]models.Booking, 0, len(day.Bookings))
+	for _, b := range day.Bookings {
+		if b.EnquiryId != enquiryId {
+			bookings = append(bookings, b)
+		}
+	}
+	day.Bookings = bookings
+	r.days[key] = day
+
+	return nil
+}
+
+func (r *memoryCalendarRepository) DeleteTransport(ctx context.Context, transportId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, day := range r.days {
+		if day.TransportId == transportId {
+			delete(r.days, key)
+		}
+	}
+	return nil
+}
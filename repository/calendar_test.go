@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+)
+
+const deliveryDate = "2026-11-02"
+
+func booking(enquiryId string, quantity int) models.Booking {
+	return models.Booking{EnquiryId: enquiryId, Quantity: quantity}
+}
+
+func TestReserve(t *testing.T) {
+	tests := []struct {
+		name     string
+		booked   []int
+		quantity int
+		capacity int
+		slots    int
+		err      error
+	}{
+		{"empty day", nil, 10, 10, 1, nil},
+		{"no limits", []int{100, 100}, 100, 0, 0, nil},
+		{"up to the capacity", []int{6}, 4, 10, 0, nil},
+		{"over the capacity", []int{6}, 5, 10, 0, ErrFullyBooked},
+		{"last slot", []int{1}, 1, 0, 2, nil},
+		{"no slot left", []int{1, 1}, 1, 0, 2, ErrFullyBooked},
+		{"slot left but no capacity", []int{9}, 2, 10, 5, ErrFullyBooked},
+	}
+
+	for _, tt := range tests {
+		repo := NewMemory().Calendar
+		for i, quantity := range tt.booked {
+			if err := repo.Reserve(context.Background(), "t1", deliveryDate, booking(fmt.Sprint("e", i), quantity), 0, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err := repo.Reserve(context.Background(), "t1", deliveryDate, booking("new", tt.quantity), tt.capacity, tt.slots)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+
+		day, _ := repo.Day(context.Background(), "t1", deliveryDate)
+		want := len(tt.booked)
+		if tt.err == nil {
+			want++
+		}
+		if len(day.Bookings) != want {
+			t.Errorf("%s: %d bookings, want %d", tt.name, len(day.Bookings), want)
+		}
+	}
+}
+
+func TestReserveClosedDay(t *testing.T) {
+	repo := NewMemory().Calendar
+	if _, err := repo.SetDay(context.Background(), "t1", deliveryDate, &models.CalendarDayUpdate{Closed: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Reserve(context.Background(), "t1", deliveryDate, booking("e1", 1), 0, 0); !errors.Is(err, ErrFullyBooked) {
+		t.Errorf("closed day: got %v, want ErrFullyBooked", err)
+	}
+	// other transports and days are open
+	if err := repo.Reserve(context.Background(), "t2", deliveryDate, booking("e2", 1), 0, 0); err != nil {
+		t.Errorf("other transport: %v", err)
+	}
+	if err := repo.Reserve(context.Background(), "t1", "2026-11-03", booking("e3", 1), 0, 0); err != nil {
+		t.Errorf("other day: %v", err)
+	}
+
+	// opening the day again keeps nothing of the refused booking
+	if _, err := repo.SetDay(context.Background(), "t1", deliveryDate, &models.CalendarDayUpdate{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Reserve(context.Background(), "t1", deliveryDate, booking("e1", 1), 0, 0); err != nil {
+		t.Errorf("reopened day: %v", err)
+	}
+	if day, _ := repo.Day(context.Background(), "t1", deliveryDate); len(day.Bookings) != 1 {
+		t.Errorf("got %+v, want one booking", day.Bookings)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	repo := NewMemory().Calendar
+	for _, b := range []models.Booking{booking("e1", 6), booking("e2", 4)} {
+		if err := repo.Reserve(context.Background(), "t1", deliveryDate, b, 10, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Reserve(context.Background(), "t1", deliveryDate, booking("e3", 1), 10, 0); !errors.Is(err, ErrFullyBooked) {
+		t.Fatalf("full day: got %v, want ErrFullyBooked", err)
+	}
+
+	if err := repo.Release(context.Background(), "t1", deliveryDate, "e1"); err != nil {
+		t.Fatal(err)
+	}
+	day, _ := repo.Day(context.Background(), "t1", deliveryDate)
+	if len(day.Bookings) != 1 || day.Bookings[0].EnquiryId != "e2" || day.Reserved() != 4 {
+		t.Errorf("got %+v, want the booking of e2", day.Bookings)
+	}
+	if err := repo.Reserve(context.Background(), "t1", deliveryDate, booking("e3", 6), 10, 0); err != nil {
+		t.Errorf("after release: %v", err)
+	}
+
+	// releasing what isn't booked does nothing
+	for _, release := range [][3]string{{"t1", deliveryDate, "missing"}, {"t1", "2026-11-03", "e2"}, {"t2", deliveryDate, "e2"}} {
+		if err := repo.Release(context.Background(), release[0], release[1], release[2]); err != nil {
+			t.Errorf("release %v: %v", release, err)
+		}
+	}
+	if day, _ := repo.Day(context.Background(), "t1", deliveryDate); day.Reserved() != 10 {
+		t.Errorf("reserved %d, want 10", day.Reserved())
+	}
+}
+
+func TestConcurrentReserve(t *testing.T) {
+	repo := NewMemory().Calendar
+
+	// 20 bookings of 3 race for a capacity of 30 and 8 slots, only 8 may win
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	won := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := repo.Reserve(context.Background(), "t1", deliveryDate, booking(fmt.Sprint("e", i), 3), 30, 8)
+			if err != nil && !errors.Is(err, ErrFullyBooked) {
+				t.Error(err)
+				return
+			}
+			if err == nil {
+				mu.Lock()
+				won++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	day, _ := repo.Day(context.Background(), "t1", deliveryDate)
+	if won != 8 || len(day.Bookings) != 8 || day.Reserved() != 24 {
+		t.Errorf("%d reserved, %d bookings of %d, want 8 of 24", won, len(day.Bookings), day.Reserved())
+	}
+}
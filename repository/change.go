@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Changed receives the resource as it was before and after a write, both come from the operation making the change
+// so no other write can come in between. after is nil when the write deleted the resource.
+type Changed func(before interface{}, after interface{})
+
+type changedKey struct{}
+
+// OnChange returns a ctx whose updates and deletes pass the resource they change to changed
+func OnChange(ctx context.Context, changed Changed) context.Context {
+	return context.WithValue(ctx, changedKey{}, changed)
+}
+
+func reportChange(ctx context.Context, before interface{}, after interface{}) {
+	if changed, ok := ctx.Value(changedKey{}).(Changed); ok {
+		changed(before, after)
+	}
+}
+
+// applyTo makes change to doc and decodes doc into a T before and after it, doc is either the stored document of a
+// memory repository or the one a find-and-modify returned as it was before its update
+func applyTo[T any](ctx context.Context, doc bson.M, change func(doc bson.M) error) (T, error) {
+	var before, after T
+	if err := fromDocument(doc, &before); err != nil {
+		return after, err
+	}
+	if err := change(doc); err != nil {
+		return after, err
+	}
+	if err := fromDocument(doc, &after); err != nil {
+		return after, err
+	}
+
+	reportChange(ctx, before, after)
+	return after, nil
+}
+
+// setFields is $set, only the fields present in set are replaced
+func setFields(set bson.M) func(doc bson.M) error {
+	return func(doc bson.M) error {
+		for key, value := range set {
+			doc[key] = value
+		}
+		return nil
+	}
+}
+
+// deleted reports the deletion of doc
+func deleted[T any](ctx context.Context, doc bson.M) error {
+	var before T
+	if err := fromDocument(doc, &before); err != nil {
+		return err
+	}
+
+	reportChange(ctx, before, nil)
+	return nil
+}
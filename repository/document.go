@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// parseID converts a hex id into an ObjectID
+func parseID(id string) (primitive.ObjectID, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, ErrInvalidID
+	}
+
+	return objectID, nil
+}
+
+// toDocument marshals v using its bson tags, dropping omitempty fields the same way the driver does
+func toDocument(v interface{}) (bson.M, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.M{}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// fromDocument decodes doc into v, only overwriting the fields present in doc
+func fromDocument(doc bson.M, v interface{}) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return bson.Unmarshal(data, v)
+}
+
+// newDocument prepares item for insertion by giving it a fresh ObjectID
+func newDocument(item interface{}) (bson.M, error) {
+	doc, err := toDocument(item)
+	if err != nil {
+		return nil, err
+	}
+	doc["_id"] = primitive.NewObjectID()
+
+	return doc, nil
+}
+
+// lookup returns the value at a dotted path like "price.amount", or nil when it is missing
+func lookup(doc bson.M, path string) interface{} {
+	var value interface{} = doc
+	for _, key := range strings.Split(path, ".") {
+		switch d := value.(type) {
+		case bson.M:
+			value = d[key]
+		case bson.D:
+			value = d.Map()[key]
+		default:
+			return nil
+		}
+	}
+	return value
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoEnquiryRepository struct {
+	*mongoRepository[models.GenerateEnquiry, models.EnquiryUpdate]
+}
+
+func (r *mongoEnquiryRepository) UpdateWhile(ctx context.Context, id string, statuses []models.EnquiryStatus, update *models.EnquiryUpdate) (models.GenerateEnquiry, error) {
+	objectID, err := parseID(id)
+	if err != nil {
+		return models.GenerateEnquiry{}, err
+	}
+	set, err := toDocument(update)
+	if err != nil {
+		return models.GenerateEnquiry{}, err
+	}
+
+	// the status filter makes the update fail when another request moved the enquiry out of statuses first
+	filter := bson.M{"_id": objectID, "status": bson.M{"$in": statuses}}
+	return r.findAndModify(ctx, filter, bson.M{"$set": set}, setFields(set))
+}
+
+func (r *mongoEnquiryRepository) Transition(ctx context.Context, id string, change models.StatusChange) error {
+	objectID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	// the status filter makes the update fail when another request changed the status first
+	_, err = r.findAndModify(ctx,
+		bson.M{"_id": objectID, "status": change.From},
+		bson.M{
+			"$set":  bson.M{"status": change.To},
+			"$push": bson.M{"statusHistory": change},
+		},
+		transitioned(change, nil),
+	)
+	return err
+}
+
+func (r *mongoEnquiryRepository) AddQuote(ctx context.Context, id string, quote models.Quote, change models.StatusChange) error {
+	objectID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	// the quote only fits when the enquiry holds exactly the versions before it
+	filter := bson.M{"_id": objectID, "status": change.From}
+	filter[fmt.Sprintf("quotes.%d", quote.Version-1)] = bson.M{"$exists": false}
+	if quote.Version > 1 {
+		filter[fmt.Sprintf("quotes.%d", quote.Version-2)] = bson.M{"$exists": true}
+	}
+
+	_, err = r.findAndModify(ctx, filter, bson.M{
+		"$set":  bson.M{"status": change.To},
+		"$push": bson.M{"statusHistory": change, "quotes": quote},
+	}, transitioned(change, pushQuote(quote)))
+	return err
+}
+
+func (r *mongoEnquiryRepository) AcceptQuote(ctx context.Context, id string, version int, change models.StatusChange) error {
+	objectID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.findAndModify(ctx,
+		bson.M{"_id": objectID, "status": change.From},
+		bson.M{
+			"$set":  bson.M{"status": change.To, "acceptedQuote": version},
+			"$push": bson.M{"statusHistory": change},
+		},
+		transitioned(change, acceptQuote(version)),
+	)
+	return err
+}
+
+// findAndModify makes the conditional update and returns the enquiry after it. The update returns the document as
+// it was, apply makes the same change to it so the enquiry before and after come from the one operation.
+func (r *mongoEnquiryRepository) findAndModify(ctx context.Context, filter bson.M, update bson.M, apply func(doc bson.M) error) (models.GenerateEnquiry, error) {
+	var doc bson.M
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before).SetComment(comment(ctx))
+	err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.GenerateEnquiry{}, r.missingOrConflict(ctx, filter["_id"])
+	}
+	if err != nil {
+		return models.GenerateEnquiry{}, err
+	}
+
+	return applyTo[models.GenerateEnquiry](ctx, doc, apply)
+}
+
+// missingOrConflict tells apart an enquiry that was deleted from one whose status changed
+func (r *mongoEnquiryRepository) missingOrConflict(ctx context.Context, id interface{}) error {
+	count, err := r.coll.CountDocuments(ctx, bson.M{"_id": id}, options.Count().SetComment(comment(ctx)))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return ErrStatusConflict
+}
+
+type memoryEnquiryRepository struct {
+	*memoryRepository[models.GenerateEnquiry, models.EnquiryUpdate]
+}
+
+func (r *memoryEnquiryRepository) UpdateWhile(ctx context.Context, id string, statuses []models.EnquiryStatus, update *models.EnquiryUpdate) (models.GenerateEnquiry, error) {
+	var enquiry models.GenerateEnquiry
+	objectID, err := parseID(id)
+	if err != nil {
+		return enquiry, err
+	}
+	set, err := toDocument(update)
+	if err != nil {
+		return enquiry, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.docs[objectID]
+	if !ok {
+		return enquiry, ErrNotFound
+	}
+	if !hasStatus(doc, statuses) {
+		return enquiry, ErrStatusConflict
+	}
+
+	return applyTo[models.GenerateEnquiry](ctx, doc, setFields(set))
+}
+
+func hasStatus(doc bson.M, statuses []models.EnquiryStatus) bool {
+	for _, status := range statuses {
+		if doc["status"] == string(status) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryEnquiryRepository) Transition(ctx context.Context, id string, change models.StatusChange) error {
+	return r.transition(ctx, id, change, nil)
+}
+
+func (r *memoryEnquiryRepository) AddQuote(ctx context.Context, id string, quote models.Quote, change models.StatusChange) error {
+	return r.transition(ctx, id, change, func(doc bson.M) error {
+		quotes, _ := doc["quotes"].(bson.A)
+		if len(quotes) != quote.Version-1 {
+			return ErrStatusConflict
+		}
+		return pushQuote(quote)(doc)
+	})
+}
+
+func (r *memoryEnquiryRepository) AcceptQuote(ctx context.Context, id string, version int, change models.StatusChange) error {
+	return r.transition(ctx, id, change, acceptQuote(version))
+}
+
+// transition makes change like the conditional update of mongoEnquiryRepository, apply can change doc as part of it
+func (r *memoryEnquiryRepository) transition(ctx context.Context, id string, change models.StatusChange, apply func(doc bson.M) error) error {
+	objectID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.docs[objectID]
+	if !ok {
+		return ErrNotFound
+	}
+	if doc["status"] != string(change.From) {
+		return ErrStatusConflict
+	}
+
+	_, err = applyTo[models.GenerateEnquiry](ctx, doc, transitioned(change, apply))
+	return err
+}
+
+// transitioned is the $set of the status and the $push of change to the history, apply can change doc as part of it
+func transitioned(change models.StatusChange, apply func(doc bson.M) error) func(doc bson.M) error {
+	return func(doc bson.M) error {
+		entry, err := toDocument(change)
+		if err != nil {
+			return err
+		}
+		if apply != nil {
+			if err := apply(doc); err != nil {
+				return err
+			}
+		}
+
+		history, _ := doc["statusHistory"].(bson.A)
+		doc["status"] = string(change.To)
+		doc["statusHistory"] = append(history, entry)
+		return nil
+	}
+}
+
+func pushQuote(quote models.Quote) func(doc bson.M) error {
+	return func(doc bson.M) error {
+		quoteDoc, err := toDocument(quote)
+		if err != nil {
+			return err
+		}
+		quotes, _ := doc["quotes"].(bson.A)
+		doc["quotes"] = append(quotes, quoteDoc)
+		return nil
+	}
+}
+
+func acceptQuote(version int) func(doc bson.M) error {
+	return func(doc bson.M) error {
+		doc["acceptedQuote"] = int32(version)
+		return nil
+	}
+}
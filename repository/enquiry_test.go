@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func seedEnquiry(t *testing.T, repo EnquiryRepository, status models.EnquiryStatus) string {
+	t.Helper()
+	enquiry := models.GenerateEnquiry{
+		BuyerId:        "buyer",
+		TransportId:    "transport",
+		ProductId:      "product",
+		Quantity:       10,
+		DateOfDelivery: "2026-11-02",
+		Status:         status,
+		StatusHistory:  []models.StatusChange{},
+		Quotes:         []models.Quote{},
+	}
+	if err := repo.Create(context.Background(), &enquiry); err != nil {
+		t.Fatal(err)
+	}
+	return enquiry.ID
+}
+
+func TestTransition(t *testing.T) {
+	for _, tr := range models.EnquiryTransitions {
+		for _, from := range tr.From {
+			t.Run(tr.Action+" from "+string(from), func(t *testing.T) {
+				repo := NewMemory().Enquiries
+				id := seedEnquiry(t, repo, from)
+
+				change := models.StatusChange{From: from, To: tr.To, By: "user", At: time.Now().UTC().Truncate(time.Millisecond), Note: tr.Action}
+				if err := repo.Transition(context.Background(), id, change); err != nil {
+					t.Fatal(err)
+				}
+
+				enquiry, err := repo.Get(context.Background(), id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if enquiry.Status != tr.To {
+					t.Errorf("status is %s, want %s", enquiry.Status, tr.To)
+				}
+				if len(enquiry.StatusHistory) != 1 || enquiry.StatusHistory[0] != change {
+					t.Errorf("history is %+v, want [%+v]", enquiry.StatusHistory, change)
+				}
+			})
+		}
+	}
+}
+
+func TestTransitionFromStaleStatus(t *testing.T) {
+	repo := NewMemory().Enquiries
+	id := seedEnquiry(t, repo, models.StatusScheduled)
+
+	// the request read the enquiry while it was still accepted
+	change := models.StatusChange{From: models.StatusAccepted, To: models.StatusCancelled, By: "user"}
+	if err := repo.Transition(context.Background(), id, change); !errors.Is(err, ErrStatusConflict) {
+		t.Fatalf("got %v, want ErrStatusConflict", err)
+	}
+
+	enquiry, err := repo.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enquiry.Status != models.StatusScheduled || len(enquiry.StatusHistory) != 0 {
+		t.Errorf("the enquiry changed to %s with history %+v", enquiry.Status, enquiry.StatusHistory)
+	}
+}
+
+func TestTransitionRace(t *testing.T) {
+	repo := NewMemory().Enquiries
+	id := seedEnquiry(t, repo, models.StatusQuoted)
+
+	// a reject and a cancel both read the enquiry as quoted, only one of them can win
+	changes := []models.StatusChange{
+		{From: models.StatusQuoted, To: models.StatusRejected, By: "transporter"},
+		{From: models.StatusQuoted, To: models.StatusCancelled, By: "buyer"},
+	}
+	errs := make([]error, len(changes))
+	var wg sync.WaitGroup
+	for i := range changes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repo.Transition(context.Background(), id, changes[i])
+		}(i)
+	}
+	wg.Wait()
+
+	winner := -1
+	for i, err := range errs {
+		switch {
+		case err == nil && winner == -1:
+			winner = i
+		case err == nil:
+			t.Fatal("both transitions succeeded")
+		case !errors.Is(err, ErrStatusConflict):
+			t.Fatalf("the loser got %v, want ErrStatusConflict", err)
+		}
+	}
+	if winner == -1 {
+		t.Fatal("no transition succeeded")
+	}
+
+	enquiry, err := repo.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enquiry.Status != changes[winner].To || len(enquiry.StatusHistory) != 1 {
+		t.Errorf("status is %s with history %+v, want only %s", enquiry.Status, enquiry.StatusHistory, changes[winner].To)
+	}
+}
+
+func TestTransitionOfMissingEnquiry(t *testing.T) {
+	repo := NewMemory().Enquiries
+	change := models.StatusChange{From: models.StatusRequested, To: models.StatusCancelled}
+
+	if err := repo.Transition(context.Background(), primitive.NewObjectID().Hex(), change); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+	if err := repo.Transition(context.Background(), "not-an-id", change); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("got %v, want ErrInvalidID", err)
+	}
+}
+
+func TestUpdateWhile(t *testing.T) {
+	repo := NewMemory().Enquiries
+	id := seedEnquiry(t, repo, models.StatusAccepted)
+
+	// the request checked the enquiry while it was still quoted
+	if _, err := repo.UpdateWhile(context.Background(), id, models.UnbookedStatuses, &models.EnquiryUpdate{Quantity: 20}); !errors.Is(err, ErrStatusConflict) {
+		t.Fatalf("got %v, want ErrStatusConflict", err)
+	}
+	enquiry, err := repo.UpdateWhile(context.Background(), id, models.OpenStatuses, &models.EnquiryUpdate{DeliveryAddress: "Pune"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enquiry.Quantity != 10 || enquiry.DeliveryAddress != "Pune" {
+		t.Errorf("got quantity %d and address %q, want 10 and Pune", enquiry.Quantity, enquiry.DeliveryAddress)
+	}
+
+	if _, err := repo.UpdateWhile(context.Background(), primitive.NewObjectID().Hex(), models.OpenStatuses, &models.EnquiryUpdate{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing enquiry: got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultLimit is the page size used when ListOptions.Limit is not set
+const DefaultLimit = 20
+
+// MaxLimit is the largest page size a client can ask for
+const MaxLimit = 100
+
+// ErrInvalidCursor is returned when a cursor can't be decoded or was created for a different sort
+var ErrInvalidCursor = apperror.New(apperror.KindBadRequest, "invalid cursor")
+
+type FieldKind int
+
+const (
+	StringField FieldKind = iota
+	NumberField
+	BoolField
+	// MoneyField is a money.Money, filters and sorts apply to its amount in the major unit
+	MoneyField
+)
+
+// Fields lists the bson fields of a resource that can be filtered and sorted on
+type Fields map[string]FieldKind
+
+var BookFields = Fields{
+	"title":  StringField,
+	"author": StringField,
+	"year":   StringField,
+}
+
+var ProductFields = Fields{
+	"name":        StringField,
+	"price":       MoneyField,
+	"minQuantity": NumberField,
+	"sellerId":    StringField,
+	"rating":      NumberField,
+	"ratingCount": NumberField,
+}
+
+var ReviewFields = Fields{
+	"rating":    NumberField,
+	"buyerId":   StringField,
+	"enquiryId": StringField,
+}
+
+var TransportFields = Fields{
+	"name":        StringField,
+	"phone":       StringField,
+	"services":    StringField,
+	"price":       MoneyField,
+	"minQuantity": NumberField,
+	"capacity":    NumberField,
+	"address":     StringField,
+	"available":   BoolField,
+	"rating":      NumberField,
+	"ratingCount": NumberField,
+	"ownerId":     StringField,
+}
+
+var EnquiryFields = Fields{
+	"buyerId":         StringField,
+	"transportId":     StringField,
+	"productId":       StringField,
+	"quantity":        NumberField,
+	"deliveryAddress": StringField,
+	"dateOfDelivery":  StringField,
+	"status":          StringField,
+}
+
+var QueryFields = Fields{
+	"name":  StringField,
+	"email": StringField,
+	"phone": StringField,
+}
+
+// ParseValue converts a raw query string value into the type stored for field
+func (f Fields) ParseValue(field string, raw string) (interface{}, error) {
+	kind, ok := f[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown field '%s'", field)
+	}
+
+	switch kind {
+	case MoneyField:
+		d, err := primitive.ParseDecimal128(raw)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' must be a number", field)
+		}
+		return d, nil
+	case NumberField:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' must be a number", field)
+		}
+		return n, nil
+	case BoolField:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' must be true or false", field)
+		}
+		return b, nil
+	default:
+		return raw, nil
+	}
+}
+
+// Path returns the bson path filters and sorts on field use
+func (f Fields) Path(field string) string {
+	if f[field] == MoneyField {
+		return field + ".amount"
+	}
+	return field
+}
+
+type Operator string
+
+const (
+	Eq  Operator = "eq"
+	Ne  Operator = "ne"
+	Gt  Operator = "gt"
+	Gte Operator = "gte"
+	Lt  Operator = "lt"
+	Lte Operator = "lte"
+	In  Operator = "in"
+)
+
+// Operators contains every operator a filter can use
+var Operators = map[Operator]bool{Eq: true, Ne: true, Gt: true, Gte: true, Lt: true, Lte: true, In: true}
+
+// Filter matches documents whose Field compares to Value with Op, for In the Value is a []interface{}
+type Filter struct {
+	Field string
+	Op    Operator
+	Value interface{}
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ListOptions controls which page of a collection List returns
+type ListOptions struct {
+	Filters []Filter
+	Sort    []SortField
+	Limit   int
+	Cursor  string
+}
+
+func (o ListOptions) limit() int {
+	if o.Limit <= 0 {
+		return DefaultLimit
+	}
+	return o.Limit
+}
+
+// Page is one page of a list, NextCursor is empty on the last page and Total counts every match of the filters
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+	Total      int64
+}
+
+// cursorToken holds the sort values and id of the last item of a page, results continue strictly after it
+type cursorToken struct {
+	Sort   string             `bson:"s"`
+	Values bson.A             `bson:"v"`
+	ID     primitive.ObjectID `bson:"id"`
+}
+
+func sortKey(sort []SortField) string {
+	keys := make([]string, len(sort))
+	for i, s := range sort {
+		keys[i] = s.Field
+		if s.Desc {
+			keys[i] = "-" + s.Field
+		}
+	}
+	return strings.Join(keys, ",")
+}
+
+func encodeCursor(sort []SortField, doc bson.M) (string, error) {
+	id, ok := doc["_id"].(primitive.ObjectID)
+	if !ok {
+		return "", errors.New("document has no ObjectID")
+	}
+
+	token := cursorToken{Sort: sortKey(sort), Values: bson.A{}, ID: id}
+	for _, s := range sort {
+		token.Values = append(token.Values, lookup(doc, s.Field))
+	}
+
+	data, err := bson.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(raw string, sort []SortField) (cursorToken, error) {
+	token := cursorToken{}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return token, ErrInvalidCursor
+	}
+	if err := bson.Unmarshal(data, &token); err != nil {
+		return token, ErrInvalidCursor
+	}
+	if token.Sort != sortKey(sort) || len(token.Values) != len(sort) {
+		return token, ErrInvalidCursor
+	}
+
+	return token, nil
+}
+
+// newPage decodes docs into page, docs holds up to limit+1 documents and the extra one only signals a next page
+func newPage[T any](page Page[T], docs []bson.M, sort []SortField, limit int) (Page[T], error) {
+	if len(docs) > limit {
+		docs = docs[:limit]
+		next, err := encodeCursor(sort, docs[limit-1])
+		if err != nil {
+			return page, err
+		}
+		page.NextCursor = next
+	}
+
+	for _, doc := range docs {
+		var item T
+		if err := fromDocument(doc, &item); err != nil {
+			return page, err
+		}
+		page.Items = append(page.Items, item)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := primitive.NewObjectID()
+	doc := bson.M{"_id": id, "name": "truck", "capacity": int32(100), "price": bson.M{"amount": decimal(t, "12.50")}}
+
+	tests := []struct {
+		name string
+		sort []SortField
+		want bson.A
+	}{
+		{"no sort", nil, bson.A{}},
+		{"one field", []SortField{{Field: "name"}}, bson.A{"truck"}},
+		{"descending and nested", []SortField{{Field: "capacity", Desc: true}, {Field: "price.amount"}}, bson.A{int32(100), decimal(t, "12.50")}},
+		{"missing field", []SortField{{Field: "rating"}}, bson.A{nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := encodeCursor(tt.sort, doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := decodeCursor(raw, tt.sort)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.ID != id {
+				t.Errorf("id is %s, want %s", token.ID, id)
+			}
+			if len(token.Values) != len(tt.want) {
+				t.Fatalf("values are %v, want %v", token.Values, tt.want)
+			}
+			for i := range tt.want {
+				if compareValues(token.Values[i], tt.want[i]) != 0 {
+					t.Errorf("value %d is %v, want %v", i, token.Values[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	doc := bson.M{"_id": primitive.NewObjectID(), "name": "truck"}
+	byName, err := encodeCursor([]SortField{{Field: "name"}}, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   []SortField
+	}{
+		{"not base64", "!!!", []SortField{{Field: "name"}}},
+		{"not bson", "bm90IGJzb24", []SortField{{Field: "name"}}},
+		{"other field", byName, []SortField{{Field: "capacity"}}},
+		{"other direction", byName, []SortField{{Field: "name", Desc: true}}},
+		{"more fields", byName, []SortField{{Field: "name"}, {Field: "capacity"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor, tt.sort); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("got %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+
+	if _, err := encodeCursor(nil, bson.M{"name": "truck"}); err == nil {
+		t.Error("encoding a document without an ObjectID should fail")
+	}
+}
+
+func TestCompareDocsBreaksTiesOnID(t *testing.T) {
+	first, second := primitive.NewObjectID(), primitive.NewObjectID()
+	sort := []SortField{{Field: "rating", Desc: true}}
+
+	tests := []struct {
+		name string
+		doc  bson.M
+		want int
+	}{
+		{"same value, lower id", bson.M{"_id": first, "rating": 4.0}, -1},
+		{"same value, same id", bson.M{"_id": second, "rating": 4.0}, 0},
+		{"higher value comes first when descending", bson.M{"_id": second, "rating": 5.0}, -1},
+		{"lower value comes after when descending", bson.M{"_id": first, "rating": 3.0}, 1},
+		{"missing sorts as the lowest value", bson.M{"_id": first}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := compareDocs(tt.doc, bson.A{4.0}, second, sort)
+			if sign(c) != tt.want {
+				t.Errorf("got %d, want %d", c, tt.want)
+			}
+		})
+	}
+}
+
+func TestListPagesContinueOnTies(t *testing.T) {
+	repo := NewMemory().Transports
+	// the same capacity everywhere, only _id orders them
+	ids := seedTransports(t, repo,
+		models.Transport{Name: "a", Capacity: 10},
+		models.Transport{Name: "b", Capacity: 10},
+		models.Transport{Name: "c", Capacity: 10},
+	)
+
+	sort := []SortField{{Field: "capacity"}}
+	page, err := repo.List(context.Background(), ListOptions{Sort: sort, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Items[0].ID != ids[0] || page.Items[1].ID != ids[1] || page.NextCursor == "" {
+		t.Fatalf("first page is %v, next %q", namesOf(page.Items), page.NextCursor)
+	}
+
+	page, err = repo.List(context.Background(), ListOptions{Sort: sort, Limit: 2, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != ids[2] || page.NextCursor != "" {
+		t.Errorf("second page is %v, next %q", namesOf(page.Items), page.NextCursor)
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		field   string
+		raw     string
+		want    interface{}
+		wantErr bool
+	}{
+		{"name", "truck", "truck", false},
+		{"capacity", "12.5", 12.5, false},
+		{"capacity", "lots", nil, true},
+		{"available", "true", true, false},
+		{"available", "yes", nil, true},
+		{"price", "99.50", decimal(t, "99.50"), false},
+		{"price", "cheap", nil, true},
+		{"colour", "red", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field+"="+tt.raw, func(t *testing.T) {
+			got, err := TransportFields.ParseValue(tt.field, tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && compareValues(got, tt.want) != 0 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPath(t *testing.T) {
+	if got := TransportFields.Path("price"); got != "price.amount" {
+		t.Errorf("price: got %q", got)
+	}
+	if got := TransportFields.Path("capacity"); got != "capacity" {
+		t.Errorf("capacity: got %q", got)
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
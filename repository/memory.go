@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewMemory creates repositories that keep everything in memory, useful for running the API without MongoDB
+func NewMemory() *Repositories {
+	return &Repositories{
+		Books:      newMemoryRepository[models.Book, models.BookUpdate](),
+		Products:   newMemoryRepository[models.Product, models.UpdatePTO](),
+		Transports: &memoryTransportRepository{newMemoryRepository[models.Transport, models.TransportUpdate]()},
+		Enquiries:  &memoryEnquiryRepository{newMemoryRepository[models.GenerateEnquiry, models.EnquiryUpdate]()},
+		Queries:    newMemoryRepository[models.Query, models.Query](),
+		Users:      &memoryUserRepository{newMemoryRepository[models.User, models.User]()},
+		Calendar:   &memoryCalendarRepository{days: map[string]models.CalendarDay{}},
+		Reviews:    &memoryReviewRepository{newMemoryRepository[models.Review, models.Review]()},
+		Audit:      newMemoryRepository[models.AuditEntry, models.AuditEntry](),
+	}
+}
+
+// memoryRepository keeps documents as bson maps so they are encoded exactly like they would be in MongoDB
+type memoryRepository[T any, U any] struct {
+	mu   sync.RWMutex
+	docs map[primitive.ObjectID]bson.M
+}
+
+func newMemoryRepository[T any, U any]() *memoryRepository[T, U] {
+	return &memoryRepository[T, U]{docs: map[primitive.ObjectID]bson.M{}}
+}
+
+func (r *memoryRepository[T, U]) List(ctx context.Context, opts ListOptions) (Page[T], error) {
+	page := Page[T]{Items: make([]T, 0)}
+
+	var token *cursorToken
+	if opts.Cursor != "" {
+		t, err := decodeCursor(opts.Cursor, opts.Sort)
+		if err != nil {
+			return page, err
+		}
+		token = &t
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	docs := make([]bson.M, 0)
+	for _, doc := range r.docs {
+		if matchFilters(doc, opts.Filters) {
+			docs = append(docs, doc)
+		}
+	}
+	page.Total = int64(len(docs))
+
+	sort.Slice(docs, func(i, j int) bool {
+		return compareDocs(docs[i], sortValues(docs[j], opts.Sort), docs[j]["_id"], opts.Sort) < 0
+	})
+
+	// continue after the last item of the previous page
+	if token != nil {
+		start := sort.Search(len(docs), func(i int) bool {
+			return compareDocs(docs[i], token.Values, token.ID, opts.Sort) > 0
+		})
+		docs = docs[start:]
+	}
+
+	// keep one extra document to know if there is a next page
+	limit := opts.limit()
+	if len(docs) > limit+1 {
+		docs = docs[:limit+1]
+	}
+
+	return newPage[T](page, docs, opts.Sort, limit)
+}
+
+func (r *memoryRepository[T, U]) Get(ctx context.Context, id string) (T, error) {
+	var item T
+	objectID, err := parseID(id)
+	if err != nil {
+		return item, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	doc, ok := r.docs[objectID]
+	if !ok {
+		return item, ErrNotFound
+	}
+
+	err = fromDocument(doc, &item)
+	return item, err
+}
+
+func (r *memoryRepository[T, U]) Create(ctx context.Context, item *T) error {
+	doc, err := newDocument(item)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.docs[doc["_id"].(primitive.ObjectID)] = doc
+	r.mu.Unlock()
+
+	return fromDocument(doc, item)
+}
+
+func (r *memoryRepository[T, U]) Update(ctx context.Context, id string, update *U) (T, error) {
+	var item T
+	objectID, err := parseID(id)
+	if err != nil {
+		return item, err
+	}
+	set, err := toDocument(update)
+	if err != nil {
+		return item, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.docs[objectID]
+	if !ok {
+		return item, ErrNotFound
+	}
+
+	// omitempty leaves out nil pointers so pointers to 0 and false are still set
+	return applyTo[T](ctx, doc, setFields(set))
+}
+
+func (r *memoryRepository[T, U]) Delete(ctx context.Context, id string) error {
+	objectID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.docs[objectID]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(r.docs, objectID)
+
+	return deleted[T](ctx, doc)
+}
+
+func sortValues(doc bson.M, sort []SortField) bson.A {
+	values := bson.A{}
+	for _, s := range sort {
+		values = append(values, lookup(doc, s.Field))
+	}
+	return values
+}
+
+// compareDocs orders doc against the sort values and id of another document, the same way mongoSort does
+func compareDocs(doc bson.M, values bson.A, id interface{}, sort []SortField) int {
+	for i, s := range sort {
+		c := compareValues(lookup(doc, s.Field), values[i])
+		if s.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareValues(doc["_id"], id)
+}
+
+func matchFilters(doc bson.M, filters []Filter) bool {
+	for _, f := range filters {
+		if !matchFilter(lookup(doc, f.Field), f) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchFilter(value interface{}, f Filter) bool {
+	switch f.Op {
+	case Eq:
+		return matchEq(value, f.Value)
+	case Ne:
+		return !matchEq(value, f.Value)
+	case In:
+		for _, v := range f.Value.([]interface{}) {
+			if matchEq(value, v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// like MongoDB, range operators only match values of the same type
+	if typeOrder(value) != typeOrder(f.Value) {
+		return false
+	}
+	c := compareValues(value, f.Value)
+	switch f.Op {
+	case Gt:
+		return c > 0
+	case Gte:
+		return c >= 0
+	case Lt:
+		return c < 0
+	case Lte:
+		return c <= 0
+	}
+	return false
+}
+
+// matchEq also matches arrays containing v, like a MongoDB equality query
+func matchEq(value interface{}, v interface{}) bool {
+	if arr, ok := value.(bson.A); ok {
+		for _, elem := range arr {
+			if matchEq(elem, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return typeOrder(value) == typeOrder(v) && compareValues(value, v) == 0
+}
+
+// typeOrder follows the MongoDB comparison order of bson types
+func typeOrder(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case int, int32, int64, float64, primitive.Decimal128:
+		return 1
+	case string:
+		return 2
+	case bson.M, bson.D:
+		return 3
+	case bson.A:
+		return 4
+	case primitive.ObjectID:
+		return 5
+	case bool:
+		return 6
+	case primitive.DateTime, time.Time:
+		return 7
+	}
+	return 8
+}
+
+func compareValues(a interface{}, b interface{}) int {
+	ta, tb := typeOrder(a), typeOrder(b)
+	if ta != tb {
+		return ta - tb
+	}
+
+	switch av := a.(type) {
+	case string:
+		return strings.Compare(av, b.(string))
+	case primitive.ObjectID:
+		return strings.Compare(av.Hex(), b.(primitive.ObjectID).Hex())
+	case bool:
+		if av == b.(bool) {
+			return 0
+		} else if av {
+			return 1
+		}
+		return -1
+	}
+
+	if ta == 1 || ta == 7 {
+		x, y := toFloat(a), toFloat(b)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+	}
+	return 0
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	case primitive.Decimal128:
+		f, _ := strconv.ParseFloat(n.String(), 64)
+		return f
+	case primitive.DateTime:
+		return float64(n)
+	case time.Time:
+		return float64(n.UnixMilli())
+	}
+	return 0
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// seedTransports creates the transports in order and returns their ids
+func seedTransports(t *testing.T, repo TransportRepository, transports ...models.Transport) []string {
+	t.Helper()
+	ids := make([]string, len(transports))
+	for i := range transports {
+		if err := repo.Create(context.Background(), &transports[i]); err != nil {
+			t.Fatalf("create transport %d: %v", i, err)
+		}
+		ids[i] = transports[i].ID
+	}
+	return ids
+}
+
+func inr(t *testing.T, amount string) money.Money {
+	t.Helper()
+	m, err := money.Parse(amount, "INR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func decimal(t *testing.T, s string) primitive.Decimal128 {
+	t.Helper()
+	d, err := primitive.ParseDecimal128(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func namesOf(transports []models.Transport) []string {
+	names := make([]string, len(transports))
+	for i, t := range transports {
+		names[i] = t.Name
+	}
+	return names
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMemoryListFilters(t *testing.T) {
+	repo := NewMemory().Transports
+	seedTransports(t, repo,
+		models.Transport{Name: "truck", Sevices: []string{"grain", "cold"}, Price: inr(t, "200"), Capacity: 100, Available: true, Rating: 4.5},
+		models.Transport{Name: "van", Sevices: []string{"parcel"}, Price: inr(t, "99.50"), Capacity: 20, Available: false, Rating: 3},
+		models.Transport{Name: "lorry", Price: inr(t, "150.25"), Capacity: 300, Available: true},
+	)
+
+	tests := []struct {
+		name    string
+		filters []Filter
+		want    []string
+	}{
+		{"no filters", nil, []string{"lorry", "truck", "van"}},
+		{"eq string", []Filter{{Field: "name", Op: Eq, Value: "van"}}, []string{"van"}},
+		{"eq matches an element of an array", []Filter{{Field: "services", Op: Eq, Value: "cold"}}, []string{"truck"}},
+		{"ne also matches documents without the element", []Filter{{Field: "services", Op: Ne, Value: "grain"}}, []string{"lorry", "van"}},
+		{"eq bool", []Filter{{Field: "available", Op: Eq, Value: false}}, []string{"van"}},
+		{"numbers compare across types", []Filter{{Field: "capacity", Op: Gte, Value: float64(100)}}, []string{"lorry", "truck"}},
+		{"lt", []Filter{{Field: "capacity", Op: Lt, Value: float64(100)}}, []string{"van"}},
+		{"ranges only match the same type", []Filter{{Field: "capacity", Op: Gt, Value: "1"}}, []string{}},
+		{"money on its amount", []Filter{{Field: "price.amount", Op: Lte, Value: decimal(t, "150.25")}}, []string{"lorry", "van"}},
+		{"in", []Filter{{Field: "name", Op: In, Value: []interface{}{"van", "lorry", "bus"}}}, []string{"lorry", "van"}},
+		{"in with no values", []Filter{{Field: "name", Op: In, Value: []interface{}{}}}, []string{}},
+		{"filters are combined", []Filter{{Field: "available", Op: Eq, Value: true}, {Field: "rating", Op: Gt, Value: float64(4)}}, []string{"truck"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.List(context.Background(), ListOptions{Filters: tt.filters, Sort: []SortField{{Field: "name"}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := namesOf(page.Items); !equalStrings(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if page.Total != int64(len(tt.want)) {
+				t.Errorf("total is %d, want %d", page.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestMemoryListSort(t *testing.T) {
+	repo := NewMemory().Transports
+	seedTransports(t, repo,
+		models.Transport{Name: "a", Capacity: 20, Price: inr(t, "5")},
+		models.Transport{Name: "b", Capacity: 100, Price: inr(t, "40.50")},
+		models.Transport{Name: "c", Capacity: 5, Price: inr(t, "40.05")},
+	)
+
+	tests := []struct {
+		name string
+		sort []SortField
+		want []string
+	}{
+		{"ascending", []SortField{{Field: "capacity"}}, []string{"c", "a", "b"}},
+		{"descending", []SortField{{Field: "capacity", Desc: true}}, []string{"b", "a", "c"}},
+		{"money as a number", []SortField{{Field: "price.amount"}}, []string{"a", "c", "b"}},
+		{"no sort is by id", nil, []string{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.List(context.Background(), ListOptions{Sort: tt.sort})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := namesOf(page.Items); !equalStrings(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryListCursor(t *testing.T) {
+	repo := NewMemory().Transports
+	// every rating is shared by two transports, so the pages have to break ties on _id
+	ids := seedTransports(t, repo,
+		models.Transport{Name: "t1", Rating: 4},
+		models.Transport{Name: "t2", Rating: 5},
+		models.Transport{Name: "t3", Rating: 4},
+		models.Transport{Name: "t4", Rating: 5},
+		models.Transport{Name: "t5", Rating: 3},
+		models.Transport{Name: "t6", Rating: 3},
+	)
+	want := []string{ids[1], ids[3], ids[0], ids[2], ids[4], ids[5]}
+
+	for _, limit := range []int{1, 2, 4, 6, 10} {
+		opts := ListOptions{Sort: []SortField{{Field: "rating", Desc: true}}, Limit: limit}
+		got := make([]string, 0)
+		for pages := 0; ; pages++ {
+			if pages > len(want) {
+				t.Fatalf("limit %d: paging doesn't end", limit)
+			}
+			page, err := repo.List(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("limit %d: %v", limit, err)
+			}
+			if page.Total != int64(len(want)) {
+				t.Errorf("limit %d: total is %d, want %d", limit, page.Total, len(want))
+			}
+			for _, item := range page.Items {
+				got = append(got, item.ID)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			opts.Cursor = page.NextCursor
+		}
+		if !equalStrings(got, want) {
+			t.Errorf("limit %d: got %v, want %v", limit, got, want)
+		}
+	}
+}
+
+func TestMemoryListCursorOfOtherSort(t *testing.T) {
+	repo := NewMemory().Transports
+	seedTransports(t, repo, models.Transport{Name: "a"}, models.Transport{Name: "b"})
+
+	page, err := repo.List(context.Background(), ListOptions{Sort: []SortField{{Field: "name"}}, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.List(context.Background(), ListOptions{Sort: []SortField{{Field: "name", Desc: true}}, Cursor: page.NextCursor})
+	if !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("got %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestMemoryUpdate(t *testing.T) {
+	repo := NewMemory().Transports
+	ids := seedTransports(t, repo, models.Transport{Name: "truck", MinQuantity: 10, Capacity: 100, Available: true})
+	zero, no := 0, false
+
+	tests := []struct {
+		name   string
+		update models.TransportUpdate
+		check  func(models.Transport) bool
+	}{
+		{"only sent fields change", models.TransportUpdate{Name: "lorry"}, func(tr models.Transport) bool {
+			return tr.Name == "lorry" && tr.MinQuantity == 10 && tr.Capacity == 100 && tr.Available
+		}},
+		{"pointers set 0 and false", models.TransportUpdate{MinQuantity: &zero, Available: &no}, func(tr models.Transport) bool {
+			return tr.Name == "lorry" && tr.MinQuantity == 0 && tr.Capacity == 100 && !tr.Available
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := repo.Update(context.Background(), ids[0], &tt.update)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stored, err := repo.Get(context.Background(), ids[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(updated) || !tt.check(stored) {
+				t.Errorf("got %+v, stored %+v", updated, stored)
+			}
+		})
+	}
+
+	if _, err := repo.Update(context.Background(), primitive.NewObjectID().Hex(), &models.TransportUpdate{Name: "x"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("update of a missing transport: got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/requestid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongo creates repositories backed by the collections of db
+func NewMongo(db *mongo.Database) *Repositories {
+	return &Repositories{
+		Books:      &mongoRepository[models.Book, models.BookUpdate]{coll: db.Collection("books")},
+		Products:   &mongoRepository[models.Product, models.UpdatePTO]{coll: db.Collection("products")},
+		Transports: &mongoTransportRepository{&mongoRepository[models.Transport, models.TransportUpdate]{coll: db.Collection("transports")}},
+		Enquiries:  &mongoEnquiryRepository{&mongoRepository[models.GenerateEnquiry, models.EnquiryUpdate]{coll: db.Collection("enquiries")}},
+		Queries:    &mongoRepository[models.Query, models.Query]{coll: db.Collection("query")},
+		Users:      &mongoUserRepository{&mongoRepository[models.User, models.User]{coll: db.Collection("users")}},
+		Calendar:   &mongoCalendarRepository{coll: db.Collection("calendar")},
+		Reviews:    &mongoReviewRepository{&mongoRepository[models.Review, models.Review]{coll: db.Collection("reviews")}},
+		Audit:      &mongoRepository[models.AuditEntry, models.AuditEntry]{coll: db.Collection("audit_log")},
+	}
+}
+
+// mongoRepository stores documents of type T in a collection and applies partial updates of type U with $set
+type mongoRepository[T any, U any] struct {
+	coll *mongo.Collection
+}
+
+func (r *mongoRepository[T, U]) List(ctx context.Context, opts ListOptions) (Page[T], error) {
+	page := Page[T]{Items: make([]T, 0)}
+	filter := mongoFilter(opts.Filters)
+
+	total, err := r.coll.CountDocuments(ctx, filter, options.Count().SetComment(comment(ctx)))
+	if err != nil {
+		return page, err
+	}
+	page.Total = total
+
+	// continue after the last item of the previous page
+	if opts.Cursor != "" {
+		token, err := decodeCursor(opts.Cursor, opts.Sort)
+		if err != nil {
+			return page, err
+		}
+		filter = bson.M{"$and": bson.A{filter, mongoCursorFilter(opts.Sort, token)}}
+	}
+
+	// fetch one extra document to know if there is a next page
+	limit := opts.limit()
+	findOptions := options.Find().SetSort(mongoSort(opts.Sort)).SetLimit(int64(limit + 1)).SetComment(comment(ctx))
+	cursor, err := r.coll.Find(ctx, filter, findOptions)
+	if err != nil {
+		return page, err
+	}
+
+	docs := make([]bson.M, 0, limit+1)
+	if err := cursor.All(ctx, &docs); err != nil {
+		return page, err
+	}
+
+	return newPage[T](page, docs, opts.Sort, limit)
+}
+
+func (r *mongoRepository[T, U]) Get(ctx context.Context, id string) (T, error) {
+	var item T
+	objectID, err := parseID(id)
+	if err != nil {
+		return item, err
+	}
+
+	err = r.coll.FindOne(ctx, bson.M{"_id": objectID}, options.FindOne().SetComment(comment(ctx))).Decode(&item)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return item, ErrNotFound
+	}
+
+	return item, err
+}
+
+func (r *mongoRepository[T, U]) Create(ctx context.Context, item *T) error {
+	doc, err := newDocument(item)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.coll.InsertOne(ctx, doc, options.InsertOne().SetComment(comment(ctx))); err != nil {
+		return err
+	}
+
+	// copy the generated id back onto the item
+	return fromDocument(doc, item)
+}
+
+func (r *mongoRepository[T, U]) Update(ctx context.Context, id string, update *U) (T, error) {
+	var item T
+	objectID, err := parseID(id)
+	if err != nil {
+		return item, err
+	}
+
+	set, err := toDocument(update)
+	if err != nil {
+		return item, err
+	}
+
+	// find-and-modify returns the document as it was, the same $set made to it gives the document after the update
+	// without reading it again
+	var doc bson.M
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before).SetComment(comment(ctx))
+	err = r.coll.FindOneAndUpdate(ctx, bson.M{"_id": objectID}, bson.M{"$set": set}, opts).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return item, ErrNotFound
+	}
+	if err != nil {
+		return item, err
+	}
+
+	return applyTo[T](ctx, doc, setFields(set))
+}
+
+func (r *mongoRepository[T, U]) Delete(ctx context.Context, id string) error {
+	objectID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	var doc bson.M
+	err = r.coll.FindOneAndDelete(ctx, bson.M{"_id": objectID}, options.FindOneAndDelete().SetComment(comment(ctx))).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return deleted[T](ctx, doc)
+}
+
+// comment tags a command with the id of the request that sent it, so it can be found in the profiler and the slow
+// query log. It is empty outside of a request.
+func comment(ctx context.Context) string {
+	return requestid.From(ctx)
+}
+
+// mongoFilter turns filters into a query document, operators on the same field are merged
+func mongoFilter(filters []Filter) bson.M {
+	filter := bson.M{}
+	for _, f := range filters {
+		cond, ok := filter[f.Field].(bson.M)
+		if !ok {
+			cond = bson.M{}
+			filter[f.Field] = cond
+		}
+		cond["$"+string(f.Op)] = f.Value
+	}
+	return filter
+}
+
+// mongoSort always ends with _id so the order is stable for cursors
+func mongoSort(sort []SortField) bson.D {
+	d := bson.D{}
+	for _, s := range sort {
+		if s.Desc {
+			d = append(d, bson.E{Key: s.Field, Value: -1})
+		} else {
+			d = append(d, bson.E{Key: s.Field, Value: 1})
+		}
+	}
+	return append(d, bson.E{Key: "_id", Value: 1})
+}
+
+// mongoCursorFilter matches the documents that sort after token
+func mongoCursorFilter(sort []SortField, token cursorToken) bson.M {
+	or := bson.A{}
+	for i, s := range sort {
+		cond := bson.M{}
+		for j := 0; j < i; j++ {
+			cond[sort[j].Field] = token.Values[j]
+		}
+		if s.Desc {
+			cond[s.Field] = bson.M{"$lt": token.Values[i]}
+		} else {
+			cond[s.Field] = bson.M{"$gt": token.Values[i]}
+		}
+		or = append(or, cond)
+	}
+
+	cond := bson.M{"_id": bson.M{"$gt": token.ID}}
+	for i, s := range sort {
+		cond[s.Field] = token.Values[i]
+	}
+
+	return bson.M{"$or": append(or, cond)}
+}
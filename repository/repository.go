@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/geo"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+)
+
+// ErrNotFound is returned when no document matches the given id
+var ErrNotFound = apperror.New(apperror.KindNotFound, "document not found")
+
+// ErrStatusConflict is returned by Transition when the enquiry is no longer in the expected status
+var ErrStatusConflict = apperror.New(apperror.KindConflict, "enquiry status was changed by another request")
+
+// ErrDuplicate is returned when creating a document that must be unique, like a user with a taken email
+var ErrDuplicate = apperror.New(apperror.KindConflict, "document already exists")
+
+// ErrFullyBooked is returned by Reserve when the day is closed or the booking doesn't fit its capacity or slots
+var ErrFullyBooked = apperror.New(apperror.KindConflict, "transport is fully booked on this day")
+
+// ErrInvalidID is returned when the given id is not a valid ObjectID hex string
+var ErrInvalidID = apperror.New(apperror.KindBadRequest, "invalid id")
+
+// Update returns the document as it is after the update and Delete returns ErrNotFound when nothing matched
+type BookRepository interface {
+	List(ctx context.Context, opts ListOptions) (Page[models.Book], error)
+	Get(ctx context.Context, id string) (models.Book, error)
+	Create(ctx context.Context, book *models.Book) error
+	Update(ctx context.Context, id string, update *models.BookUpdate) (models.Book, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type ProductRepository interface {
+	List(ctx context.Context, opts ListOptions) (Page[models.Product], error)
+	Get(ctx context.Context, id string) (models.Product, error)
+	Create(ctx context.Context, product *models.Product) error
+	Update(ctx context.Context, id string, update *models.UpdatePTO) (models.Product, error)
+	Delete(ctx context.Context, id string) error
+	// SetRating stores the summary of the reviews of the product, unless the stored one counts as many reviews or more
+	SetRating(ctx context.Context, id string, summary models.RatingSummary) error
+}
+
+type TransportRepository interface {
+	List(ctx context.Context, opts ListOptions) (Page[models.Transport], error)
+	Get(ctx context.Context, id string) (models.Transport, error)
+	Create(ctx context.Context, transport *models.Transport) error
+	Update(ctx context.Context, id string, update *models.TransportUpdate) (models.Transport, error)
+	Delete(ctx context.Context, id string) error
+	// SetRating stores the summary of the reviews of the transport, unless the stored one counts as many reviews or more
+	SetRating(ctx context.Context, id string, summary models.RatingSummary) error
+	// Near returns the transports based within radiusKm of center, closest first
+	Near(ctx context.Context, center geo.Point, radiusKm float64, limit int) ([]NearbyTransport, error)
+	// Candidates returns the available transports priced in currency that take quantity, a capacity of 0 takes any,
+	// leaving out the ids in excluded
+	Candidates(ctx context.Context, quantity int, currency string, excluded []string) ([]models.Transport, error)
+}
+
+type EnquiryRepository interface {
+	List(ctx context.Context, opts ListOptions) (Page[models.GenerateEnquiry], error)
+	Get(ctx context.Context, id string) (models.GenerateEnquiry, error)
+	Create(ctx context.Context, enquiry *models.GenerateEnquiry) error
+	Update(ctx context.Context, id string, update *models.EnquiryUpdate) (models.GenerateEnquiry, error)
+	Delete(ctx context.Context, id string) error
+	// UpdateWhile is Update while the status is one of statuses, it fails with ErrStatusConflict otherwise
+	UpdateWhile(ctx context.Context, id string, statuses []models.EnquiryStatus, update *models.EnquiryUpdate) (models.GenerateEnquiry, error)
+	// Transition sets the status to change.To and appends change to the history, but only while the status is still change.From
+	Transition(ctx context.Context, id string, change models.StatusChange) error
+	// AddQuote appends quote and makes the transition, it fails with ErrStatusConflict when the status is no longer
+	// change.From or another quote took quote.Version first
+	AddQuote(ctx context.Context, id string, quote models.Quote, change models.StatusChange) error
+	// AcceptQuote records version as the accepted quote and makes the transition like Transition
+	AcceptQuote(ctx context.Context, id string, version int, change models.StatusChange) error
+}
+
+// QueryRepository has no Update since queries are never edited once submitted
+type QueryRepository interface {
+	List(ctx context.Context, opts ListOptions) (Page[models.Query], error)
+	Get(ctx context.Context, id string) (models.Query, error)
+	Create(ctx context.Context, query *models.Query) error
+	Delete(ctx context.Context, id string) error
+}
+
+type UserRepository interface {
+	Get(ctx context.Context, id string) (models.User, error)
+	GetByEmail(ctx context.Context, email string) (models.User, error)
+	// Create returns ErrDuplicate when the email is already registered
+	Create(ctx context.Context, user *models.User) error
+}
+
+// CalendarRepository keeps the days transports are booked on, a day is only stored once it is booked or has its own limits
+type CalendarRepository interface {
+	// Days returns the stored days of the transport from from to to, both included, in order
+	Days(ctx context.Context, transportId string, from string, to string) ([]models.CalendarDay, error)
+	// Day returns the day, or an empty one when it isn't stored
+	Day(ctx context.Context, transportId string, date string) (models.CalendarDay, error)
+	// On returns the stored days of every transport on date
+	On(ctx context.Context, date string) ([]models.CalendarDay, error)
+	// SetDay replaces the limits of the day and keeps its bookings
+	SetDay(ctx context.Context, transportId string, date string, update *models.CalendarDayUpdate) (models.CalendarDay, error)
+	// Reserve adds booking to the day, but only while the day is open and keeps to capacity and slots (0 is no limit),
+	// it fails with ErrFullyBooked otherwise
+	Reserve(ctx context.Context, transportId string, date string, booking models.Booking, capacity int, slots int) error
+	// Release removes the booking of the enquiry, releasing a booking that doesn't exist does nothing
+	Release(ctx context.Context, transportId string, date string, enquiryId string) error
+	// DeleteTransport removes every day of the transport
+	DeleteTransport(ctx context.Context, transportId string) error
+}
+
+// ReviewRepository has no Update or Delete, a review stays as it was submitted
+type ReviewRepository interface {
+	List(ctx context.Context, opts ListOptions) (Page[models.Review], error)
+	Get(ctx context.Context, id string) (models.Review, error)
+	// Create returns ErrDuplicate when the enquiry already has a review of the same subject
+	Create(ctx context.Context, review *models.Review) error
+	// Summarize aggregates the ratings of every review of the subject
+	Summarize(ctx context.Context, subject models.ReviewSubject, subjectId string) (models.RatingSummary, error)
+}
+
+// AuditRepository has no Update or Delete, the audit log is only appended to
+type AuditRepository interface {
+	List(ctx context.Context, opts ListOptions) (Page[models.AuditEntry], error)
+	Create(ctx context.Context, entry *models.AuditEntry) error
+}
+
+// Repositories groups the repository of every resource so they can be passed around together
+type Repositories struct {
+	Books      BookRepository
+	Products   ProductRepository
+	Transports TransportRepository
+	Enquiries  EnquiryRepository
+	Queries    QueryRepository
+	Users      UserRepository
+	Calendar   CalendarRepository
+	Reviews    ReviewRepository
+	Audit      AuditRepository
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ratingFields are the fields SetRating writes on the reviewed document
+type ratingFields struct {
+	Rating       float64 `bson:"rating"`
+	Count        int     `bson:"ratingCount"`
+	Distribution [5]int  `bson:"ratingDistribution"`
+}
+
+func newRatingFields(summary models.RatingSummary) ratingFields {
+	return ratingFields{Rating: summary.Average, Count: summary.Count, Distribution: summary.Distribution}
+}
+
+// SetRating stores summary on the document, it is only exposed for the resources that can be reviewed. Reviews are
+// never removed, so a summary of more reviews is newer: a summary is only stored over one of fewer reviews, which
+// keeps a request that summarized before another review landed from overwriting the summary including it.
+func (r *mongoRepository[T, U]) SetRating(ctx context.Context, id string, summary models.RatingSummary) error {
+	objectID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	// documents without a rating yet match too
+	filter := bson.M{"_id": objectID, "ratingCount": bson.M{"$not": bson.M{"$gte": summary.Count}}}
+	result, err := r.coll.UpdateOne(ctx, filter, bson.M{"$set": newRatingFields(summary)}, options.Update().SetComment(comment(ctx)))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	// a newer summary is already stored, unless the document is gone
+	count, err := r.coll.CountDocuments(ctx, bson.M{"_id": objectID}, options.Count().SetComment(comment(ctx)))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *memoryRepository[T, U]) SetRating(ctx context.Context, id string, summary models.RatingSummary) error {
+	objectID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	set, err := toDocument(newRatingFields(summary))
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.docs[objectID]
+	if !ok {
+		return ErrNotFound
+	}
+	var stored ratingFields
+	if err := fromDocument(doc, &stored); err != nil {
+		return err
+	}
+	if stored.Count >= summary.Count {
+		return nil
+	}
+	for key, value := range set {
+		doc[key] = value
+	}
+
+	return nil
+}
+
+type mongoReviewRepository struct {
+	*mongoRepository[models.Review, models.Review]
+}
+
+func (r *mongoReviewRepository) Create(ctx context.Context, review *models.Review) error {
+	count, err := r.coll.CountDocuments(ctx, bson.M{"enquiryId": review.EnquiryId, "subject": review.Subject}, options.Count().SetComment(comment(ctx)))
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicate
+	}
+
+	// the unique index on enquiryId and subject catches concurrent reviews
+	err = r.mongoRepository.Create(ctx, review)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+
+	return err
+}
+
+func (r *mongoReviewRepository) Summarize(ctx context.Context, subject models.ReviewSubject, subjectId string) (models.RatingSummary, error) {
+	cursor, err := r.coll.Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"subject": subject, "subjectId": subjectId}},
+		bson.M{"$group": bson.M{"_id": "$rating", "count": bson.M{"$sum": 1}}},
+	}, options.Aggregate().SetComment(comment(ctx)))
+	if err != nil {
+		return models.RatingSummary{}, err
+	}
+
+	var groups []struct {
+		Rating int `bson:"_id"`
+		Count  int `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return models.RatingSummary{}, err
+	}
+
+	var distribution [5]int
+	for _, g := range groups {
+		if g.Rating >= 1 && g.Rating <= 5 {
+			distribution[g.Rating-1] = g.Count
+		}
+	}
+	return models.NewRatingSummary(distribution), nil
+}
+
+type memoryReviewRepository struct {
+	*memoryRepository[models.Review, models.Review]
+}
+
+func (r *memoryReviewRepository) Create(ctx context.Context, review *models.Review) error {
+	doc, err := newDocument(review)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.docs {
+		if existing["enquiryId"] == review.EnquiryId && existing["subject"] == string(review.Subject) {
+			return ErrDuplicate
+		}
+	}
+	r.docs[doc["_id"].(primitive.ObjectID)] = doc
+
+	return fromDocument(doc, review)
+}
+
+func (r *memoryReviewRepository) Summarize(ctx context.Context, subject models.ReviewSubject, subjectId string) (models.RatingSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var distribution [5]int
+	for _, doc := range r.docs {
+		if doc["subject"] != string(subject) || doc["subjectId"] != subjectId {
+			continue
+		}
+		var review models.Review
+		if err := fromDocument(doc, &review); err != nil {
+			return models.RatingSummary{}, err
+		}
+		if review.Rating >= 1 && review.Rating <= 5 {
+			distribution[review.Rating-1]++
+		}
+	}
+	return models.NewRatingSummary(distribution), nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func review(subjectId string, rating int) *models.Review {
+	return &models.Review{
+		Subject:   models.ReviewTransport,
+		SubjectId: subjectId,
+		EnquiryId: primitive.NewObjectID().Hex(),
+		Rating:    rating,
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	repo := NewMemory().Reviews
+	for _, rating := range []int{5, 4, 4, 1} {
+		if err := repo.Create(context.Background(), review("t1", rating)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// other subjects don't count
+	repo.Create(context.Background(), review("t2", 1))
+
+	got, err := repo.Summarize(context.Background(), models.ReviewTransport, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.RatingSummary{Average: 3.5, Count: 4, Distribution: [5]int{1, 0, 0, 2, 1}}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestReviewOncePerEnquiry(t *testing.T) {
+	repo := NewMemory().Reviews
+	first := review("t1", 5)
+	if err := repo.Create(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+
+	again := review("t1", 1)
+	again.EnquiryId = first.EnquiryId
+	if err := repo.Create(context.Background(), again); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("got %v, want ErrDuplicate", err)
+	}
+
+	// the product of the same enquiry can still be reviewed
+	product := review("p1", 3)
+	product.Subject, product.EnquiryId = models.ReviewProduct, first.EnquiryId
+	if err := repo.Create(context.Background(), product); err != nil {
+		t.Errorf("review of the product: %v", err)
+	}
+}
+
+func TestSetRatingKeepsTheNewestSummary(t *testing.T) {
+	repo := NewMemory().Transports
+	id := seedTransports(t, repo, models.Transport{Name: "truck"})[0]
+
+	newer := models.NewRatingSummary([5]int{0, 0, 0, 1, 1})
+	older := models.NewRatingSummary([5]int{0, 0, 0, 0, 1})
+	for _, summary := range []models.RatingSummary{newer, older} {
+		if err := repo.SetRating(context.Background(), id, summary); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	transport, err := repo.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transport.Rating != newer.Average || transport.RatingCount != newer.Count || transport.RatingDistribution != newer.Distribution {
+		t.Errorf("stored %v (%d) %v, want the summary of 2 reviews", transport.Rating, transport.RatingCount, transport.RatingDistribution)
+	}
+
+	if err := repo.SetRating(context.Background(), primitive.NewObjectID().Hex(), newer); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing transport: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestConcurrentReviewsRating(t *testing.T) {
+	repos := NewMemory()
+	id := seedTransports(t, repos.Transports, models.Transport{Name: "truck"})[0]
+
+	// every review summarizes and stores the rating like the api does, whatever the order the last one counts them all
+	ratings := []int{1, 2, 3, 4, 5, 5, 4, 3, 2, 1, 5, 5}
+	var wg sync.WaitGroup
+	for _, rating := range ratings {
+		wg.Add(1)
+		go func(rating int) {
+			defer wg.Done()
+			ctx := context.Background()
+			if err := repos.Reviews.Create(ctx, review(id, rating)); err != nil {
+				t.Error(err)
+				return
+			}
+			summary, err := repos.Reviews.Summarize(ctx, models.ReviewTransport, id)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := repos.Transports.SetRating(ctx, id, summary); err != nil {
+				t.Error(err)
+			}
+		}(rating)
+	}
+	wg.Wait()
+
+	transport, err := repos.Transports.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.NewRatingSummary([5]int{2, 2, 2, 2, 4})
+	if transport.RatingCount != want.Count || transport.Rating != want.Average || transport.RatingDistribution != want.Distribution {
+		t.Errorf("stored %v (%d) %v, want %+v", transport.Rating, transport.RatingCount, transport.RatingDistribution, want)
+	}
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"github.com/bmdavis419/fiber-mongo-example/geo"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NearbyTransport is a transport found by Near and its distance to the center of the search
+type NearbyTransport struct {
+	Transport  models.Transport `json:"transport"`
+	DistanceKm float64          `json:"distanceKm"`
+}
+
+type mongoTransportRepository struct {
+	*mongoRepository[models.Transport, models.TransportUpdate]
+}
+
+// Near uses $geoNear, which needs the 2dsphere index on location of migration 2
+func (r *mongoTransportRepository) Near(ctx context.Context, center geo.Point, radiusKm float64, limit int) ([]NearbyTransport, error) {
+	cursor, err := r.coll.Aggregate(ctx, bson.A{
+		bson.M{"$geoNear": bson.M{
+			"near":          center,
+			"key":           "location",
+			"distanceField": "distance",
+			"maxDistance":   radiusKm * 1000,
+			"spherical":     true,
+		}},
+		bson.M{"$limit": limit},
+	}, options.Aggregate().SetComment(comment(ctx)))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []struct {
+		models.Transport `bson:",inline"`
+		Distance         float64 `bson:"distance"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	nearby := make([]NearbyTransport, len(docs))
+	for i, doc := range docs {
+		nearby[i] = NearbyTransport{Transport: doc.Transport, DistanceKm: doc.Distance / 1000}
+	}
+	return nearby, nil
+}
+
+func (r *mongoTransportRepository) Candidates(ctx context.Context, quantity int, currency string, excluded []string) ([]models.Transport, error) {
+	ids := bson.A{}
+	for _, id := range excluded {
+		if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
+			ids = append(ids, objectID)
+		}
+	}
+
+	filter := bson.M{
+		"available":      true,
+		"minQuantity":    bson.M{"$lte": quantity},
+		"price.currency": currency,
+		"$or": bson.A{
+			bson.M{"capacity": bson.M{"$not": bson.M{"$gt": 0}}},
+			bson.M{"capacity": bson.M{"$gte": quantity}},
+		},
+	}
+	if len(ids) > 0 {
+		filter["_id"] = bson.M{"$nin": ids}
+	}
+
+	cursor, err := r.coll.Find(ctx, filter, options.Find().SetComment(comment(ctx)))
+	if err != nil {
+		return nil, err
+	}
+	transports := make([]models.Transport, 0)
+	if err := cursor.All(ctx, &transports); err != nil {
+		return nil, err
+	}
+	return transports, nil
+}
+
+type memoryTransportRepository struct {
+	*memoryRepository[models.Transport, models.TransportUpdate]
+}
+
+func (r *memoryTransportRepository) Near(ctx context.Context, center geo.Point, radiusKm float64, limit int) ([]NearbyTransport, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nearby := make([]NearbyTransport, 0)
+	for _, doc := range r.docs {
+		var t models.Transport
+		if err := fromDocument(doc, &t); err != nil {
+			return nil, err
+		}
+		if t.Location == nil {
+			continue
+		}
+		if d := geo.DistanceKm(center, *t.Location); d <= radiusKm {
+			nearby = append(nearby, NearbyTransport{Transport: t, DistanceKm: d})
+		}
+	}
+
+	sort.Slice(nearby, func(i, j int) bool {
+		return nearby[i].DistanceKm < nearby[j].DistanceKm
+	})
+	if len(nearby) > limit {
+		nearby = nearby[:limit]
+	}
+	return nearby, nil
+}
+
+func (r *memoryTransportRepository) Candidates(ctx context.Context, quantity int, currency string, excluded []string) ([]models.Transport, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	skip := map[string]bool{}
+	for _, id := range excluded {
+		skip[id] = true
+	}
+
+	transports := make([]models.Transport, 0)
+	for _, doc := range r.docs {
+		var t models.Transport
+		if err := fromDocument(doc, &t); err != nil {
+			return nil, err
+		}
+		switch {
+		case !t.Available, skip[t.ID], t.MinQuantity > quantity, t.Price.Currency != currency:
+			continue
+		case t.Capacity > 0 && t.Capacity < quantity:
+			continue
+		}
+		transports = append(transports, t)
+	}
+	return transports, nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/geo"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+)
+
+func TestMemoryNear(t *testing.T) {
+	repo := NewMemory().Transports
+	at := func(p geo.Point) *geo.Point { return &p }
+	seedTransports(t, repo,
+		models.Transport{Name: "mumbai", Location: at(geo.Cities["mumbai"])},
+		models.Transport{Name: "pune", Location: at(geo.Cities["pune"])},
+		models.Transport{Name: "delhi", Location: at(geo.Cities["delhi"])},
+		models.Transport{Name: "nowhere"},
+	)
+
+	tests := []struct {
+		name     string
+		radiusKm float64
+		limit    int
+		want     []string
+	}{
+		{"only the center", 10, 10, []string{"pune"}},
+		{"nearest first", 200, 10, []string{"pune", "mumbai"}},
+		{"limit", 2000, 2, []string{"pune", "mumbai"}},
+		{"all located", 2000, 10, []string{"pune", "mumbai", "delhi"}},
+	}
+
+	for _, tt := range tests {
+		nearby, err := repo.Near(context.Background(), geo.Cities["pune"], tt.radiusKm, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]string, len(nearby))
+		for i, n := range nearby {
+			got[i] = n.Transport.Name
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+		for _, n := range nearby {
+			if n.DistanceKm > tt.radiusKm {
+				t.Errorf("%s: %s is %.0fkm away", tt.name, n.Transport.Name, n.DistanceKm)
+			}
+		}
+	}
+}
+
+func TestMemoryCandidates(t *testing.T) {
+	repo := NewMemory().Transports
+	rupees := inr(t, "200")
+	ids := seedTransports(t, repo,
+		models.Transport{Name: "any capacity", Available: true, Price: rupees},
+		models.Transport{Name: "big enough", Available: true, Price: rupees, Capacity: 50, MinQuantity: 50},
+		models.Transport{Name: "too small", Available: true, Price: rupees, Capacity: 49},
+		models.Transport{Name: "minimum too high", Available: true, Price: rupees, MinQuantity: 51},
+		models.Transport{Name: "unavailable", Price: rupees},
+		models.Transport{Name: "other currency", Available: true, Price: money.Money{Amount: 100, Currency: "USD"}},
+		models.Transport{Name: "excluded", Available: true, Price: rupees},
+	)
+
+	transports, err := repo.Candidates(context.Background(), 50, "INR", []string{ids[6], "not an id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, tr := range transports {
+		got[tr.Name] = true
+	}
+	if len(got) != 2 || !got["any capacity"] || !got["big enough"] {
+		t.Errorf("got %v, want any capacity and big enough", got)
+	}
+}
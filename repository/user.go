@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoUserRepository struct {
+	*mongoRepository[models.User, models.User]
+}
+
+func (r *mongoUserRepository) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	user := models.User{}
+	err := r.coll.FindOne(ctx, bson.M{"email": strings.ToLower(email)}, options.FindOne().SetComment(comment(ctx))).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return user, ErrNotFound
+	}
+
+	return user, err
+}
+
+func (r *mongoUserRepository) Create(ctx context.Context, user *models.User) error {
+	user.Email = strings.ToLower(user.Email)
+	if _, err := r.GetByEmail(ctx, user.Email); !errors.Is(err, ErrNotFound) {
+		if err == nil {
+			return ErrDuplicate
+		}
+		return err
+	}
+
+	// a unique index on email, when present, catches concurrent registrations
+	err := r.mongoRepository.Create(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+
+	return err
+}
+
+type memoryUserRepository struct {
+	*memoryRepository[models.User, models.User]
+}
+
+func (r *memoryUserRepository) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	user := models.User{}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, doc := range r.docs {
+		if doc["email"] == strings.ToLower(email) {
+			err := fromDocument(doc, &user)
+			return user, err
+		}
+	}
+
+	return user, ErrNotFound
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	user.Email = strings.ToLower(user.Email)
+	doc, err := newDocument(user)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.docs {
+		if existing["email"] == user.Email {
+			return ErrDuplicate
+		}
+	}
+	r.docs[doc["_id"].(primitive.ObjectID)] = doc
+
+	return fromDocument(doc, user)
+}
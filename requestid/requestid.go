@@ -0,0 +1,58 @@
+// Package requestid gives every request an id, sent back in the X-Request-ID header and carried in its context, so
+// the log lines, MongoDB commands and error responses of a request can be matched.
+package requestid
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// Header is read from the request and written to the response
+const Header = "X-Request-ID"
+
+type key struct{}
+
+// With returns a copy of ctx carrying id
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// From returns the id carried by ctx, empty outside of a request
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
+
+// Middleware keeps the id the caller sent, like a proxy that already gave the request one, and generates one when
+// it sent none or one that isn't a short token. The id is in the user context of the request.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// the header points into a buffer fiber reuses, the context keeps a copy
+		id := utils.CopyString(c.Get(Header))
+		if !valid(id) {
+			id = utils.UUIDv4()
+		}
+
+		c.Set(Header, id)
+		c.SetUserContext(With(c.UserContext(), id))
+		return c.Next()
+	}
+}
+
+// valid accepts up to 128 letters, digits and "-", "_", ".", ":", so an id can't break a log line
+func valid(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package router
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/auth"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+type adminHandler struct {
+	audit repository.AuditRepository
+}
+
+func AddAdminGroup(app *fiber.App, audit repository.AuditRepository, tokens *auth.Tokens) {
+	h := &adminHandler{audit: audit}
+	adminGroup := app.Group("/admin")
+
+	admins := auth.Protect(tokens, models.RoleAdmin)
+
+	adminGroup.Get("/audit", admins, h.getAudit)
+}
+
+// getAudit lists the audit log newest first, ?resource=, ?resourceId=, ?actor= and ?action= match exactly and
+// ?from= and ?to= take a date or an RFC 3339 time, from included and to excluded
+func (h *adminHandler) getAudit(c *fiber.Ctx) error {
+	opts := repository.ListOptions{
+		Sort:   []repository.SortField{{Field: "time", Desc: true}},
+		Cursor: c.Query("cursor"),
+	}
+
+	for _, field := range []string{"resource", "resourceId", "actor", "action"} {
+		if value := c.Query(field); value != "" {
+			opts.Filters = append(opts.Filters, repository.Filter{Field: field, Op: repository.Eq, Value: value})
+		}
+	}
+	for param, op := range map[string]repository.Operator{"from": repository.Gte, "to": repository.Lt} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := parseAuditTime(value)
+		if err != nil {
+			return apperror.BadRequest(param + " must be a date like 2024-05-01 or a time like 2024-05-01T10:00:00Z")
+		}
+		opts.Filters = append(opts.Filters, repository.Filter{Field: "time", Op: op, Value: t})
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > repository.MaxLimit {
+			return apperror.BadRequest("limit must be between 1 and " + strconv.Itoa(repository.MaxLimit))
+		}
+		opts.Limit = limit
+	}
+
+	page, err := h.audit.List(c.UserContext(), opts)
+	if err != nil {
+		return err
+	}
+
+	return listResponse(c, page)
+}
+
+func parseAuditTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,151 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/auth"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+type authHandler struct {
+	users  repository.UserRepository
+	tokens *auth.Tokens
+}
+
+func AddAuthGroup(app *fiber.App, users repository.UserRepository, tokens *auth.Tokens) {
+	h := &authHandler{users: users, tokens: tokens}
+	authGroup := app.Group("/auth")
+
+	authGroup.Post("/register", validateBody[registerDTO](), h.register)
+	authGroup.Post("/login", validateBody[loginDTO](), h.login)
+	authGroup.Post("/refresh", validateBody[refreshDTO](), h.refresh)
+	authGroup.Get("/me", auth.Protect(tokens), h.me)
+}
+
+type registerDTO struct {
+	Email    string      `json:"email" validate:"required,email,max=254"`
+	Password string      `json:"password" validate:"required,min=8,max=72"`
+	Role     models.Role `json:"role" validate:"oneof=buyer seller transporter"`
+}
+
+func (h *authHandler) register(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	b := parsedBody[registerDTO](c)
+	// admins can't sign up, they are created with ADMIN_EMAIL
+	if b.Role == "" {
+		b.Role = models.RoleBuyer
+	}
+
+	hash, err := auth.HashPassword(b.Password)
+	if err != nil {
+		return err
+	}
+
+	// Create the user
+	user := &models.User{
+		Email:        b.Email,
+		PasswordHash: hash,
+		Role:         b.Role,
+		CreatedAt:    time.Now().UTC().Truncate(time.Millisecond),
+	}
+	err = h.users.Create(c.UserContext(), user)
+	if errors.Is(err, repository.ErrDuplicate) {
+		return apperror.Conflict("email is already registered")
+	}
+	if err != nil {
+		return err
+	}
+
+	return h.issue(c, 201, *user)
+}
+
+type loginDTO struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+func (h *authHandler) login(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	b := parsedBody[loginDTO](c)
+
+	// Check the credentials, without telling which one was wrong
+	user, err := h.users.GetByEmail(c.UserContext(), b.Email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	if err != nil || !auth.CheckPassword(user.PasswordHash, b.Password) {
+		return apperror.Unauthorized("invalid email or password")
+	}
+
+	return h.issue(c, 200, user)
+}
+
+type refreshDTO struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+func (h *authHandler) refresh(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	b := parsedBody[refreshDTO](c)
+
+	claims, err := h.tokens.ParseRefresh(b.RefreshToken)
+	if err != nil {
+		return apperror.Unauthorized(err.Error())
+	}
+
+	// Reload the user so a deleted user or a changed role is picked up
+	user, err := h.users.Get(c.UserContext(), claims.UserID())
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrInvalidID) {
+		return apperror.Unauthorized(auth.ErrInvalidToken.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	return h.issue(c, 200, user)
+}
+
+func (h *authHandler) me(c *fiber.Ctx) error {
+	user, err := h.users.Get(c.UserContext(), auth.CurrentUser(c).UserID())
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("user not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.Status(200).JSON(fiber.Map{"data": user})
+}
+
+func (h *authHandler) issue(c *fiber.Ctx, status int, user models.User) error {
+	tokens, err := h.tokens.Issue(user)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"data":   user,
+		"tokens": tokens,
+	})
+}
+
+// requireOwner answers 403 unless the current user owns the document at :id, ownerOf looks up the id of its owner
+func requireOwner(ownerOf func(ctx context.Context, id string) (string, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		owner, err := ownerOf(c.UserContext(), c.Params("id"))
+		if err != nil {
+			return err
+		}
+
+		if !auth.CanModify(c, owner) {
+			return apperror.Forbidden("you can only modify your own resources")
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,109 @@
+package router
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/bmdavis419/fiber-mongo-example/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+const dateLayout = "2006-01-02"
+
+// maxAvailabilityDays is the longest range of days returned at once
+const maxAvailabilityDays = 92
+
+type availabilityQuery struct {
+	From string `query:"from" validate:"date"`
+	To   string `query:"to" validate:"date"`
+}
+
+// getAvailability lists what the transport can still take on every day from from to to, the next 30 days by default
+func (h *transportHandler) getAvailability(c *fiber.Ctx) error {
+	// Query checked by validateQuery
+	q := parsedQuery[availabilityQuery](c)
+
+	from := time.Now().UTC().Truncate(24 * time.Hour)
+	if q.From != "" {
+		from, _ = time.Parse(dateLayout, q.From)
+	}
+	to := from.AddDate(0, 0, 29)
+	if q.To != "" {
+		to, _ = time.Parse(dateLayout, q.To)
+	}
+	switch {
+	case to.Before(from):
+		return apperror.Validation("Validation failed", []validation.Violation{
+			{Field: "to", Rule: "after", Message: "must not be before from"},
+		})
+	case to.Sub(from) >= maxAvailabilityDays*24*time.Hour:
+		return apperror.Validation("Validation failed", []validation.Violation{
+			{Field: "to", Rule: "range", Message: "must be less than 92 days after from"},
+		})
+	}
+
+	// Find the transport
+	id := c.Params("id")
+	transport, err := h.repo.Get(c.UserContext(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("transport not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	// Fill the days that aren't stored with empty ones
+	days, err := h.calendar.Days(c.UserContext(), id, from.Format(dateLayout), to.Format(dateLayout))
+	if err != nil {
+		return err
+	}
+	stored := map[string]models.CalendarDay{}
+	for _, day := range days {
+		stored[day.Date] = day
+	}
+
+	availability := make([]models.DayAvailability, 0)
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		day, ok := stored[d.Format(dateLayout)]
+		if !ok {
+			day = models.CalendarDay{TransportId: id, Date: d.Format(dateLayout)}
+		}
+		availability = append(availability, day.Availability(transport))
+	}
+
+	return c.Status(200).JSON(fiber.Map{"data": availability})
+}
+
+// setAvailability replaces the limits of one day, bookings already made stay even when the day no longer fits them
+func (h *transportHandler) setAvailability(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	body := parsedBody[models.CalendarDayUpdate](c)
+
+	date := c.Params("date")
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return apperror.Validation("Validation failed", []validation.Violation{
+			{Field: "date", Rule: "date", Message: "must be a date formatted as YYYY-MM-DD"},
+		})
+	}
+
+	// Find the transport
+	id := c.Params("id")
+	transport, err := h.repo.Get(c.UserContext(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("transport not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	// Update the day
+	day, err := h.calendar.SetDay(c.UserContext(), id, date, body)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(200).JSON(fiber.Map{"data": day.Availability(transport)})
+}
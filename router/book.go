@@ -1,181 +1,111 @@
 package router
 
 import (
-	"github.com/bmdavis419/fiber-mongo-example/common"
+	"errors"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/auth"
 	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func AddBookGroup(app *fiber.App) {
+type bookHandler struct {
+	repo repository.BookRepository
+}
+
+func AddBookGroup(app *fiber.App, repo repository.BookRepository, tokens *auth.Tokens) {
+	h := &bookHandler{repo: repo}
 	bookGroup := app.Group("/books")
 
-	bookGroup.Get("/", getBooks)
-	bookGroup.Get("/:id", getBook)
-	bookGroup.Post("/", createBook)
-	bookGroup.Put("/:id", updateBook)
-	bookGroup.Delete("/:id", deleteBook)
-}
+	admins := auth.Protect(tokens, models.RoleAdmin)
 
-func getBooks(c *fiber.Ctx) error {
-	coll := common.GetDBCollection("books") //
+	bookGroup.Get("/", h.getBooks)
+	bookGroup.Get("/:id", h.getBook)
+	bookGroup.Post("/", admins, validateBody[createDTO](), h.createBook)
+	bookGroup.Put("/:id", admins, validateBody[models.BookUpdate](), h.updateBook)
+	bookGroup.Delete("/:id", admins, h.deleteBook)
+}
 
-	// find all books
-	books := make([]models.Book, 0)
-	cursor, err := coll.Find(c.Context(), bson.M{})
+func (h *bookHandler) getBooks(c *fiber.Ctx) error {
+	// find a page of books
+	opts, err := parseListOptions(c, repository.BookFields)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return apperror.BadRequest(err.Error())
 	}
 
-	// iterate over the cursor
-	for cursor.Next(c.Context()) {
-		book := models.Book{}
-		err := cursor.Decode(&book)
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		}
-		books = append(books, book)
+	page, err := h.repo.List(c.UserContext(), opts)
+	if err != nil {
+		return err
 	}
 
-	return c.Status(200).JSON(fiber.Map{"data": books})
+	return listResponse(c, page)
 }
 
-func getBook(c *fiber.Ctx) error {
-	coll := common.GetDBCollection("books")
-
+func (h *bookHandler) getBook(c *fiber.Ctx) error {
 	// find the book
-	id := c.Params("id")
-	if id == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "id is required",
-		})
+	book, err := h.repo.Get(c.UserContext(), c.Params("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("book not found")
 	}
-	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid id",
-		})
-	}
-
-	book := models.Book{}
-
-	err = coll.FindOne(c.Context(), bson.M{"_id": objectId}).Decode(&book)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return err
 	}
 
 	return c.Status(200).JSON(fiber.Map{"data": book})
 }
 
 type createDTO struct {
-	Title  string `json:"title" bson:"title"`
-	Author string `json:"author" bson:"author"`
-	Year   string `json:"year" bson:"year"`
+	Title  string `json:"title" bson:"title" validate:"required,max=200"`
+	Author string `json:"author" bson:"author" validate:"required,max=100"`
+	Year   string `json:"year" bson:"year" validate:"required,len=4,digits"`
 }
 
-func createBook(c *fiber.Ctx) error {
-	// validate the body
-	b := new(createDTO)
-	if err := c.BodyParser(b); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid body",
-		})
-	}
+func (h *bookHandler) createBook(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	b := parsedBody[createDTO](c)
 
 	// create the book
-	coll := common.GetDBCollection("books")
-	result, err := coll.InsertOne(c.Context(), b)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to create book",
-			"message": err.Error(),
-		})
+	book := &models.Book{
+		Title:  b.Title,
+		Author: b.Author,
+		Year:   b.Year,
+	}
+	if err := h.repo.Create(c.UserContext(), book); err != nil {
+		return err
 	}
 
 	// return the book
-	return c.Status(201).JSON(fiber.Map{
-		"result": result,
-	})
+	c.Location("/books/" + book.ID)
+	return c.Status(201).JSON(fiber.Map{"data": book})
 }
 
-type updateDTO struct {
-	Title  string `json:"title,omitempty" bson:"title,omitempty"`
-	Author string `json:"author,omitempty" bson:"author,omitempty"`
-	Year   string `json:"year,omitempty" bson:"year,omitempty"`
-}
-
-func updateBook(c *fiber.Ctx) error {
-	// validate the body
-	b := new(updateDTO)
-	if err := c.BodyParser(b); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid body",
-		})
-	}
-
-	// get the id
-	id := c.Params("id")
-	if id == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "id is required",
-		})
-	}
-	objectId, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid id",
-		})
-	}
+func (h *bookHandler) updateBook(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	b := parsedBody[models.BookUpdate](c)
 
 	// update the book
-	coll := common.GetDBCollection("books")
-	result, err := coll.UpdateOne(c.Context(), bson.M{"_id": objectId}, bson.M{"$set": b})
+	book, err := h.repo.Update(c.UserContext(), c.Params("id"), b)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("book not found")
+	}
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to update book",
-			"message": err.Error(),
-		})
+		return err
 	}
 
 	// return the book
-	return c.Status(200).JSON(fiber.Map{
-		"result": result,
-	})
+	return c.Status(200).JSON(fiber.Map{"data": book})
 }
 
-func deleteBook(c *fiber.Ctx) error {
-	// get the id
-	id := c.Params("id")
-	if id == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "id is required",
-		})
-	}
-	objectId, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid id",
-		})
-	}
-
+func (h *bookHandler) deleteBook(c *fiber.Ctx) error {
 	// delete the book
-	coll := common.GetDBCollection("books")
-	result, err := coll.DeleteOne(c.Context(), bson.M{"_id": objectId})
+	err := h.repo.Delete(c.UserContext(), c.Params("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("book not found")
+	}
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to delete book",
-			"message": err.Error(),
-		})
+		return err
 	}
 
-	return c.Status(200).JSON(fiber.Map{
-		"result": result,
-	})
+	return c.SendStatus(204)
 }
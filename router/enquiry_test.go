@@ -0,0 +1,246 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+)
+
+// enquiryParties is an enquiry of buyer for the transport of transporter, with users that take no part in it
+type enquiryParties struct {
+	*testApp
+	enquiryId, transportId                          string
+	buyer, transporter, admin, otherBuyer, outsider string
+}
+
+func newEnquiryParties(t *testing.T) *enquiryParties {
+	t.Helper()
+	a := newTestApp(t)
+	p := &enquiryParties{testApp: a}
+	_, p.transporter = a.user("t@x.io", models.RoleTransporter)
+	_, p.buyer = a.user("b@x.io", models.RoleBuyer)
+	_, p.admin = a.user("a@x.io", models.RoleAdmin)
+	_, p.otherBuyer = a.user("b2@x.io", models.RoleBuyer)
+	_, p.outsider = a.user("t2@x.io", models.RoleTransporter)
+	sellerId, _ := a.user("s@x.io", models.RoleSeller)
+
+	var transport data[models.Transport]
+	if status := a.do("POST", "/transports", p.transporter, transportBody("truck"), &transport); status != 201 {
+		t.Fatalf("create transport: got %d", status)
+	}
+	p.transportId = transport.Data.ID
+	// the outsider has a transport too, just not this one
+	a.do("POST", "/transports", p.outsider, transportBody("van"), nil)
+
+	var enquiry data[models.GenerateEnquiry]
+	status := a.do("POST", "/enquiries", p.buyer, map[string]interface{}{
+		"transportId":     p.transportId,
+		"productId":       a.product(sellerId),
+		"quantity":        50,
+		"deliveryAddress": "Mumbai",
+		"dateOfDelivery":  time.Now().AddDate(0, 0, 14).Format("2006-01-02"),
+	}, &enquiry)
+	if status != 201 {
+		t.Fatalf("create enquiry: got %d", status)
+	}
+	p.enquiryId = enquiry.Data.ID
+
+	return p
+}
+
+func TestReadEnquiry(t *testing.T) {
+	p := newEnquiryParties(t)
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"buyer", p.buyer, 200},
+		{"transporter", p.transporter, 200},
+		{"admin", p.admin, 200},
+		{"other buyer", p.otherBuyer, 403},
+		{"other transporter", p.outsider, 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, path := range []string{"/enquiries/" + p.enquiryId, "/enquiries/" + p.enquiryId + "/quotes"} {
+				if status := p.do("GET", path, tt.token, nil, nil); status != tt.status {
+					t.Errorf("GET %s: got %d, want %d", path, status, tt.status)
+				}
+			}
+
+			// the list only holds the enquiries the user is a party to
+			var page list[models.GenerateEnquiry]
+			if status := p.do("GET", "/enquiries", tt.token, nil, &page); status != 200 {
+				t.Fatalf("list: got %d", status)
+			}
+			want := 0
+			if tt.status == 200 {
+				want = 1
+			}
+			if len(page.Data) != want || page.Total != int64(want) {
+				t.Errorf("list has %d of %d enquiries, want %d", len(page.Data), page.Total, want)
+			}
+		})
+	}
+}
+
+func TestTransitionParties(t *testing.T) {
+	tests := []struct {
+		action string
+		token  func(p *enquiryParties) string
+		status int
+	}{
+		{"reject", func(p *enquiryParties) string { return p.buyer }, 403},
+		{"reject", func(p *enquiryParties) string { return p.outsider }, 403},
+		{"reject", func(p *enquiryParties) string { return p.transporter }, 200},
+		{"reject", func(p *enquiryParties) string { return p.admin }, 200},
+		{"cancel", func(p *enquiryParties) string { return p.otherBuyer }, 403},
+		{"cancel", func(p *enquiryParties) string { return p.outsider }, 403},
+		{"cancel", func(p *enquiryParties) string { return p.buyer }, 200},
+		{"cancel", func(p *enquiryParties) string { return p.transporter }, 200},
+		{"cancel", func(p *enquiryParties) string { return p.admin }, 200},
+	}
+
+	for _, tt := range tests {
+		p := newEnquiryParties(t)
+		status := p.do("POST", "/enquiries/"+p.enquiryId+"/"+tt.action, tt.token(p), map[string]interface{}{}, nil)
+		if status != tt.status {
+			t.Errorf("%s: got %d, want %d", tt.action, status, tt.status)
+		}
+	}
+}
+
+func TestEnquiryLifecycle(t *testing.T) {
+	p := newEnquiryParties(t)
+	path := "/enquiries/" + p.enquiryId
+
+	steps := []struct {
+		action string
+		token  string
+		body   map[string]interface{}
+		status int
+		want   models.EnquiryStatus
+	}{
+		{"schedule", p.transporter, nil, 409, models.StatusRequested},
+		{"quote", p.transporter, map[string]interface{}{"distanceKm": 150, "ratePerKm": map[string]interface{}{"amount": 1500, "currency": "INR"}}, 201, models.StatusQuoted},
+		{"accept", p.transporter, map[string]interface{}{"version": 1}, 403, models.StatusQuoted},
+		{"accept", p.buyer, map[string]interface{}{"version": 1}, 200, models.StatusAccepted},
+		{"schedule", p.buyer, nil, 403, models.StatusAccepted},
+		{"schedule", p.transporter, nil, 200, models.StatusScheduled},
+		{"dispatch", p.transporter, nil, 200, models.StatusInTransit},
+		{"cancel", p.buyer, nil, 409, models.StatusInTransit},
+		{"deliver", p.buyer, nil, 403, models.StatusInTransit},
+		{"deliver", p.transporter, nil, 200, models.StatusDelivered},
+	}
+
+	for _, s := range steps {
+		body := s.body
+		if body == nil {
+			body = map[string]interface{}{}
+		}
+		if status := p.do("POST", path+"/"+s.action, s.token, body, nil); status != s.status {
+			t.Fatalf("%s: got %d, want %d", s.action, status, s.status)
+		}
+		var enquiry data[models.GenerateEnquiry]
+		p.do("GET", path, p.admin, nil, &enquiry)
+		if enquiry.Data.Status != s.want {
+			t.Fatalf("after %s the enquiry is %s, want %s", s.action, enquiry.Data.Status, s.want)
+		}
+	}
+
+	// the delivered enquiry can now be reviewed by its buyer
+	review := map[string]interface{}{"enquiryId": p.enquiryId, "rating": 4}
+	if status := p.do("POST", "/transports/"+p.transportId+"/reviews", p.otherBuyer, review, nil); status != 403 {
+		t.Errorf("review by another buyer: got %d, want 403", status)
+	}
+	if status := p.do("POST", "/transports/"+p.transportId+"/reviews", p.admin, review, nil); status != 403 {
+		t.Errorf("review by an admin: got %d, want 403", status)
+	}
+	if status := p.do("POST", "/transports/"+p.transportId+"/reviews", p.buyer, review, nil); status != 201 {
+		t.Errorf("review by the buyer: got %d, want 201", status)
+	}
+}
+
+func TestTransitionConflicts(t *testing.T) {
+	statuses := []models.EnquiryStatus{
+		models.StatusRequested, models.StatusQuoted, models.StatusAccepted, models.StatusScheduled,
+		models.StatusInTransit, models.StatusDelivered, models.StatusRejected, models.StatusCancelled,
+	}
+
+	for _, tr := range models.EnquiryTransitions {
+		// quote and accept need a priced enquiry, they are covered by the lifecycle
+		if tr.Action == "quote" || tr.Action == "accept" {
+			continue
+		}
+		for _, from := range statuses {
+			p := newEnquiryParties(t)
+			if err := p.repos.Enquiries.Transition(context.Background(), p.enquiryId, models.StatusChange{From: models.StatusRequested, To: from}); err != nil {
+				t.Fatal(err)
+			}
+
+			want := 409
+			if tr.Allows(from) {
+				want = 200
+			}
+			if status := p.do("POST", "/enquiries/"+p.enquiryId+"/"+tr.Action, p.admin, map[string]interface{}{}, nil); status != want {
+				t.Errorf("%s from %s: got %d, want %d", tr.Action, from, status, want)
+			}
+		}
+	}
+}
+
+func TestUpdateEnquiryStatuses(t *testing.T) {
+	address := map[string]interface{}{"deliveryAddress": "Pune"}
+	quantity := map[string]interface{}{"quantity": 60}
+
+	tests := []struct {
+		status   models.EnquiryStatus
+		body     map[string]interface{}
+		want     int
+		contains string
+	}{
+		{models.StatusRequested, quantity, 200, ""},
+		{models.StatusQuoted, address, 200, ""},
+		{models.StatusAccepted, address, 200, ""},
+		{models.StatusAccepted, quantity, 409, "cancel it instead"},
+		{models.StatusInTransit, quantity, 409, "cancel it instead"},
+		{models.StatusDelivered, address, 409, "enquiry that is delivered"},
+		{models.StatusDelivered, quantity, 409, "enquiry that is delivered"},
+		{models.StatusCancelled, address, 409, "enquiry that is cancelled"},
+		{models.StatusRejected, map[string]interface{}{"productId": "6390b0c6f1d7a1b2c3d4e5f6"}, 409, "enquiry that is rejected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			p := newEnquiryParties(t)
+			change := models.StatusChange{From: models.StatusRequested, To: tt.status, By: "test"}
+			if err := p.repos.Enquiries.Transition(context.Background(), p.enquiryId, change); err != nil && tt.status != models.StatusRequested {
+				t.Fatal(err)
+			}
+
+			var body struct {
+				Detail string `json:"detail"`
+			}
+			if status := p.do("PUT", "/enquiries/"+p.enquiryId, p.buyer, tt.body, &body); status != tt.want {
+				t.Fatalf("got %d, want %d: %s", status, tt.want, body.Detail)
+			}
+			if !strings.Contains(body.Detail, tt.contains) {
+				t.Errorf("message is %q, want it to contain %q", body.Detail, tt.contains)
+			}
+
+			enquiry, err := p.repos.Enquiries.Get(context.Background(), p.enquiryId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated := enquiry.DeliveryAddress == "Pune" || enquiry.Quantity == 60; updated != (tt.want == 200) {
+				t.Errorf("the enquiry was updated: %v", updated)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package router
+
+import (
+	"github.com/bmdavis419/fiber-mongo-example/health"
+	"github.com/gofiber/fiber/v2"
+)
+
+type healthHandler struct {
+	health *health.Health
+}
+
+// AddHealthGroup adds the probes of the orchestrator, /healthz answers while the process runs and /readyz while the
+// dependencies can be reached and the server isn't shutting down
+func AddHealthGroup(app *fiber.App, h *health.Health) {
+	handler := &healthHandler{health: h}
+
+	app.Get("/healthz", handler.live)
+	app.Get("/readyz", handler.ready)
+}
+
+func (h *healthHandler) live(c *fiber.Ctx) error {
+	return c.Status(200).JSON(fiber.Map{"status": health.StatusUp})
+}
+
+func (h *healthHandler) ready(c *fiber.Ctx) error {
+	report := h.health.Check(c.UserContext())
+
+	// probes must never be cached
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	if !report.Ready() {
+		return c.Status(503).JSON(report)
+	}
+	return c.Status(200).JSON(report)
+}
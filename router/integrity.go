@@ -0,0 +1,200 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/bmdavis419/fiber-mongo-example/validation"
+)
+
+// DeletePolicy decides what happens to open enquiries when their product or transport is deleted
+type DeletePolicy string
+
+const (
+	// DeleteRestrict refuses to delete a product or transport that still has open enquiries
+	DeleteRestrict DeletePolicy = "restrict"
+	// DeleteCascade cancels the open enquiries before deleting
+	DeleteCascade DeletePolicy = "cascade"
+)
+
+func ParseDeletePolicy(s string) (DeletePolicy, error) {
+	switch DeletePolicy(s) {
+	case "", DeleteRestrict:
+		return DeleteRestrict, nil
+	case DeleteCascade:
+		return DeleteCascade, nil
+	}
+	return "", fmt.Errorf("unknown delete policy '%s', use '%s' or '%s'", s, DeleteRestrict, DeleteCascade)
+}
+
+// openStatuses are the statuses an enquiry can still leave, as filter values
+var openStatuses = func() []interface{} {
+	values := make([]interface{}, len(models.OpenStatuses))
+	for i, status := range models.OpenStatuses {
+		values[i] = string(status)
+	}
+	return values
+}()
+
+// checkEnquiryReferences makes sure the product and transport of e exist, accept its quantity, that the transport delivers there
+// and still has room for it on the day of delivery
+func checkEnquiryReferences(ctx context.Context, products repository.ProductRepository, transports repository.TransportRepository, calendar repository.CalendarRepository, e *models.GenerateEnquiry) ([]validation.Violation, error) {
+	violations := make([]validation.Violation, 0)
+
+	product, err := products.Get(ctx, e.ProductId)
+	switch {
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, repository.ErrInvalidID):
+		violations = append(violations, validation.Violation{Field: "productId", Rule: "exists", Message: fmt.Sprintf("product '%s' does not exist", e.ProductId)})
+	case err != nil:
+		return nil, err
+	case e.Quantity < product.MinQuantity:
+		violations = append(violations, validation.Violation{Field: "quantity", Rule: "product_min_quantity", Message: fmt.Sprintf("quantity must be at least %d for this product", product.MinQuantity)})
+	}
+
+	transport, err := transports.Get(ctx, e.TransportId)
+	switch {
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, repository.ErrInvalidID):
+		violations = append(violations, validation.Violation{Field: "transportId", Rule: "exists", Message: fmt.Sprintf("transport '%s' does not exist", e.TransportId)})
+	case err != nil:
+		return nil, err
+	default:
+		if !transport.Available {
+			violations = append(violations, validation.Violation{Field: "transportId", Rule: "available", Message: "transport is not available"})
+		}
+		if e.Quantity < transport.MinQuantity {
+			violations = append(violations, validation.Violation{Field: "quantity", Rule: "transport_min_quantity", Message: fmt.Sprintf("quantity must be at least %d for this transport", transport.MinQuantity)})
+		}
+		if !transport.Serves(e.DeliveryLocation, e.DeliveryAddress) {
+			message := "delivery address is outside the service area of this transport"
+			if e.DeliveryLocation == nil {
+				message = "delivery address could not be located in the service area of this transport, send a deliveryLocation"
+			}
+			violations = append(violations, validation.Violation{Field: "deliveryAddress", Rule: "service_area", Message: message})
+		}
+
+		day, err := calendar.Day(ctx, transport.ID, e.DateOfDelivery)
+		if err != nil {
+			return nil, err
+		}
+		if a := day.Availability(transport); !a.Fits(e.Quantity) {
+			violations = append(violations, validation.Violation{Field: "dateOfDelivery", Rule: "capacity", Message: capacityMessage(a)})
+		}
+	}
+
+	return violations, nil
+}
+
+// capacityMessage explains why a day can't take another booking
+func capacityMessage(a models.DayAvailability) string {
+	switch {
+	case a.Closed:
+		return fmt.Sprintf("transport is closed on %s", a.Date)
+	case a.Slots > 0 && a.Booked >= a.Slots:
+		return fmt.Sprintf("transport is fully booked on %s", a.Date)
+	}
+	return fmt.Sprintf("transport can only take %d more on %s", *a.Remaining, a.Date)
+}
+
+var errHasOpenEnquiries = errors.New("it is referenced by open enquiries")
+
+// releaseEnquiries applies policy to the open enquiries whose field references id before it gets deleted, cancelled enquiries
+// give back their bookings. It returns errHasOpenEnquiries when some of them have to stay open, before cancelling any.
+func releaseEnquiries(ctx context.Context, enquiries repository.EnquiryRepository, calendar repository.CalendarRepository, policy DeletePolicy, field string, id string) error {
+	open, err := openEnquiries(ctx, enquiries, field, id)
+	if err != nil {
+		return err
+	}
+	if len(open) == 0 {
+		return nil
+	}
+	if policy == DeleteRestrict {
+		return fmt.Errorf("%w (%d)", errHasOpenEnquiries, len(open))
+	}
+
+	// enquiries already in transit can't be cancelled, then none of them is
+	cancel, _ := models.FindEnquiryTransition("cancel")
+	stuck := 0
+	for _, e := range open {
+		if !cancel.Allows(e.Status) {
+			stuck++
+		}
+	}
+	if stuck > 0 {
+		return fmt.Errorf("%w (%d in transit)", errHasOpenEnquiries, stuck)
+	}
+
+	note := fmt.Sprintf("cancelled because %s %s was deleted", strings.TrimSuffix(field, "Id"), id)
+	for _, e := range open {
+		if err := cancelEnquiry(ctx, enquiries, calendar, cancel, e, note); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// openEnquiries returns every open enquiry whose field references id
+func openEnquiries(ctx context.Context, enquiries repository.EnquiryRepository, field string, id string) ([]models.GenerateEnquiry, error) {
+	open := make([]models.GenerateEnquiry, 0)
+	opts := repository.ListOptions{
+		Filters: []repository.Filter{
+			{Field: field, Op: repository.Eq, Value: id},
+			{Field: "status", Op: repository.In, Value: openStatuses},
+		},
+		Limit: repository.MaxLimit,
+	}
+	for {
+		page, err := enquiries.List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		open = append(open, page.Items...)
+		if page.NextCursor == "" {
+			return open, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// cancelEnquiry cancels e and releases its booking. When another request changed its status first, it is read again
+// and cancelled from its new status, unless it closed in the meantime. One dispatched in the meantime fails with
+// errHasOpenEnquiries.
+func cancelEnquiry(ctx context.Context, enquiries repository.EnquiryRepository, calendar repository.CalendarRepository, cancel models.EnquiryTransition, e models.GenerateEnquiry, note string) error {
+	for {
+		if e.Status == models.StatusInTransit {
+			return fmt.Errorf("%w (enquiry %s was dispatched meanwhile)", errHasOpenEnquiries, e.ID)
+		}
+		if !cancel.Allows(e.Status) {
+			return nil
+		}
+
+		change := models.StatusChange{From: e.Status, To: cancel.To, By: "system", At: time.Now().UTC().Truncate(time.Millisecond), Note: note}
+		err := enquiries.Transition(ctx, e.ID, change)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		if errors.Is(err, repository.ErrStatusConflict) {
+			e, err = enquiries.Get(ctx, e.ID)
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if e.Booked() {
+			return calendar.Release(ctx, e.TransportId, e.DateOfDelivery, e.ID)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/background"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/bmdavis419/fiber-mongo-example/storage"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const deliveryDate = "2026-11-02"
+
+// openEnquiry stores an enquiry for transportId in status, booked ones hold their booking of the calendar
+func (a *testApp) openEnquiry(transportId string, status models.EnquiryStatus) string {
+	a.t.Helper()
+	ctx := context.Background()
+	enquiry := models.GenerateEnquiry{
+		BuyerId:        primitive.NewObjectID().Hex(),
+		TransportId:    transportId,
+		ProductId:      primitive.NewObjectID().Hex(),
+		Quantity:       10,
+		DateOfDelivery: deliveryDate,
+		Status:         status,
+		StatusHistory:  []models.StatusChange{},
+		Quotes:         []models.Quote{},
+	}
+	if err := a.repos.Enquiries.Create(ctx, &enquiry); err != nil {
+		a.t.Fatal(err)
+	}
+	if enquiry.Booked() {
+		booking := models.Booking{EnquiryId: enquiry.ID, Quantity: enquiry.Quantity}
+		if err := a.repos.Calendar.Reserve(ctx, transportId, deliveryDate, booking, 0, 0); err != nil {
+			a.t.Fatal(err)
+		}
+	}
+	return enquiry.ID
+}
+
+// newCascadeApp runs the product and transport routes of a with the cascade delete policy
+func newCascadeApp(a *testApp) *fiber.App {
+	a.t.Helper()
+	store, err := storage.NewLocal(a.t.TempDir(), "/media")
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.Handler, DisableStartupMessage: true})
+	AddProductGroup(app, a.repos.Products, a.repos.Enquiries, a.repos.Calendar, store, background.New(), DeleteCascade, a.tokens)
+	AddTransportGroup(app, a.repos.Transports, a.repos.Enquiries, a.repos.Calendar, DeleteCascade, a.tokens)
+	return app
+}
+
+func (a *testApp) status(id string) models.EnquiryStatus {
+	a.t.Helper()
+	enquiry, err := a.repos.Enquiries.Get(context.Background(), id)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return enquiry.Status
+}
+
+func TestDeleteTransportCascade(t *testing.T) {
+	a := newTestApp(t)
+	a.app = newCascadeApp(a)
+	ownerId, owner := a.user("t@x.io", models.RoleTransporter)
+	_, other := a.user("t2@x.io", models.RoleTransporter)
+
+	transport := models.Transport{Name: "truck", OwnerId: ownerId, Available: true}
+	if err := a.repos.Transports.Create(context.Background(), &transport); err != nil {
+		t.Fatal(err)
+	}
+	requested := a.openEnquiry(transport.ID, models.StatusRequested)
+	scheduled := a.openEnquiry(transport.ID, models.StatusScheduled)
+	inTransit := a.openEnquiry(transport.ID, models.StatusInTransit)
+	path := "/transports/" + transport.ID
+
+	if status := a.do("DELETE", "/transports/"+primitive.NewObjectID().Hex(), owner, nil, nil); status != 404 {
+		t.Errorf("missing transport: got %d, want 404", status)
+	}
+	if status := a.do("DELETE", path, other, nil, nil); status != 403 {
+		t.Errorf("other transporter: got %d, want 403", status)
+	}
+
+	// the enquiry in transit keeps the transport, and the others open
+	if status := a.do("DELETE", path, owner, nil, nil); status != 409 {
+		t.Errorf("with an enquiry in transit: got %d, want 409", status)
+	}
+	if a.status(requested) != models.StatusRequested || a.status(scheduled) != models.StatusScheduled {
+		t.Fatalf("enquiries were cancelled by refused deletes: %s, %s", a.status(requested), a.status(scheduled))
+	}
+
+	deliver := models.StatusChange{From: models.StatusInTransit, To: models.StatusDelivered, By: "transporter"}
+	if err := a.repos.Enquiries.Transition(context.Background(), inTransit, deliver); err != nil {
+		t.Fatal(err)
+	}
+	if status := a.do("DELETE", path, owner, nil, nil); status != 204 {
+		t.Fatalf("after delivery: got %d, want 204", status)
+	}
+	if a.status(requested) != models.StatusCancelled || a.status(scheduled) != models.StatusCancelled {
+		t.Errorf("enquiries are %s and %s, want cancelled", a.status(requested), a.status(scheduled))
+	}
+	if a.status(inTransit) != models.StatusDelivered {
+		t.Errorf("the delivered enquiry is %s", a.status(inTransit))
+	}
+	if _, err := a.repos.Transports.Get(context.Background(), transport.ID); err != repository.ErrNotFound {
+		t.Errorf("the transport is still there: %v", err)
+	}
+}
+
+func TestDeleteProductCascade(t *testing.T) {
+	a := newTestApp(t)
+	a.app = newCascadeApp(a)
+	sellerId, seller := a.user("s@x.io", models.RoleSeller)
+	_, other := a.user("s2@x.io", models.RoleSeller)
+	productId := a.product(sellerId)
+
+	transportId := primitive.NewObjectID().Hex()
+	enquiry := models.GenerateEnquiry{TransportId: transportId, ProductId: productId, Status: models.StatusAccepted, DateOfDelivery: deliveryDate, Quantity: 10}
+	if err := a.repos.Enquiries.Create(context.Background(), &enquiry); err != nil {
+		t.Fatal(err)
+	}
+	booking := models.Booking{EnquiryId: enquiry.ID, Quantity: enquiry.Quantity}
+	if err := a.repos.Calendar.Reserve(context.Background(), transportId, deliveryDate, booking, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := a.do("DELETE", "/products/"+productId, other, nil, nil); status != 403 {
+		t.Errorf("other seller: got %d, want 403", status)
+	}
+	if a.status(enquiry.ID) != models.StatusAccepted {
+		t.Fatalf("a refused delete cancelled the enquiry")
+	}
+
+	if status := a.do("DELETE", "/products/"+productId, seller, nil, nil); status != 204 {
+		t.Fatalf("got %d, want 204", status)
+	}
+	if a.status(enquiry.ID) != models.StatusCancelled {
+		t.Errorf("the enquiry is %s, want cancelled", a.status(enquiry.ID))
+	}
+	day, err := a.repos.Calendar.Day(context.Background(), transportId, deliveryDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(day.Bookings) != 0 {
+		t.Errorf("the booking wasn't released: %+v", day.Bookings)
+	}
+}
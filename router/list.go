@@ -0,0 +1,102 @@
+package router
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+// filterKey matches filters written as field[op]=value, e.g. price[lte]=100
+var filterKey = regexp.MustCompile(`^(\w+)\[(\w+)\]$`)
+
+// parseListOptions reads ?limit=, ?cursor=, ?sort= and field filters for a resource with the given fields
+func parseListOptions(c *fiber.Ctx, fields repository.Fields) (repository.ListOptions, error) {
+	opts := repository.ListOptions{}
+	var err error
+
+	c.Context().QueryArgs().VisitAll(func(k, v []byte) {
+		if err != nil {
+			return
+		}
+		key, value := string(k), string(v)
+
+		switch key {
+		case "limit":
+			opts.Limit, err = strconv.Atoi(value)
+			if err != nil || opts.Limit < 1 || opts.Limit > repository.MaxLimit {
+				err = fmt.Errorf("limit must be between 1 and %d", repository.MaxLimit)
+			}
+		case "cursor":
+			opts.Cursor = value
+		case "sort":
+			opts.Sort, err = parseSort(value, fields)
+		default:
+			var filter repository.Filter
+			filter, err = parseFilter(key, value, fields)
+			opts.Filters = append(opts.Filters, filter)
+		}
+	})
+
+	return opts, err
+}
+
+// parseSort reads a comma separated list of fields, a leading '-' sorts that field descending
+func parseSort(value string, fields repository.Fields) ([]repository.SortField, error) {
+	sort := make([]repository.SortField, 0)
+	for _, field := range strings.Split(value, ",") {
+		s := repository.SortField{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")}
+		if _, ok := fields[s.Field]; !ok {
+			return nil, fmt.Errorf("cannot sort by '%s'", s.Field)
+		}
+		s.Field = fields.Path(s.Field)
+		sort = append(sort, s)
+	}
+
+	return sort, nil
+}
+
+func parseFilter(key string, value string, fields repository.Fields) (repository.Filter, error) {
+	filter := repository.Filter{Field: key, Op: repository.Eq}
+	if m := filterKey.FindStringSubmatch(key); m != nil {
+		filter.Field, filter.Op = m[1], repository.Operator(m[2])
+	}
+	if !repository.Operators[filter.Op] {
+		return filter, fmt.Errorf("unknown operator '%s'", filter.Op)
+	}
+
+	// in takes a comma separated list of values
+	if filter.Op == repository.In {
+		values := make([]interface{}, 0)
+		for _, raw := range strings.Split(value, ",") {
+			v, err := fields.ParseValue(filter.Field, raw)
+			if err != nil {
+				return filter, err
+			}
+			values = append(values, v)
+		}
+		filter.Field, filter.Value = fields.Path(filter.Field), values
+		return filter, nil
+	}
+
+	v, err := fields.ParseValue(filter.Field, value)
+	filter.Field, filter.Value = fields.Path(filter.Field), v
+	return filter, err
+}
+
+// listResponse writes a page in the envelope shared by all list endpoints
+func listResponse[T any](c *fiber.Ctx, page repository.Page[T]) error {
+	var nextCursor interface{}
+	if page.NextCursor != "" {
+		nextCursor = page.NextCursor
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"data":       page.Items,
+		"nextCursor": nextCursor,
+		"total":      page.Total,
+	})
+}
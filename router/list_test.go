@@ -0,0 +1,66 @@
+package router
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		want    repository.Filter
+		wantErr bool
+	}{
+		{"name", "truck", repository.Filter{Field: "name", Op: repository.Eq, Value: "truck"}, false},
+		{"capacity[gte]", "100", repository.Filter{Field: "capacity", Op: repository.Gte, Value: 100.0}, false},
+		{"available[ne]", "false", repository.Filter{Field: "available", Op: repository.Ne, Value: false}, false},
+		{"name[in]", "truck,van", repository.Filter{Field: "name", Op: repository.In, Value: []interface{}{"truck", "van"}}, false},
+		{"capacity[in]", "1,many", repository.Filter{}, true},
+		{"capacity[like]", "1", repository.Filter{}, true},
+		{"capacity[]", "1", repository.Filter{}, true},
+		{"colour", "red", repository.Filter{}, true},
+		{"colour[eq]", "red", repository.Filter{}, true},
+		{"capacity", "many", repository.Filter{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			got, err := parseFilter(tt.key, tt.value, repository.TransportFields)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []repository.SortField
+		wantErr bool
+	}{
+		{"name", []repository.SortField{{Field: "name"}}, false},
+		{"-capacity,name", []repository.SortField{{Field: "capacity", Desc: true}, {Field: "name"}}, false},
+		{"-price", []repository.SortField{{Field: "price.amount", Desc: true}}, false},
+		{"colour", nil, true},
+		{"name,", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSort(tt.value, repository.TransportFields)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package router
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/geo"
+	"github.com/bmdavis419/fiber-mongo-example/matching"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/bmdavis419/fiber-mongo-example/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+type matchQuery struct {
+	ProductId       string  `query:"productId" validate:"required,objectid"`
+	Quantity        int     `query:"quantity" validate:"required,min=1"`
+	DeliveryAddress string  `query:"deliveryAddress" validate:"required,max=500"`
+	Lat             float64 `query:"lat" validate:"min=-90,max=90"`
+	Lng             float64 `query:"lng" validate:"min=-180,max=180"`
+	Date            string  `query:"date" validate:"date"`
+	// Services is a comma separated list of services the transport must offer
+	Services string `query:"services" validate:"max=500"`
+}
+
+// matchTransports ranks the transports that can carry a product to an address, best first
+func (h *enquiryHandler) matchTransports(c *fiber.Ctx) error {
+	// Query checked by validateQuery
+	q := parsedQuery[matchQuery](c)
+
+	// Find the product
+	product, err := h.products.Get(c.UserContext(), q.ProductId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.Validation("Validation failed", []validation.Violation{
+			{Field: "productId", Rule: "exists", Message: "product does not exist"},
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	// Find where the delivery goes, lat and lng win over the address
+	var sent *geo.Point
+	if c.Query("lat") != "" && c.Query("lng") != "" {
+		p := geo.NewPoint(q.Lat, q.Lng)
+		sent = &p
+	}
+	location, err := h.locate(c.UserContext(), q.DeliveryAddress, sent)
+	if err != nil {
+		return err
+	}
+
+	request := matching.Request{
+		Product:          product,
+		Quantity:         q.Quantity,
+		DeliveryAddress:  q.DeliveryAddress,
+		DeliveryLocation: location,
+	}
+	// Leave out the transports whose day is closed or full whatever their own limits, the rest is checked by matching
+	var full []string
+	if q.Date != "" {
+		days, err := h.calendar.On(c.UserContext(), q.Date)
+		if err != nil {
+			return err
+		}
+		request.Calendar = map[string]models.CalendarDay{}
+		for _, day := range days {
+			request.Calendar[day.TransportId] = day
+			if !day.Availability(models.Transport{}).Fits(q.Quantity) {
+				full = append(full, day.TransportId)
+			}
+		}
+	}
+	for _, s := range strings.Split(q.Services, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			request.Services = append(request.Services, s)
+		}
+	}
+
+	// Only load the transports that can take the quantity in the currency of the product
+	transports, err := h.transports.Candidates(c.UserContext(), q.Quantity, product.Price.Currency, full)
+	if err != nil {
+		return err
+	}
+	matches := matching.Rank(transports, request)
+
+	return c.Status(200).JSON(fiber.Map{
+		"data":  matches,
+		"total": len(matches),
+	})
+}
@@ -0,0 +1,76 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/matching"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+)
+
+func TestMatchTransports(t *testing.T) {
+	a := newTestApp(t)
+	sellerId, _ := a.user("s@x.io", models.RoleSeller)
+	_, buyer := a.user("b@x.io", models.RoleBuyer)
+	productId := a.product(sellerId)
+
+	ids := map[string]string{}
+	create := func(name string, tr models.Transport) {
+		tr.Name = name
+		if tr.Price.IsZero() {
+			tr.Price = money.Money{Amount: 20000, Currency: "INR"}
+		}
+		if err := a.repos.Transports.Create(context.Background(), &tr); err != nil {
+			t.Fatal(err)
+		}
+		ids[tr.ID] = name
+	}
+	create("cheap", models.Transport{Available: true, Price: money.Money{Amount: 10000, Currency: "INR"}})
+	create("big", models.Transport{Available: true, Capacity: 500, Rating: 4})
+	create("small", models.Transport{Available: true, Capacity: 20})
+	create("unavailable", models.Transport{})
+	create("dollars", models.Transport{Available: true, Price: money.Money{Amount: 100, Currency: "USD"}})
+	create("closed", models.Transport{Available: true})
+	create("booked", models.Transport{Available: true, DailyCapacity: 60})
+
+	ctx := context.Background()
+	for id, name := range ids {
+		switch name {
+		case "closed":
+			if _, err := a.repos.Calendar.SetDay(ctx, id, deliveryDate, &models.CalendarDayUpdate{Closed: true}); err != nil {
+				t.Fatal(err)
+			}
+		case "booked":
+			if err := a.repos.Calendar.Reserve(ctx, id, deliveryDate, models.Booking{EnquiryId: "e1", Quantity: 20}, 0, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name string
+		date string
+		want []string
+	}{
+		{"any day", "", []string{"cheap", "big", "closed", "booked"}},
+		{"on the day", "&date=" + deliveryDate, []string{"cheap", "big"}},
+	}
+
+	for _, tt := range tests {
+		var matches list[matching.Match]
+		path := "/enquiries/match?productId=" + productId + "&quantity=50&deliveryAddress=Pune" + tt.date
+		if status := a.do("GET", path, buyer, nil, &matches); status != 200 {
+			t.Fatalf("%s: got %d", tt.name, status)
+		}
+
+		got := []string{}
+		for _, m := range matches.Data {
+			got = append(got, ids[m.Transport.ID])
+		}
+		// cheap wins on price, then big on its rating, the order of the rest is not checked
+		if len(got) != len(tt.want) || got[0] != tt.want[0] || got[1] != tt.want[1] {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package router
+
+import (
+	"errors"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+type mediaHandler struct {
+	store storage.ObjectStore
+}
+
+// AddMediaGroup serves the objects of store, S3 keeps them private so every backend is served from here
+func AddMediaGroup(app *fiber.App, store storage.ObjectStore) {
+	h := &mediaHandler{store: store}
+	mediaGroup := app.Group("/media")
+
+	mediaGroup.Get("/*", h.getMedia)
+}
+
+func (h *mediaHandler) getMedia(c *fiber.Ctx) error {
+	key := c.Params("*")
+	if key == "" {
+		return apperror.BadRequest("key is required")
+	}
+
+	body, err := h.store.Get(c.UserContext(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return apperror.NotFound("media not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	// only images are shown inline and browsers must not guess another type, so an upload can't run scripts on this origin
+	c.Set(fiber.HeaderContentType, storage.ContentType(key))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	if !storage.IsImage(key) {
+		c.Set(fiber.HeaderContentDisposition, "attachment")
+	}
+	return c.Status(200).SendStream(body)
+}
@@ -0,0 +1,107 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/background"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/storage"
+)
+
+func TestUploadOnlyImages(t *testing.T) {
+	a := newTestApp(t)
+	store, err := storage.NewLocal(t.TempDir(), "/media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddProductGroup(a.app, a.repos.Products, a.repos.Enquiries, a.repos.Calendar, store, background.New(), DeleteRestrict, a.tokens)
+	_, seller := a.user("s@x.io", models.RoleSeller)
+
+	tests := []struct {
+		filename string
+		status   int
+	}{
+		{"rice.png", 201},
+		{"rice.JPG", 201},
+		{"rice.webp", 201},
+		{"rice.svg", 422},
+		{"rice.html", 422},
+		{"rice", 422},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			form.WriteField("name", "rice")
+			form.WriteField("price", "12.50")
+			part, _ := form.CreateFormFile("image", tt.filename)
+			part.Write([]byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`))
+			form.Close()
+
+			req := httptest.NewRequest("POST", "/products", &body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			req.Header.Set("Authorization", "Bearer "+seller)
+			resp, err := a.app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("got %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestMediaHeaders(t *testing.T) {
+	a := newTestApp(t)
+	store, err := storage.NewLocal(t.TempDir(), "/media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddMediaGroup(a.app, store)
+
+	// objects stored before SVG was refused are downloaded, not shown
+	tests := []struct {
+		key         string
+		contentType string
+		disposition string
+	}{
+		{"products/a.png", "image/png", ""},
+		{"products/b.svg", "application/octet-stream", "attachment"},
+		{"products/c.html", "application/octet-stream", "attachment"},
+		{"docs/d.pdf", "application/pdf", "attachment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if err := store.Put(context.Background(), tt.key, strings.NewReader("data"), storage.ContentType(tt.key)); err != nil {
+				t.Fatal(err)
+			}
+			resp, err := a.app.Test(httptest.NewRequest("GET", "/media/"+tt.key, nil), -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != 200 {
+				t.Fatalf("got %d, want 200", resp.StatusCode)
+			}
+			if got := resp.Header.Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type is %q, want %q", got, tt.contentType)
+			}
+			if got := resp.Header.Get("Content-Disposition"); got != tt.disposition {
+				t.Errorf("Content-Disposition is %q, want %q", got, tt.disposition)
+			}
+			if got := resp.Header.Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("X-Content-Type-Options is %q, want nosniff", got)
+			}
+		})
+	}
+}
@@ -4,305 +4,229 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"mime/multipart"
-	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/bmdavis419/fiber-mongo-example/common"
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/auth"
+	"github.com/bmdavis419/fiber-mongo-example/background"
+	"github.com/bmdavis419/fiber-mongo-example/metrics"
 	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/bmdavis419/fiber-mongo-example/storage"
+	"github.com/bmdavis419/fiber-mongo-example/validation"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/exp/slog"
 )
 
-func AddProductGroup(app *fiber.App) {
+type productHandler struct {
+	repo      repository.ProductRepository
+	enquiries repository.EnquiryRepository
+	calendar  repository.CalendarRepository
+	store     storage.ObjectStore
+	tasks     *background.Tasks
+	policy    DeletePolicy
+}
+
+func AddProductGroup(app *fiber.App, repo repository.ProductRepository, enquiries repository.EnquiryRepository, calendar repository.CalendarRepository, store storage.ObjectStore, tasks *background.Tasks, policy DeletePolicy, tokens *auth.Tokens) {
+	h := &productHandler{repo: repo, enquiries: enquiries, calendar: calendar, store: store, tasks: tasks, policy: policy}
 	productGroup := app.Group("/products")
 
-	productGroup.Get("/", getProducts)
-	productGroup.Get("/:id", getProduct)
-	productGroup.Post("/", createProduct)
-	productGroup.Put("/:id", updateProduct)
-	productGroup.Delete("/:id", deleteProduct)
+	sellers := auth.Protect(tokens, models.RoleSeller, models.RoleAdmin)
+	owner := requireOwner(h.sellerOf)
+
+	productGroup.Get("/", h.getProducts)
+	productGroup.Get("/:id", h.getProduct)
+	productGroup.Post("/", sellers, validateBody[createPTO](), h.createProduct)
+	productGroup.Put("/:id", sellers, owner, validateBody[models.UpdatePTO](), h.updateProduct)
+	productGroup.Delete("/:id", sellers, h.deleteProduct)
 }
 
-func getProducts(c *fiber.Ctx) error {
-	coll := common.GetDBCollection("products")
+// sellerOf returns the user that sells the product, only they can modify it
+func (h *productHandler) sellerOf(ctx context.Context, id string) (string, error) {
+	product, err := h.repo.Get(ctx, id)
+	return product.SellerId, err
+}
 
-	// Find all products
-	products := make([]models.Product, 0)
-	cursor, err := coll.Find(c.Context(), bson.M{})
+func (h *productHandler) getProducts(c *fiber.Ctx) error {
+	// Find a page of products
+	opts, err := parseListOptions(c, repository.ProductFields)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return apperror.BadRequest(err.Error())
 	}
 
-	// Iterate over the cursor
-	for cursor.Next(c.Context()) {
-		product := models.Product{}
-		err := cursor.Decode(&product)
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		}
-		products = append(products, product)
+	page, err := h.repo.List(c.UserContext(), opts)
+	if err != nil {
+		return err
 	}
 
-	return c.Status(200).JSON(fiber.Map{"data": products})
+	return listResponse(c, page)
 }
 
-func getProduct(c *fiber.Ctx) error {
-	coll := common.GetDBCollection("products")
-
+func (h *productHandler) getProduct(c *fiber.Ctx) error {
 	// Find the product
-	id := c.Params("id")
-	if id == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "id is required",
-		})
-	}
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid id",
-		})
+	product, err := h.repo.Get(c.UserContext(), c.Params("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("product not found")
 	}
-
-	product := models.Product{}
-
-	err = coll.FindOne(c.Context(), bson.M{"_id": objectID}).Decode(&product)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return err
 	}
 
 	return c.Status(200).JSON(fiber.Map{"data": product})
 }
 
 type createPTO struct {
-	Name        string                `form:"name" bson:"name"`
-	Image       *multipart.FileHeader `form:"image" bson:"image"`
-	Description string                `form:"description" bson:"description"`
-	Price       string                `form:"price" bson:"price"`
-	MinQuantity int                   `form:"minQuantity" bson:"minQuantity"`
-	SellerId    string                `form:"sellerId" bson:"sellerId"`
+	Name        string                `form:"name" bson:"name" validate:"required,max=100"`
+	Image       *multipart.FileHeader `form:"image" bson:"image" validate:"required"`
+	Description string                `form:"description" bson:"description" validate:"max=2000"`
+	Price       string                `form:"price" bson:"price" validate:"required,price"`
+	Currency    string                `form:"currency" bson:"currency" validate:"currency"`
+	MinQuantity int                   `form:"minQuantity" bson:"minQuantity" validate:"min=0"`
 }
 
-func createProduct(c *fiber.Ctx) error {
-	// Validate the body
-	p := new(createPTO)
-	if err := c.BodyParser(p); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid body",
-		})
-	}
-
-	awsAccessKeyID := os.Getenv("AWS_ACCESS_KEY_ID")
-	awsSecretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
-
-	// Retrieve AWS region from environment variable
-	awsRegion := os.Getenv("AWS_REGION")
-	if awsRegion == "" {
-		log.Println("AWS_REGION environment variable is not set.")
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to load AWS S3 config",
-			"message": "AWS_REGION environment variable is not set.",
-		})
-	}
+func (h *productHandler) createProduct(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	p := parsedBody[createPTO](c)
 
-	// Setup S3 uploader
-	// cfg, err := config.LoadDefaultConfig(context.TODO())
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion(awsRegion),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(awsAccessKeyID, awsSecretAccessKey, "")),
-	)
+	// Read the price, the currency decides how many decimals it may have
+	price, err := money.Parse(p.Price, p.Currency)
 	if err != nil {
-		log.Printf("error: %v", err)
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to load AWS S3 config",
-			"message": err.Error(),
+		return apperror.Validation("Validation failed", []validation.Violation{
+			{Field: "price", Rule: "price", Message: err.Error()},
 		})
 	}
 
-	client := s3.NewFromConfig(cfg)
-	uploader := manager.NewUploader(client)
-
 	// Handle product image upload
-	imageURL, err := handleProductUpload(c, uploader)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to upload product image",
-			"message": err.Error(),
+	if !storage.IsImage(p.Image.Filename) {
+		return apperror.Validation("Validation failed", []validation.Violation{
+			{Field: "image", Rule: "image", Message: "must be a PNG, JPEG, GIF or WebP image"},
 		})
 	}
+	imageURL, err := h.handleProductUpload(c, p.Image)
+	if err != nil {
+		return err
+	}
 
 	// Set the imageURL in the product struct
-
-	newData := &models.CreatePDB{
+	product := &models.Product{
 		Name:        p.Name,
 		Image:       imageURL,
 		Description: p.Description,
-		Price:       p.Price,
+		Price:       price,
 		MinQuantity: p.MinQuantity,
-		SellerId:    p.SellerId,
+		SellerId:    auth.CurrentUser(c).UserID(),
 	}
 
-	// Create the product
-	coll := common.GetDBCollection("products")
-	result, err := coll.InsertOne(c.Context(), newData)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to create product",
-			"message": err.Error(),
-		})
+	// Create the product, the image is of no use without it
+	if err := h.repo.Create(c.UserContext(), product); err != nil {
+		h.removeImage(imageURL)
+		return err
 	}
 
 	// Return the product
-	return c.Status(201).JSON(fiber.Map{
-		"result": result,
-		"msg":    "Product created successfully",
-	})
+	c.Location("/products/" + product.ID)
+	return c.Status(201).JSON(fiber.Map{"data": product})
 }
 
-type updatePTODB struct {
-	Name        string                `form:"name,omitempty" bson:"name,omitempty"`
-	Image       *multipart.FileHeader `form:"image,omitempty" bson:"image,omitempty"`
-	Description string                `form:"description,omitempty" bson:"description,omitempty"`
-	Price       string                `form:"price,omitempty" bson:"price,omitempty"`
-	MinQuantity int                   `form:"minQuantity,omitempty" bson:"minQuantity,omitempty"`
-	SellerId    string                `form:"sellerId,omitempty" bson:"sellerId,omitempty"`
-}
+func (h *productHandler) updateProduct(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	p := parsedBody[models.UpdatePTO](c)
 
-func updateProduct(c *fiber.Ctx) error {
-	// Validate the body
-	p := new(models.UpdatePTO)
-	if err := c.BodyParser(p); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid body",
-		})
+	// Update the product
+	old, err := h.repo.Get(c.UserContext(), c.Params("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("product not found")
 	}
-
-	// Get the id
-	id := c.Params("id")
-	if id == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "id is required",
-		})
+	if err != nil {
+		return err
+	}
+	product, err := h.repo.Update(c.UserContext(), c.Params("id"), p)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("product not found")
 	}
-	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid id",
-		})
+		return err
 	}
 
-	// Update the product
-	coll := common.GetDBCollection("products")
-	result, err := coll.UpdateOne(c.Context(), bson.M{"_id": objectID}, bson.M{"$set": p})
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to update product",
-			"message": err.Error(),
-		})
+	// Remove the image that was replaced
+	if product.Image != old.Image {
+		h.removeImage(old.Image)
 	}
 
 	// Return the product
-	return c.Status(200).JSON(fiber.Map{
-		"result": result,
-		"msg":    "Product updated successfully",
-	})
+	return c.Status(200).JSON(fiber.Map{"data": product})
 }
 
-func deleteProduct(c *fiber.Ctx) error {
-	// Get the id
+func (h *productHandler) deleteProduct(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if id == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "id is required",
-		})
+
+	// Make sure the product exists and the user can delete it before touching its enquiries
+	product, err := h.repo.Get(c.UserContext(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("product not found")
 	}
-	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid id",
-		})
+		return err
+	}
+	if !auth.CanModify(c, product.SellerId) {
+		return apperror.Forbidden("you can only modify your own resources")
+	}
+
+	// Deal with the enquiries for the product
+	err = releaseEnquiries(c.UserContext(), h.enquiries, h.calendar, h.policy, "productId", id)
+	if errors.Is(err, errHasOpenEnquiries) {
+		return apperror.Conflict("Cannot delete product, " + err.Error())
+	}
+	if err != nil {
+		return err
 	}
 
-	// Delete the product
-	coll := common.GetDBCollection("products")
-	result, err := coll.DeleteOne(c.Context(), bson.M{"_id": objectID})
+	// Delete the product and then its image
+	err = h.repo.Delete(c.UserContext(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("product not found")
+	}
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to delete product",
-			"message": err.Error(),
-		})
+		return err
 	}
+	h.removeImage(product.Image)
 
-	return c.Status(200).JSON(fiber.Map{
-		"result": result,
-		"msg":    "Product deleted successfully",
-	})
+	return c.SendStatus(204)
 }
 
-func handleProductUpload(c *fiber.Ctx, uploader *manager.Uploader) (string, error) {
-	file, err := c.FormFile("image")
-	if err != nil {
-		return "", err
+// removeImage deletes an image from the object store after the response, images stored elsewhere are left alone
+func (h *productHandler) removeImage(url string) {
+	key, ok := storage.KeyOf(h.store, url)
+	if !ok {
+		return
 	}
+	h.tasks.Go("remove "+key, func(ctx context.Context) error {
+		return h.store.Delete(ctx, key)
+	})
+}
 
+func (h *productHandler) handleProductUpload(c *fiber.Ctx, file *multipart.FileHeader) (string, error) {
 	f, err := file.Open()
 	if err != nil {
 		return "", err
 	}
+	defer f.Close()
 
-	// Determine Content-Type based on the file extension (you can enhance this logic)
-	contentType := determineContentType(file.Filename)
-
-	result, err := uploader.Upload(context.TODO(), &s3.PutObjectInput{
-		Bucket:             aws.String("grain"),
-		Key:                aws.String("grains/gi" + file.Filename),
-		Body:               f,
-		ACL:                "public-read",
-		ContentType:        aws.String(contentType),
-		ContentDisposition: aws.String("inline"), // Set to "inline" to display in the browser
-
-	}, func(u *manager.Uploader) {
-		u.PartSize = 6 * 1024 * 1024 // Override the PartSize to 6 MiB
-	})
+	// Give every upload its own key so products never overwrite each other's image
+	key := "products/" + primitive.NewObjectID().Hex() + strings.ToLower(filepath.Ext(file.Filename))
 
+	start := time.Now()
+	err = h.store.Put(c.UserContext(), key, f, storage.ContentType(file.Filename))
+	metrics.ObserveUpload(file.Size, start, err)
 	if err != nil {
-		var mu manager.MultiUploadFailure
-		if errors.As(err, &mu) {
-			fmt.Println("Error:", mu)
-			uploadID := mu.UploadID()
-			return uploadID, err
-		} else {
-			fmt.Println("Error:", err.Error())
-			return "", err
-		}
+		return "", fmt.Errorf("failed to upload %s: %w", key, err)
 	}
+	slog.InfoCtx(c.UserContext(), "image uploaded", "key", key, "bytes", file.Size)
 
-	return result.Location, nil
-}
-
-func determineContentType(filename string) string {
-	// You can implement more sophisticated logic to determine the Content-Type
-	// For simplicity, this example uses a basic mapping based on file extension
-	switch filepath.Ext(filename) {
-	case ".pdf":
-		return "application/pdf"
-	case ".png":
-		return "image/png"
-	default:
-		// Set a default Content-Type or handle unknown types accordingly
-		return "application/octet-stream"
-	}
+	return h.store.URL(key), nil
 }
@@ -0,0 +1,39 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+)
+
+func TestUpdateProduct(t *testing.T) {
+	a := newTestApp(t)
+	a.app = newCascadeApp(a)
+	sellerId, seller := a.user("s@x.io", models.RoleSeller)
+	_, other := a.user("o@x.io", models.RoleSeller)
+	path := "/products/" + a.product(sellerId)
+
+	if status := a.do("PUT", path, other, map[string]interface{}{"name": "mine"}, nil); status != 403 {
+		t.Errorf("update by another seller: got %d, want 403", status)
+	}
+	if status := a.do("PUT", path, seller, map[string]interface{}{"minQuantity": -1}, nil); status != 422 {
+		t.Errorf("negative minimum: got %d, want 422", status)
+	}
+
+	// 0 is a value to set, not a missing field
+	var updated data[models.Product]
+	if status := a.do("PUT", path, seller, map[string]interface{}{"minQuantity": 0}, &updated); status != 200 {
+		t.Fatalf("got %d, want 200", status)
+	}
+	if updated.Data.MinQuantity != 0 || updated.Data.Name != "rice" {
+		t.Errorf("got %+v", updated.Data)
+	}
+
+	// fields left out keep their value
+	if status := a.do("PUT", path, seller, map[string]interface{}{"name": "basmati"}, &updated); status != 200 {
+		t.Fatalf("got %d, want 200", status)
+	}
+	if updated.Data.MinQuantity != 0 || updated.Data.Name != "basmati" || updated.Data.Price.Amount != 1000 {
+		t.Errorf("got %+v", updated.Data)
+	}
+}
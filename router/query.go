@@ -1,136 +1,98 @@
 package router
 
 import (
-	"github.com/bmdavis419/fiber-mongo-example/common"
+	"errors"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/auth"
 	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/exp/slog"
 )
 
-func AddQueryGroup(app *fiber.App) {
+type queryHandler struct {
+	repo repository.QueryRepository
+}
+
+func AddQueryGroup(app *fiber.App, repo repository.QueryRepository, tokens *auth.Tokens) {
+	h := &queryHandler{repo: repo}
 	queryGroup := app.Group("/query")
 
-	queryGroup.Get("/", getQueries)
-	queryGroup.Get("/:id", getQuery)
-	queryGroup.Post("/", createQuery)
-	queryGroup.Delete("/:id", deleteQuery)
-}
+	// anyone can send a query, only admins read them
+	admins := auth.Protect(tokens, models.RoleAdmin)
 
-func getQueries(c *fiber.Ctx) error {
-	coll := common.GetDBCollection("query")
+	queryGroup.Get("/", admins, h.getQueries)
+	queryGroup.Get("/:id", admins, h.getQuery)
+	queryGroup.Post("/", validateBody[QueryBody](), h.createQuery)
+	queryGroup.Delete("/:id", admins, h.deleteQuery)
+}
 
-	// Find all queries
-	queries := make([]models.Query, 0)
-	cursor, err := coll.Find(c.Context(), bson.M{})
+func (h *queryHandler) getQueries(c *fiber.Ctx) error {
+	// Find a page of queries
+	opts, err := parseListOptions(c, repository.QueryFields)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return apperror.BadRequest(err.Error())
 	}
 
-	// Iterate over the cursor
-	for cursor.Next(c.Context()) {
-		query := models.Query{}
-		err := cursor.Decode(&query)
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		}
-		queries = append(queries, query)
+	page, err := h.repo.List(c.UserContext(), opts)
+	if err != nil {
+		return err
 	}
 
-	return c.Status(200).JSON(fiber.Map{"data": queries})
+	return listResponse(c, page)
 }
 
-func getQuery(c *fiber.Ctx) error {
-	coll := common.GetDBCollection("query")
-
+func (h *queryHandler) getQuery(c *fiber.Ctx) error {
 	// Find the query
-	id := c.Params("id")
-	if id == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "id is required",
-		})
-	}
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	query, err := h.repo.Get(c.UserContext(), c.Params("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("query not found")
 	}
-	query := &models.Query{}
-	filter := bson.M{"_id": objectID}
-	err = coll.FindOne(c.Context(), filter).Decode(query)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return err
 	}
 
 	return c.Status(200).JSON(fiber.Map{"data": query})
 }
 
 type QueryBody struct {
-	Name    string `json:"name" bson:"name"`
-	Email   string `json:"email" bson:"email"`
-	Phone   string `json:"phone" bson:"phone"`
-	Message string `json:"message" bson:"message"`
+	Name    string `json:"name" bson:"name" validate:"required,max=100"`
+	Email   string `json:"email" bson:"email" validate:"required,email,max=254"`
+	Phone   string `json:"phone" bson:"phone" validate:"phone"`
+	Message string `json:"message" bson:"message" validate:"required,max=5000"`
 }
 
-func createQuery(c *fiber.Ctx) error {
-	coll := common.GetDBCollection("query")
+func (h *queryHandler) createQuery(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	body := parsedBody[QueryBody](c)
 
-	// New Product struct
-	query := new(QueryBody)
-
-	// Parse body into struct
-	err := c.BodyParser(query)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	// Insert new query
+	query := &models.Query{
+		Name:    body.Name,
+		Email:   body.Email,
+		Phone:   body.Phone,
+		Message: body.Message,
 	}
-
-	// Insert new product
-	result, err := coll.InsertOne(c.Context(), query)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	if err := h.repo.Create(c.UserContext(), query); err != nil {
+		return err
 	}
+	slog.InfoCtx(c.UserContext(), "query received", "query", query)
 
-	// Return product
-	return c.Status(201).JSON(fiber.Map{"data": result})
+	// Return query
+	c.Location("/query/" + query.ID)
+	return c.Status(201).JSON(fiber.Map{"data": query})
 }
 
-func deleteQuery(c *fiber.Ctx) error {
-	coll := common.GetDBCollection("query")
-
-	// Find the query
-	id := c.Params("id")
-	if id == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "id is required",
-		})
-	}
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+func (h *queryHandler) deleteQuery(c *fiber.Ctx) error {
+	// Delete the query
+	err := h.repo.Delete(c.UserContext(), c.Params("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("query not found")
 	}
-
-	filter := bson.M{"_id": objectID}
-	_, err = coll.DeleteOne(c.Context(), filter)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return err
 	}
 
-	return c.Status(200).JSON(fiber.Map{
-		"msg": "Query deleted successfully",
-	})
+	return c.SendStatus(204)
 }
@@ -0,0 +1,248 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/auth"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+	"github.com/bmdavis419/fiber-mongo-example/pricing"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/bmdavis419/fiber-mongo-example/validation"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/exp/slog"
+)
+
+// defaultQuoteValidity is how long a quote can be accepted when validForHours isn't sent
+const defaultQuoteValidity = 72 * time.Hour
+
+type surchargeDTO struct {
+	Name   string      `json:"name" validate:"required,max=100"`
+	Amount money.Money `json:"amount" validate:"required,money"`
+}
+
+type quoteDTO struct {
+	DistanceKm    int            `json:"distanceKm" validate:"min=0,max=20000"`
+	RatePerKm     money.Money    `json:"ratePerKm" validate:"money"`
+	Surcharges    []surchargeDTO `json:"surcharges" validate:"max=20,dive"`
+	ValidForHours int            `json:"validForHours" validate:"min=1,max=720"`
+	Note          string         `json:"note" validate:"max=1000"`
+}
+
+type acceptDTO struct {
+	Version int    `json:"version" validate:"required,min=1"`
+	Note    string `json:"note" validate:"max=1000"`
+}
+
+func (h *enquiryHandler) getQuotes(c *fiber.Ctx) error {
+	// Find the enquiry
+	enquiry, err := h.repo.Get(c.UserContext(), c.Params("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("enquiry not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.Status(200).JSON(fiber.Map{"data": enquiry.Quotes})
+}
+
+func (h *enquiryHandler) getQuote(c *fiber.Ctx) error {
+	// Find the enquiry
+	enquiry, err := h.repo.Get(c.UserContext(), c.Params("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("enquiry not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	// Find the version
+	version, _ := strconv.Atoi(c.Params("version"))
+	quote, ok := enquiry.Quote(version)
+	if !ok {
+		return apperror.NotFound("quote not found")
+	}
+
+	return c.Status(200).JSON(fiber.Map{"data": quote})
+}
+
+// quoteEnquiry prices the enquiry from its product and transport and adds the result as its next quote version
+func (h *enquiryHandler) quoteEnquiry(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	body := parsedBody[quoteDTO](c)
+	if body.DistanceKm > 0 && body.RatePerKm.IsZero() {
+		return apperror.Validation("Validation failed", []validation.Violation{
+			{Field: "ratePerKm", Rule: "required", Message: "is required with distanceKm"},
+		})
+	}
+
+	// Find the enquiry
+	id := c.Params("id")
+	enquiry, err := h.repo.Get(c.UserContext(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("enquiry not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	t, _ := models.FindEnquiryTransition("quote")
+	if !t.Allows(enquiry.Status) {
+		return apperror.Conflict(fmt.Sprintf("cannot quote an enquiry that is %s", enquiry.Status))
+	}
+
+	// Only the transporter carrying the enquiry quotes it
+	transport, err := h.transports.Get(c.UserContext(), enquiry.TransportId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.Conflict("the transport of the enquiry no longer exists")
+	}
+	if err != nil {
+		return err
+	}
+	if !auth.CanModify(c, transport.OwnerId) {
+		return apperror.Forbidden("only the transporter of the enquiry can quote it")
+	}
+	product, err := h.products.Get(c.UserContext(), enquiry.ProductId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.Conflict("the product of the enquiry no longer exists")
+	}
+	if err != nil {
+		return err
+	}
+
+	// Price the enquiry
+	surcharges := make([]pricing.Surcharge, len(body.Surcharges))
+	for i, s := range body.Surcharges {
+		surcharges[i] = pricing.Surcharge{Name: s.Name, Amount: s.Amount}
+	}
+	lines, total, err := pricing.Calculate(pricing.Input{
+		Product:    product,
+		Transport:  transport,
+		Quantity:   enquiry.Quantity,
+		DistanceKm: body.DistanceKm,
+		RatePerKm:  body.RatePerKm,
+		Surcharges: surcharges,
+		Tiers:      pricing.DefaultTiers,
+	})
+	if err != nil {
+		return apperror.Validation("Cannot quote the enquiry, "+err.Error(), nil)
+	}
+
+	validFor := defaultQuoteValidity
+	if body.ValidForHours > 0 {
+		validFor = time.Duration(body.ValidForHours) * time.Hour
+	}
+	user := auth.CurrentUser(c)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	quote := models.Quote{
+		Version:   len(enquiry.Quotes) + 1,
+		Lines:     lines,
+		Total:     total,
+		By:        user.UserID(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(validFor),
+		Note:      body.Note,
+	}
+
+	// Store the quote, the enquiry becomes quoted
+	change := models.StatusChange{
+		From: enquiry.Status,
+		To:   t.To,
+		By:   user.UserID(),
+		At:   now,
+		Note: fmt.Sprintf("quote version %d", quote.Version),
+	}
+	if err := h.repo.AddQuote(c.UserContext(), id, quote, change); err != nil {
+		return err
+	}
+
+	// Return the quote
+	c.Location(fmt.Sprintf("/enquiries/%s/quotes/%d", id, quote.Version))
+	return c.Status(201).JSON(fiber.Map{"data": quote})
+}
+
+// acceptQuote accepts one version of the quotes of the enquiry, as long as it hasn't expired
+func (h *enquiryHandler) acceptQuote(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	body := parsedBody[acceptDTO](c)
+
+	// Find the enquiry
+	id := c.Params("id")
+	enquiry, err := h.repo.Get(c.UserContext(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("enquiry not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	t, _ := models.FindEnquiryTransition("accept")
+	if !t.Allows(enquiry.Status) {
+		return apperror.Conflict(fmt.Sprintf("cannot accept an enquiry that is %s", enquiry.Status))
+	}
+
+	// Check the quote
+	quote, ok := enquiry.Quote(body.Version)
+	if !ok {
+		return apperror.Validation("Validation failed", []validation.Violation{
+			{Field: "version", Rule: "exists", Message: "the enquiry has no quote with this version"},
+		})
+	}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if quote.Expired(now) {
+		return apperror.Conflict(fmt.Sprintf("quote version %d expired at %s", quote.Version, quote.ExpiresAt.Format(time.RFC3339)))
+	}
+
+	// Reserve the quantity on the calendar of the transport
+	transport, err := h.transports.Get(c.UserContext(), enquiry.TransportId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.Conflict("the transport of the enquiry no longer exists")
+	}
+	if err != nil {
+		return err
+	}
+	day, err := h.calendar.Day(c.UserContext(), enquiry.TransportId, enquiry.DateOfDelivery)
+	if err != nil {
+		return err
+	}
+	a := day.Availability(transport)
+	if !a.Fits(enquiry.Quantity) {
+		return apperror.Conflict(capacityMessage(a))
+	}
+	booking := models.Booking{EnquiryId: id, Quantity: enquiry.Quantity, At: now}
+	err = h.calendar.Reserve(c.UserContext(), enquiry.TransportId, enquiry.DateOfDelivery, booking, a.Capacity, a.Slots)
+	if errors.Is(err, repository.ErrFullyBooked) {
+		return apperror.Conflict(fmt.Sprintf("transport is fully booked on %s", enquiry.DateOfDelivery))
+	}
+	if err != nil {
+		return err
+	}
+
+	// Move the enquiry to accepted, giving the booking back when that fails
+	change := models.StatusChange{
+		From: enquiry.Status,
+		To:   t.To,
+		By:   auth.CurrentUser(c).UserID(),
+		At:   now,
+		Note: body.Note,
+	}
+	if err := h.repo.AcceptQuote(c.UserContext(), id, quote.Version, change); err != nil {
+		// the accept error is the one reported, a failed release leaves the day looking fuller than it is until fixed
+		if releaseErr := h.calendar.Release(c.UserContext(), enquiry.TransportId, enquiry.DateOfDelivery, id); releaseErr != nil {
+			slog.ErrorCtx(c.UserContext(), "booking of a refused accept not released", "enquiry", id,
+				"transport", enquiry.TransportId, "date", enquiry.DateOfDelivery, "err", releaseErr)
+		}
+		return err
+	}
+
+	enquiry.Status = t.To
+	enquiry.AcceptedQuote = quote.Version
+	enquiry.StatusHistory = append(enquiry.StatusHistory, change)
+
+	return c.Status(200).JSON(fiber.Map{"data": enquiry})
+}
@@ -0,0 +1,174 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/auth"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/bmdavis419/fiber-mongo-example/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+type reviewHandler struct {
+	repo       repository.ReviewRepository
+	enquiries  repository.EnquiryRepository
+	products   repository.ProductRepository
+	transports repository.TransportRepository
+}
+
+// AddReviewGroup adds the reviews of transports and products, e.g. POST /transports/:id/reviews
+func AddReviewGroup(app *fiber.App, repo repository.ReviewRepository, enquiries repository.EnquiryRepository, products repository.ProductRepository, transports repository.TransportRepository, tokens *auth.Tokens) {
+	h := &reviewHandler{repo: repo, enquiries: enquiries, products: products, transports: transports}
+
+	buyers := auth.Protect(tokens, models.RoleBuyer)
+
+	for _, subject := range []models.ReviewSubject{models.ReviewTransport, models.ReviewProduct} {
+		group := app.Group("/" + string(subject) + "s/:id/reviews")
+		group.Get("/", h.getReviews(subject))
+		group.Get("/:reviewId", h.getReview(subject))
+		group.Post("/", buyers, validateBody[reviewDTO](), h.createReview(subject))
+	}
+}
+
+type reviewDTO struct {
+	EnquiryId string `json:"enquiryId" validate:"required,objectid"`
+	Rating    int    `json:"rating" validate:"required,min=1,max=5"`
+	Comment   string `json:"comment" validate:"max=2000"`
+}
+
+// find returns apperror.NotFound when the reviewed transport or product doesn't exist
+func (h *reviewHandler) find(ctx context.Context, subject models.ReviewSubject, id string) error {
+	var err error
+	switch subject {
+	case models.ReviewTransport:
+		_, err = h.transports.Get(ctx, id)
+	case models.ReviewProduct:
+		_, err = h.products.Get(ctx, id)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound(fmt.Sprintf("%s not found", subject))
+	}
+	return err
+}
+
+func (h *reviewHandler) getReviews(subject models.ReviewSubject) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		if err := h.find(c.UserContext(), subject, id); err != nil {
+			return err
+		}
+
+		// Find a page of the reviews of the subject
+		opts, err := parseListOptions(c, repository.ReviewFields)
+		if err != nil {
+			return apperror.BadRequest(err.Error())
+		}
+		opts.Filters = append(opts.Filters,
+			repository.Filter{Field: "subject", Op: repository.Eq, Value: string(subject)},
+			repository.Filter{Field: "subjectId", Op: repository.Eq, Value: id},
+		)
+
+		page, err := h.repo.List(c.UserContext(), opts)
+		if err != nil {
+			return err
+		}
+
+		return listResponse(c, page)
+	}
+}
+
+func (h *reviewHandler) getReview(subject models.ReviewSubject) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Find the review, it has to be about the subject of the path
+		review, err := h.repo.Get(c.UserContext(), c.Params("reviewId"))
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && (review.Subject != subject || review.SubjectId != c.Params("id"))) {
+			return apperror.NotFound("review not found")
+		}
+		if err != nil {
+			return err
+		}
+
+		return c.Status(200).JSON(fiber.Map{"data": review})
+	}
+}
+
+// createReview lets the buyer of a delivered enquiry review its transport or product once, the rating of the subject is then recomputed
+func (h *reviewHandler) createReview(subject models.ReviewSubject) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Body checked by validateBody
+		body := parsedBody[reviewDTO](c)
+
+		id := c.Params("id")
+		if err := h.find(c.UserContext(), subject, id); err != nil {
+			return err
+		}
+
+		// Check the enquiry
+		enquiry, err := h.enquiries.Get(c.UserContext(), body.EnquiryId)
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperror.Validation("Validation failed", []validation.Violation{
+				{Field: "enquiryId", Rule: "exists", Message: "enquiry does not exist"},
+			})
+		}
+		if err != nil {
+			return err
+		}
+		reviewed := enquiry.TransportId
+		if subject == models.ReviewProduct {
+			reviewed = enquiry.ProductId
+		}
+		if reviewed != id {
+			return apperror.Validation("Validation failed", []validation.Violation{
+				{Field: "enquiryId", Rule: "subject", Message: fmt.Sprintf("enquiry is not for this %s", subject)},
+			})
+		}
+		if auth.CurrentUser(c).UserID() != enquiry.BuyerId {
+			return apperror.Forbidden("only the buyer of the enquiry can review it")
+		}
+		if enquiry.Status != models.StatusDelivered {
+			return apperror.Conflict(fmt.Sprintf("cannot review an enquiry that is %s, it has to be delivered", enquiry.Status))
+		}
+
+		// Create the review
+		review := &models.Review{
+			Subject:   subject,
+			SubjectId: id,
+			EnquiryId: enquiry.ID,
+			BuyerId:   enquiry.BuyerId,
+			Rating:    body.Rating,
+			Comment:   body.Comment,
+			CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+		}
+		err = h.repo.Create(c.UserContext(), review)
+		if errors.Is(err, repository.ErrDuplicate) {
+			return apperror.Conflict(fmt.Sprintf("the enquiry already has a review of its %s", subject))
+		}
+		if err != nil {
+			return err
+		}
+
+		// Recompute the rating of the subject, a summary older than the stored one is dropped
+		summary, err := h.repo.Summarize(c.UserContext(), subject, id)
+		if err != nil {
+			return err
+		}
+		switch subject {
+		case models.ReviewTransport:
+			err = h.transports.SetRating(c.UserContext(), id, summary)
+		case models.ReviewProduct:
+			err = h.products.SetRating(c.UserContext(), id, summary)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Return the review
+		c.Location(fmt.Sprintf("/%ss/%s/reviews/%s", subject, id, review.ID))
+		return c.Status(201).JSON(fiber.Map{"data": review})
+	}
+}
@@ -0,0 +1,115 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// deliver moves the enquiry of p straight to delivered and returns its product
+func (p *enquiryParties) deliver() string {
+	p.t.Helper()
+	ctx := context.Background()
+	enquiry, err := p.repos.Enquiries.Get(ctx, p.enquiryId)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	change := models.StatusChange{From: enquiry.Status, To: models.StatusDelivered, By: "transporter"}
+	if err := p.repos.Enquiries.Transition(ctx, p.enquiryId, change); err != nil {
+		p.t.Fatal(err)
+	}
+	return enquiry.ProductId
+}
+
+func TestCreateReview(t *testing.T) {
+	p := newEnquiryParties(t)
+	transportReviews := "/transports/" + p.transportId + "/reviews"
+	review := map[string]interface{}{"enquiryId": p.enquiryId, "rating": 4, "comment": "on time"}
+
+	if status := p.do("POST", transportReviews, p.buyer, review, nil); status != 409 {
+		t.Errorf("before delivery: got %d, want 409", status)
+	}
+	productId := p.deliver()
+	otherProduct := p.product(primitive.NewObjectID().Hex())
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		body   map[string]interface{}
+		status int
+	}{
+		{"transporter", transportReviews, p.transporter, review, 403},
+		{"admin", transportReviews, p.admin, review, 403},
+		{"other buyer", transportReviews, p.otherBuyer, review, 403},
+		{"no rating", transportReviews, p.buyer, map[string]interface{}{"enquiryId": p.enquiryId}, 422},
+		{"six stars", transportReviews, p.buyer, map[string]interface{}{"enquiryId": p.enquiryId, "rating": 6}, 422},
+		{"missing enquiry", transportReviews, p.buyer, map[string]interface{}{"enquiryId": primitive.NewObjectID().Hex(), "rating": 4}, 422},
+		{"enquiry of another product", "/products/" + otherProduct + "/reviews", p.buyer, review, 422},
+		{"missing transport", "/transports/" + primitive.NewObjectID().Hex() + "/reviews", p.buyer, review, 404},
+		{"buyer", transportReviews, p.buyer, review, 201},
+		{"twice", transportReviews, p.buyer, review, 409},
+		{"the product too", "/products/" + productId + "/reviews", p.buyer, map[string]interface{}{"enquiryId": p.enquiryId, "rating": 2}, 201},
+	}
+
+	for _, tt := range tests {
+		if status := p.do("POST", tt.path, tt.token, tt.body, nil); status != tt.status {
+			t.Errorf("%s: got %d, want %d", tt.name, status, tt.status)
+		}
+	}
+
+	var reviews list[models.Review]
+	if status := p.do("GET", transportReviews, "", nil, &reviews); status != 200 {
+		t.Fatalf("list: got %d", status)
+	}
+	if len(reviews.Data) != 1 || reviews.Data[0].Rating != 4 || reviews.Data[0].Comment != "on time" {
+		t.Fatalf("got %+v, want the one review", reviews.Data)
+	}
+	if status := p.do("GET", "/products/"+productId+"/reviews/"+reviews.Data[0].ID, "", nil, nil); status != 404 {
+		t.Errorf("review of the transport under the product: got %d, want 404", status)
+	}
+}
+
+func TestReviewRating(t *testing.T) {
+	p := newEnquiryParties(t)
+	p.deliver()
+	if status := p.do("POST", "/transports/"+p.transportId+"/reviews", p.buyer, map[string]interface{}{"enquiryId": p.enquiryId, "rating": 5}, nil); status != 201 {
+		t.Fatalf("got %d, want 201", status)
+	}
+
+	// earlier reviews of other enquiries
+	for _, rating := range []int{2, 4} {
+		r := models.Review{Subject: models.ReviewTransport, SubjectId: p.transportId, EnquiryId: primitive.NewObjectID().Hex(), Rating: rating}
+		if err := p.repos.Reviews.Create(context.Background(), &r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	second := newEnquiryFor(p)
+	if status := p.do("POST", "/transports/"+p.transportId+"/reviews", p.buyer, map[string]interface{}{"enquiryId": second, "rating": 4}, nil); status != 201 {
+		t.Fatalf("got %d, want 201", status)
+	}
+
+	var transport data[models.Transport]
+	p.do("GET", "/transports/"+p.transportId, "", nil, &transport)
+	want := models.NewRatingSummary([5]int{0, 1, 0, 2, 1})
+	if got := transport.Data; got.Rating != want.Average || got.RatingCount != want.Count || got.RatingDistribution != want.Distribution {
+		t.Errorf("rating is %v (%d) %v, want %v (%d) %v", got.Rating, got.RatingCount, got.RatingDistribution, want.Average, want.Count, want.Distribution)
+	}
+}
+
+// newEnquiryFor stores another delivered enquiry of the buyer of p for its transport
+func newEnquiryFor(p *enquiryParties) string {
+	p.t.Helper()
+	first, err := p.repos.Enquiries.Get(context.Background(), p.enquiryId)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	enquiry := first
+	enquiry.ID = ""
+	if err := p.repos.Enquiries.Create(context.Background(), &enquiry); err != nil {
+		p.t.Fatal(err)
+	}
+	return enquiry.ID
+}
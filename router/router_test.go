@@ -0,0 +1,110 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/auth"
+	"github.com/bmdavis419/fiber-mongo-example/geo"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+// testApp runs the transport, enquiry and review routes on in memory repositories
+type testApp struct {
+	t      *testing.T
+	app    *fiber.App
+	repos  *repository.Repositories
+	tokens *auth.Tokens
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+	tokens, err := auth.NewTokens("a-secret-of-at-least-32-characters!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos := repository.NewMemory()
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.Handler, DisableStartupMessage: true})
+	AddTransportGroup(app, repos.Transports, repos.Enquiries, repos.Calendar, DeleteRestrict, tokens)
+	AddEnquiryGroup(app, repos.Enquiries, repos.Products, repos.Transports, repos.Calendar, geo.NewStatic(geo.Cities), tokens)
+	AddReviewGroup(app, repos.Reviews, repos.Enquiries, repos.Products, repos.Transports, tokens)
+
+	return &testApp{t: t, app: app, repos: repos, tokens: tokens}
+}
+
+// user creates a user with role and returns its id and access token
+func (a *testApp) user(email string, role models.Role) (string, string) {
+	a.t.Helper()
+	user := models.User{Email: email, Role: role, CreatedAt: time.Now().UTC()}
+	if err := a.repos.Users.Create(context.Background(), &user); err != nil {
+		a.t.Fatal(err)
+	}
+	pair, err := a.tokens.Issue(user)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return user.ID, pair.AccessToken
+}
+
+// product stores a product of sellerId priced at ₹10 per unit
+func (a *testApp) product(sellerId string) string {
+	a.t.Helper()
+	product := models.Product{Name: "rice", Price: money.Money{Amount: 1000, Currency: "INR"}, MinQuantity: 1, SellerId: sellerId}
+	if err := a.repos.Products.Create(context.Background(), &product); err != nil {
+		a.t.Fatal(err)
+	}
+	return product.ID
+}
+
+// do sends body as JSON with the token and decodes the JSON response into out when it isn't nil
+func (a *testApp) do(method string, path string, token string, body interface{}, out interface{}) int {
+	a.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := a.app.Test(req, -1)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			a.t.Fatalf("%s %s: decode response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// data is the envelope of single resources
+type data[T any] struct {
+	Data T `json:"data"`
+}
+
+// list is the envelope of pages
+type list[T any] struct {
+	Data       []T     `json:"data"`
+	NextCursor *string `json:"nextCursor"`
+	Total      int64   `json:"total"`
+}
@@ -0,0 +1,57 @@
+package router
+
+import (
+	"github.com/bmdavis419/fiber-mongo-example/search"
+	"github.com/gofiber/fiber/v2"
+)
+
+type searchHandler struct {
+	searcher search.Searcher
+}
+
+func AddSearchGroup(app *fiber.App, searcher search.Searcher) {
+	h := &searchHandler{searcher: searcher}
+
+	app.Get("/search", validateQuery[searchQuery](), h.search)
+}
+
+type searchQuery struct {
+	Q        string `query:"q" validate:"required,max=200"`
+	Type     string `query:"type" validate:"oneof=product transport"`
+	SellerId string `query:"sellerId" validate:"objectid"`
+	Services string `query:"services" validate:"max=100"`
+	Limit    int    `query:"limit" validate:"min=1,max=100"`
+	Offset   int    `query:"offset" validate:"min=0,max=1000"`
+}
+
+// search finds the products and transports matching q, best first, with the facet counts of every hit
+func (h *searchHandler) search(c *fiber.Ctx) error {
+	// Query checked by validateQuery
+	q := parsedQuery[searchQuery](c)
+
+	query := search.Query{Text: q.Q, Facets: map[string]string{}, Limit: q.Limit, Offset: q.Offset}
+	if query.Limit == 0 {
+		query.Limit = 20
+	}
+	if q.Type != "" {
+		query.Kinds = []search.Kind{search.Kind(q.Type)}
+	}
+	if q.SellerId != "" {
+		query.Facets[search.FacetSeller] = q.SellerId
+	}
+	if q.Services != "" {
+		query.Facets[search.FacetServices] = q.Services
+	}
+
+	result, err := h.searcher.Search(c.UserContext(), query)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"data":    result.Hits,
+		"total":   result.Total,
+		"facets":  result.Facets,
+		"partial": result.Partial,
+	})
+}
@@ -1,361 +1,643 @@
 package router
 
 import (
-	"github.com/bmdavis419/fiber-mongo-example/common"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/auth"
+	"github.com/bmdavis419/fiber-mongo-example/geo"
 	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/bmdavis419/fiber-mongo-example/validation"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func AddTransportGroup(app *fiber.App) {
+type transportHandler struct {
+	repo      repository.TransportRepository
+	enquiries repository.EnquiryRepository
+	calendar  repository.CalendarRepository
+	policy    DeletePolicy
+}
+
+func AddTransportGroup(app *fiber.App, repo repository.TransportRepository, enquiries repository.EnquiryRepository, calendar repository.CalendarRepository, policy DeletePolicy, tokens *auth.Tokens) {
+	h := &transportHandler{repo: repo, enquiries: enquiries, calendar: calendar, policy: policy}
 	transportGroup := app.Group("/transports")
 
-	transportGroup.Get("/", getTransports)
-	transportGroup.Get("/:id", getTransport)
-	transportGroup.Post("/", createTransport)
-	transportGroup.Put("/:id", updateTransport)
-	transportGroup.Delete("/:id", deleteTransport)
+	transporters := auth.Protect(tokens, models.RoleTransporter, models.RoleAdmin)
+	owner := requireOwner(h.ownerOf)
+
+	transportGroup.Get("/", h.getTransports)
+	transportGroup.Get("/near", validateQuery[nearQuery](), h.nearTransports)
+	transportGroup.Get("/:id", h.getTransport)
+	transportGroup.Post("/", transporters, validateBody[TransportQuery](), h.createTransport)
+	transportGroup.Put("/:id", transporters, owner, validateBody[models.TransportUpdate](), h.updateTransport)
+	transportGroup.Delete("/:id", transporters, h.deleteTransport)
+
+	// capacity calendar
+	transportGroup.Get("/:id/availability", validateQuery[availabilityQuery](), h.getAvailability)
+	transportGroup.Put("/:id/availability/:date", transporters, owner, validateBody[models.CalendarDayUpdate](), h.setAvailability)
 }
 
-func getTransports(c *fiber.Ctx) error {
-	coll := common.GetDBCollection("transports")
+// ownerOf returns the transporter running the transport, only they can modify it
+func (h *transportHandler) ownerOf(ctx context.Context, id string) (string, error) {
+	transport, err := h.repo.Get(ctx, id)
+	return transport.OwnerId, err
+}
 
-	// Find all transports
-	transports := make([]models.Transport, 0)
-	cursor, err := coll.Find(c.Context(), bson.M{})
+func (h *transportHandler) getTransports(c *fiber.Ctx) error {
+	// Find a page of transports
+	opts, err := parseListOptions(c, repository.TransportFields)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return apperror.BadRequest(err.Error())
 	}
 
-	// Iterate over the cursor
-	for cursor.Next(c.Context()) {
-		transport := models.Transport{}
-		err := cursor.Decode(&transport)
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		}
-		transports = append(transports, transport)
+	page, err := h.repo.List(c.UserContext(), opts)
+	if err != nil {
+		return err
 	}
 
-	return c.Status(200).JSON(fiber.Map{"data": transports})
+	return listResponse(c, page)
 }
 
-func getTransport(c *fiber.Ctx) error {
-	coll := common.GetDBCollection("transports")
+type nearQuery struct {
+	Lat      float64 `query:"lat" validate:"min=-90,max=90"`
+	Lng      float64 `query:"lng" validate:"min=-180,max=180"`
+	RadiusKm float64 `query:"radiusKm" validate:"min=0,max=2000"`
+	Limit    int     `query:"limit" validate:"min=0,max=100"`
+}
 
-	// Find the transport
-	id := c.Params("id")
-	if id == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "id is required",
-		})
+// nearTransports finds the transports located within radiusKm of a point, closest first
+func (h *transportHandler) nearTransports(c *fiber.Ctx) error {
+	// Query checked by validateQuery, 0 is a valid coordinate so check they were sent
+	q := parsedQuery[nearQuery](c)
+	var violations []validation.Violation
+	for _, name := range []string{"lat", "lng"} {
+		if c.Query(name) == "" {
+			violations = append(violations, validation.Violation{Field: name, Rule: "required", Message: "is required"})
+		}
+	}
+	if len(violations) > 0 {
+		return apperror.Validation("Validation failed", violations)
+	}
+	if q.RadiusKm == 0 {
+		q.RadiusKm = 50
+	}
+	if q.Limit == 0 {
+		q.Limit = 20
 	}
-	objectID, err := primitive.ObjectIDFromHex(id)
+
+	nearby, err := h.repo.Near(c.UserContext(), geo.NewPoint(q.Lat, q.Lng), q.RadiusKm, q.Limit)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid id",
-		})
+		return err
 	}
 
-	transport := models.Transport{}
+	return c.Status(200).JSON(fiber.Map{
+		"data":  nearby,
+		"total": len(nearby),
+	})
+}
+
+func (h *transportHandler) getTransport(c *fiber.Ctx) error {
+	// Find the transport
+	id := c.Params("id")
 
-	err = coll.FindOne(c.Context(), bson.M{"_id": objectID}).Decode(&transport)
+	transport, err := h.repo.Get(c.UserContext(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("transport not found")
+	}
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return err
 	}
 
 	return c.Status(200).JSON(fiber.Map{"data": transport})
 }
 
 type TransportQuery struct {
-	Name        string   `json:"name" bson:"name"`
-	Logo        string   `json:"logo" bson:"logo"`
-	Phone       string   `json:"phone" bson:"phone"`
-	Sevices     []string `json:"services" bson:"services"`
-	Price       float64  `json:"price" bson:"price"`
-	MinQuantity int      `json:"minQuantity" bson:"minQuantity"`
-	Address     string   `json:"address" bson:"address"`
-	Available   bool     `json:"available" bson:"available"`
-	Rating      float64  `json:"rating" bson:"rating"`
+	Name          string      `json:"name" bson:"name" validate:"required,max=100"`
+	Logo          string      `json:"logo" bson:"logo" validate:"max=2048"`
+	Phone         string      `json:"phone" bson:"phone" validate:"required,phone"`
+	Sevices       []string    `json:"services" bson:"services" validate:"max=20"`
+	Price         money.Money `json:"price" bson:"price" validate:"required,money"`
+	MinQuantity   int         `json:"minQuantity" bson:"minQuantity" validate:"min=0"`
+	Capacity      int         `json:"capacity" bson:"capacity" validate:"min=0"`
+	DailyCapacity int         `json:"dailyCapacity" bson:"dailyCapacity" validate:"min=0"`
+	DailySlots    int         `json:"dailySlots" bson:"dailySlots" validate:"min=0"`
+	Address       string      `json:"address" bson:"address" validate:"required,max=500"`
+	Location      *geo.Point  `json:"location" bson:"location" validate:"point"`
+	ServiceAreas  []geo.Area  `json:"serviceAreas" bson:"serviceAreas" validate:"max=50,areas"`
+	Available     bool        `json:"available" bson:"available"`
+	Rating        float64     `json:"rating" bson:"-" validate:"readonly"`
 }
 
-func createTransport(c *fiber.Ctx) error {
-	// Validate the body
-	// t := new(models.Transport)
-	t := new(TransportQuery)
-	if err := c.BodyParser(t); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid body",
-		})
-	}
+func (h *transportHandler) createTransport(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	t := parsedBody[TransportQuery](c)
 
 	// Create the transport
-	coll := common.GetDBCollection("transports")
-	result, err := coll.InsertOne(c.Context(), t)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to create transport",
-			"message": err.Error(),
-		})
+	transport := &models.Transport{
+		Name:          t.Name,
+		Logo:          t.Logo,
+		Phone:         t.Phone,
+		Sevices:       t.Sevices,
+		Price:         t.Price,
+		MinQuantity:   t.MinQuantity,
+		Capacity:      t.Capacity,
+		DailyCapacity: t.DailyCapacity,
+		DailySlots:    t.DailySlots,
+		Address:       t.Address,
+		Location:      t.Location,
+		ServiceAreas:  t.ServiceAreas,
+		Available:     t.Available,
+		OwnerId:       auth.CurrentUser(c).UserID(),
+	}
+	if err := h.repo.Create(c.UserContext(), transport); err != nil {
+		return err
 	}
 
 	// Return the transport
-	return c.Status(201).JSON(fiber.Map{
-		"result": result,
-	})
+	c.Location("/transports/" + transport.ID)
+	return c.Status(201).JSON(fiber.Map{"data": transport})
 }
 
-func updateTransport(c *fiber.Ctx) error {
-	// Validate the body
-	t := new(models.Transport)
-	if err := c.BodyParser(t); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid body",
-		})
-	}
+func (h *transportHandler) updateTransport(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	t := parsedBody[models.TransportUpdate](c)
 
 	// Get the ID
 	id := c.Params("id")
-	if id == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "id is required",
-		})
-	}
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid id",
-		})
-	}
 
 	// Update the transport
-	coll := common.GetDBCollection("transports")
-	result, err := coll.UpdateOne(c.Context(), bson.M{"_id": objectID}, bson.M{"$set": t})
+	transport, err := h.repo.Update(c.UserContext(), id, t)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("transport not found")
+	}
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to update transport",
-			"message": err.Error(),
-		})
+		return err
 	}
 
 	// Return the transport
-	return c.Status(200).JSON(fiber.Map{
-		"result": result,
-	})
+	return c.Status(200).JSON(fiber.Map{"data": transport})
 }
 
-func deleteTransport(c *fiber.Ctx) error {
+func (h *transportHandler) deleteTransport(c *fiber.Ctx) error {
 	// Get the ID
 	id := c.Params("id")
-	if id == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "id is required",
-		})
+
+	// Make sure the transport exists and the user can delete it before touching its enquiries
+	transport, err := h.repo.Get(c.UserContext(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("transport not found")
 	}
-	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid id",
-		})
+		return err
+	}
+	if !auth.CanModify(c, transport.OwnerId) {
+		return apperror.Forbidden("you can only modify your own resources")
 	}
 
-	// Delete the transport
-	coll := common.GetDBCollection("transports")
-	result, err := coll.DeleteOne(c.Context(), bson.M{"_id": objectID})
+	// Deal with the enquiries using the transport
+	err = releaseEnquiries(c.UserContext(), h.enquiries, h.calendar, h.policy, "transportId", id)
+	if errors.Is(err, errHasOpenEnquiries) {
+		return apperror.Conflict("Cannot delete transport, " + err.Error())
+	}
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to delete transport",
-			"message": err.Error(),
-		})
+		return err
 	}
 
-	return c.Status(200).JSON(fiber.Map{
-		"result": result,
-	})
-}
+	// Delete the transport and its calendar
+	err = h.repo.Delete(c.UserContext(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("transport not found")
+	}
+	if err != nil {
+		return err
+	}
+	if err := h.calendar.DeleteTransport(c.UserContext(), id); err != nil {
+		return err
+	}
 
-func AddEnquiryGroup(app *fiber.App) {
-	enquiryGroup := app.Group("/enquiries")
+	return c.SendStatus(204)
+}
 
-	enquiryGroup.Get("/", getEnquiries)
-	enquiryGroup.Get("/:id", getEnquiry)
-	enquiryGroup.Post("/", createEnquiry)
-	enquiryGroup.Put("/:id", updateEnquiry)
-	enquiryGroup.Delete("/:id", deleteEnquiry)
+type enquiryHandler struct {
+	repo       repository.EnquiryRepository
+	products   repository.ProductRepository
+	transports repository.TransportRepository
+	calendar   repository.CalendarRepository
+	geocoder   geo.Geocoder
 }
 
-func getEnquiries(c *fiber.Ctx) error {
-	coll := common.GetDBCollection("enquiries")
+func AddEnquiryGroup(app *fiber.App, repo repository.EnquiryRepository, products repository.ProductRepository, transports repository.TransportRepository, calendar repository.CalendarRepository, geocoder geo.Geocoder, tokens *auth.Tokens) {
+	h := &enquiryHandler{repo: repo, products: products, transports: transports, calendar: calendar, geocoder: geocoder}
+	enquiryGroup := app.Group("/enquiries", auth.Protect(tokens))
+
+	buyers := auth.Protect(tokens, models.RoleBuyer, models.RoleAdmin)
+	transporters := auth.Protect(tokens, models.RoleTransporter, models.RoleAdmin)
+	owner := requireOwner(h.buyerOf)
+	parties := h.requireParty(models.PartyBuyer, models.PartyTransporter)
+
+	enquiryGroup.Get("/", h.getEnquiries)
+	enquiryGroup.Get("/match", validateQuery[matchQuery](), h.matchTransports)
+	enquiryGroup.Get("/:id", parties, h.getEnquiry)
+	enquiryGroup.Post("/", buyers, validateBody[EnquiryQuery](), h.createEnquiry)
+	enquiryGroup.Put("/:id", owner, validateBody[models.EnquiryUpdate](), h.updateEnquiry)
+	enquiryGroup.Delete("/:id", owner, h.deleteEnquiry)
+
+	// quotes, accepting one is how a quoted enquiry becomes accepted
+	enquiryGroup.Get("/:id/quotes", parties, h.getQuotes)
+	enquiryGroup.Get("/:id/quotes/:version", parties, h.getQuote)
+	enquiryGroup.Post("/:id/quote", transporters, validateBody[quoteDTO](), h.quoteEnquiry)
+	enquiryGroup.Post("/:id/accept", owner, validateBody[acceptDTO](), h.acceptQuote)
+
+	// rest of the lifecycle, e.g. POST /enquiries/:id/dispatch
+	for _, t := range models.EnquiryTransitions {
+		if t.Action == "quote" || t.Action == "accept" {
+			continue
+		}
+		enquiryGroup.Post("/:id/"+t.Action, h.requireParty(t.By...), validateBody[transitionDTO](), h.transitionEnquiry(t))
+	}
+}
 
-	// Find all enquiries
-	enquiries := make([]models.GenerateEnquiry, 0)
-	cursor, err := coll.Find(c.Context(), bson.M{})
+func (h *enquiryHandler) getEnquiries(c *fiber.Ctx) error {
+	// Find a page of enquiries
+	opts, err := parseListOptions(c, repository.EnquiryFields)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return apperror.BadRequest(err.Error())
 	}
 
-	// Iterate over the cursor
-	for cursor.Next(c.Context()) {
-		enquiry := models.GenerateEnquiry{}
-		err := cursor.Decode(&enquiry)
+	// Users only see the enquiries they are a party to, buyers the ones they made and transporters the ones for
+	// their transports
+	user := auth.CurrentUser(c)
+	switch user.Role {
+	case models.RoleAdmin:
+	case models.RoleBuyer:
+		opts.Filters = append(opts.Filters, repository.Filter{Field: "buyerId", Op: repository.Eq, Value: user.UserID()})
+	default:
+		transportIds, err := h.transportsOf(c.UserContext(), user.UserID())
 		if err != nil {
-			return c.Status(500).JSON(fiber.Map{
-				"error": err.Error(),
-			})
+			return err
 		}
-		enquiries = append(enquiries, enquiry)
+		opts.Filters = append(opts.Filters, repository.Filter{Field: "transportId", Op: repository.In, Value: transportIds})
 	}
 
-	return c.Status(200).JSON(fiber.Map{"data": enquiries})
-}
+	page, err := h.repo.List(c.UserContext(), opts)
+	if err != nil {
+		return err
+	}
 
-func getEnquiry(c *fiber.Ctx) error {
-	coll := common.GetDBCollection("enquiries")
+	return listResponse(c, page)
+}
 
+func (h *enquiryHandler) getEnquiry(c *fiber.Ctx) error {
 	// Find the enquiry
 	id := c.Params("id")
-	if id == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "id is required",
-		})
+
+	enquiry, err := h.repo.Get(c.UserContext(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("enquiry not found")
 	}
-	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid id",
-		})
+		return err
 	}
 
-	enquiry := models.GenerateEnquiry{}
+	return c.Status(200).JSON(fiber.Map{"data": enquiry})
+}
+
+// buyerOf returns the user that made the enquiry, only they can modify it
+func (h *enquiryHandler) buyerOf(ctx context.Context, id string) (string, error) {
+	enquiry, err := h.repo.Get(ctx, id)
+	return enquiry.BuyerId, err
+}
 
-	err = coll.FindOne(c.Context(), bson.M{"_id": objectID}).Decode(&enquiry)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+// requireParty only lets through admins and the users that are one of parties to the enquiry of :id
+func (h *enquiryHandler) requireParty(parties ...models.EnquiryParty) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := auth.CurrentUser(c)
+		if user.Role == models.RoleAdmin {
+			return c.Next()
+		}
+
+		enquiry, err := h.repo.Get(c.UserContext(), c.Params("id"))
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperror.NotFound("enquiry not found")
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, party := range parties {
+			switch party {
+			case models.PartyBuyer:
+				if enquiry.BuyerId == user.UserID() {
+					return c.Next()
+				}
+			case models.PartyTransporter:
+				// a deleted transport has no transporter left to act on the enquiry
+				transport, err := h.transports.Get(c.UserContext(), enquiry.TransportId)
+				if err != nil && !errors.Is(err, repository.ErrNotFound) {
+					return err
+				}
+				if err == nil && transport.OwnerId == user.UserID() {
+					return c.Next()
+				}
+			}
+		}
+
+		return apperror.Forbidden(fmt.Sprintf("only the %s of the enquiry can do this", joinParties(parties)))
 	}
+}
 
-	return c.Status(200).JSON(fiber.Map{"data": enquiry})
+func joinParties(parties []models.EnquiryParty) string {
+	names := make([]string, len(parties))
+	for i, p := range parties {
+		names[i] = string(p)
+	}
+	return strings.Join(names, " or ")
+}
+
+// transportsOf returns the ids of every transport owned by ownerId
+func (h *enquiryHandler) transportsOf(ctx context.Context, ownerId string) ([]interface{}, error) {
+	ids := make([]interface{}, 0)
+	opts := repository.ListOptions{
+		Filters: []repository.Filter{{Field: "ownerId", Op: repository.Eq, Value: ownerId}},
+		Limit:   repository.MaxLimit,
+	}
+	for {
+		page, err := h.transports.List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range page.Items {
+			ids = append(ids, t.ID)
+		}
+		if page.NextCursor == "" {
+			return ids, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
 }
 
 type EnquiryQuery struct {
-	TransportId     string `json:"transportId" bson:"transportId"`
-	ProductId       string `json:"productId" bson:"productId"`
-	Quantity        int    `json:"quantity" bson:"quantity"`
-	DeliveryAddress string `json:"deliveryAddress" bson:"deliveryAddress"`
-	DateOfDelivery  string `json:"dateOfDelivery" bson:"dateOfDelivery"`
-	Status          string `json:"status" bson:"status"`
+	TransportId      string     `json:"transportId" bson:"transportId" validate:"required,objectid"`
+	ProductId        string     `json:"productId" bson:"productId" validate:"required,objectid"`
+	Quantity         int        `json:"quantity" bson:"quantity" validate:"required,min=1"`
+	DeliveryAddress  string     `json:"deliveryAddress" bson:"deliveryAddress" validate:"required,max=500"`
+	DeliveryLocation *geo.Point `json:"deliveryLocation" bson:"deliveryLocation" validate:"point"`
+	DateOfDelivery   string     `json:"dateOfDelivery" bson:"dateOfDelivery" validate:"required,date"`
 }
 
-func createEnquiry(c *fiber.Ctx) error {
-	// Validate the body
-	// e := new(models.GenerateEnquiry)
-	e := new(EnquiryQuery)
-	if err := c.BodyParser(e); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid body",
-		})
-	}
+func (h *enquiryHandler) createEnquiry(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	e := parsedBody[EnquiryQuery](c)
 
 	// Create the enquiry
-	coll := common.GetDBCollection("enquiries")
-	result, err := coll.InsertOne(c.Context(), e)
+	user := auth.CurrentUser(c)
+	enquiry := &models.GenerateEnquiry{
+		BuyerId:         user.UserID(),
+		TransportId:     e.TransportId,
+		ProductId:       e.ProductId,
+		Quantity:        e.Quantity,
+		DeliveryAddress: e.DeliveryAddress,
+		DateOfDelivery:  e.DateOfDelivery,
+		Status:          models.StatusRequested,
+		StatusHistory: []models.StatusChange{
+			{To: models.StatusRequested, By: user.UserID(), At: time.Now().UTC().Truncate(time.Millisecond)},
+		},
+		Quotes: []models.Quote{},
+	}
+
+	// Find where the delivery goes
+	location, err := h.locate(c.UserContext(), e.DeliveryAddress, e.DeliveryLocation)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to create enquiry",
-			"message": err.Error(),
-		})
+		return err
 	}
+	enquiry.DeliveryLocation = location
 
-	// Return the enquiry
-	return c.Status(201).JSON(fiber.Map{
-		"result": result,
-	})
-}
+	// Check the product and transport
+	violations, err := checkEnquiryReferences(c.UserContext(), h.products, h.transports, h.calendar, enquiry)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return apperror.Validation("Enquiry violates integrity rules", violations)
+	}
+	if err := h.repo.Create(c.UserContext(), enquiry); err != nil {
+		return err
+	}
 
-type EnquiryQueryUpdate struct {
-	TransportId     string `json:"transportId,omitempty" bson:"transportId,omitempty"`
-	ProductId       string `json:"productId,omitempty" bson:"productId,omitempty"`
-	Quantity        int    `json:"quantity,omitempty" bson:"quantity,omitempty"`
-	DeliveryAddress string `json:"deliveryAddress,omitempty" bson:"deliveryAddress,omitempty"`
-	DateOfDelivery  string `json:"dateOfDelivery,omitempty" bson:"dateOfDelivery,omitempty"`
-	Status          string `json:"status,omitempty" bson:"status,omitempty"`
+	// Return the enquiry
+	c.Location("/enquiries/" + enquiry.ID)
+	return c.Status(201).JSON(fiber.Map{"data": enquiry})
 }
 
-func updateEnquiry(c *fiber.Ctx) error {
-	// Validate the body
-	e := new(EnquiryQueryUpdate)
-	if err := c.BodyParser(e); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid body",
-		})
-	}
+func (h *enquiryHandler) updateEnquiry(c *fiber.Ctx) error {
+	// Body checked by validateBody
+	e := parsedBody[models.EnquiryUpdate](c)
 
 	// Get the ID
 	id := c.Params("id")
-	if id == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "id is required",
-		})
+
+	// Closed enquiries don't change, and the booking of an accepted enquiry is made for its transport, quantity and date
+	booking := e.TransportId != "" || e.Quantity != 0 || e.DateOfDelivery != ""
+	statuses := models.OpenStatuses
+	if booking {
+		statuses = models.UnbookedStatuses
 	}
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid id",
-		})
+
+	// Check the product and transport again when the update touches them
+	if booking || e.ProductId != "" || e.DeliveryAddress != "" || e.DeliveryLocation != nil {
+		enquiry, err := h.repo.Get(c.UserContext(), id)
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperror.NotFound("enquiry not found")
+		}
+		if err != nil {
+			return err
+		}
+		if !allowsStatus(statuses, enquiry.Status) {
+			return updateConflict(enquiry, booking)
+		}
+
+		// A new address needs a new location, unless one was sent with it
+		if e.DeliveryAddress != "" && e.DeliveryLocation == nil {
+			location, err := h.locate(c.UserContext(), e.DeliveryAddress, nil)
+			if err != nil {
+				return err
+			}
+			if location == nil && enquiry.DeliveryLocation != nil {
+				return apperror.Validation("Validation failed", []validation.Violation{
+					{Field: "deliveryLocation", Rule: "required", Message: "is required, the new delivery address could not be located"},
+				})
+			}
+			e.DeliveryLocation = location
+		}
+		if e.DeliveryAddress != "" {
+			enquiry.DeliveryAddress = e.DeliveryAddress
+		}
+		if e.DeliveryLocation != nil {
+			enquiry.DeliveryLocation = e.DeliveryLocation
+		}
+
+		if e.TransportId != "" {
+			enquiry.TransportId = e.TransportId
+		}
+		if e.ProductId != "" {
+			enquiry.ProductId = e.ProductId
+		}
+		if e.Quantity != 0 {
+			enquiry.Quantity = e.Quantity
+		}
+		if e.DateOfDelivery != "" {
+			enquiry.DateOfDelivery = e.DateOfDelivery
+		}
+		violations, err := checkEnquiryReferences(c.UserContext(), h.products, h.transports, h.calendar, &enquiry)
+		if err != nil {
+			return err
+		}
+		if len(violations) > 0 {
+			return apperror.Validation("Enquiry violates integrity rules", violations)
+		}
 	}
 
-	// Update the enquiry
-	coll := common.GetDBCollection("enquiries")
-	result, err := coll.UpdateOne(c.Context(), bson.M{"_id": objectID}, bson.M{"$set": e})
+	// Update the enquiry, only while its status still allows it
+	enquiry, err := h.repo.UpdateWhile(c.UserContext(), id, statuses, e)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("enquiry not found")
+	}
+	if errors.Is(err, repository.ErrStatusConflict) {
+		// the status changed since it was checked
+		enquiry, err = h.repo.Get(c.UserContext(), id)
+		if err != nil {
+			return err
+		}
+		return updateConflict(enquiry, booking)
+	}
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to update enquiry",
-			"message": err.Error(),
-		})
+		return err
 	}
 
 	// Return the enquiry
-	return c.Status(200).JSON(fiber.Map{
-		"result": result,
-	})
+	return c.Status(200).JSON(fiber.Map{"data": enquiry})
 }
 
-func deleteEnquiry(c *fiber.Ctx) error {
-	// Get the ID
+// updateConflict explains why enquiry can't be updated in its status
+func updateConflict(enquiry models.GenerateEnquiry, booking bool) error {
+	if booking && enquiry.Booked() && enquiry.Status != models.StatusDelivered {
+		return apperror.Conflict(fmt.Sprintf("cannot change the transport, quantity or date of an enquiry that is %s, cancel it instead", enquiry.Status))
+	}
+	return apperror.Conflict(fmt.Sprintf("cannot change an enquiry that is %s", enquiry.Status))
+}
+
+func allowsStatus(statuses []models.EnquiryStatus, status models.EnquiryStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// locate returns sent when the buyer gave a location, otherwise it looks up address and returns nil when it is unknown
+func (h *enquiryHandler) locate(ctx context.Context, address string, sent *geo.Point) (*geo.Point, error) {
+	if sent != nil {
+		return sent, nil
+	}
+
+	location, err := h.geocoder.Geocode(ctx, address)
+	if errors.Is(err, geo.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (h *enquiryHandler) deleteEnquiry(c *fiber.Ctx) error {
+	// Find the enquiry
 	id := c.Params("id")
-	if id == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "id is required",
-		})
+	enquiry, err := h.repo.Get(c.UserContext(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("enquiry not found")
 	}
-	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid id",
-		})
+		return err
 	}
 
-	// Delete the enquiry
-	coll := common.GetDBCollection("enquiries")
-	result, err := coll.DeleteOne(c.Context(), bson.M{"_id": objectID})
+	// Delete the enquiry and give back its booking
+	err = h.repo.Delete(c.UserContext(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound("enquiry not found")
+	}
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to delete enquiry",
-			"message": err.Error(),
-		})
+		return err
+	}
+	if enquiry.Booked() {
+		if err := h.calendar.Release(c.UserContext(), enquiry.TransportId, enquiry.DateOfDelivery, id); err != nil {
+			return err
+		}
 	}
 
-	return c.Status(200).JSON(fiber.Map{
-		"result": result,
-	})
+	return c.SendStatus(204)
+}
+
+type transitionDTO struct {
+	Note string `json:"note" validate:"max=1000"`
+}
+
+// transitionEnquiry moves an enquiry along the lifecycle, refusing with 409 when t is not allowed from its current status
+func (h *enquiryHandler) transitionEnquiry(t models.EnquiryTransition) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Body checked by validateBody, it is optional
+		body := parsedBody[transitionDTO](c)
+
+		// Find the enquiry
+		id := c.Params("id")
+		enquiry, err := h.repo.Get(c.UserContext(), id)
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperror.NotFound("enquiry not found")
+		}
+		if err != nil {
+			return err
+		}
+
+		if !t.Allows(enquiry.Status) {
+			return apperror.Conflict(fmt.Sprintf("cannot %s an enquiry that is %s", t.Action, enquiry.Status))
+		}
+
+		// Move the enquiry to its new status
+		change := models.StatusChange{
+			From: enquiry.Status,
+			To:   t.To,
+			By:   auth.CurrentUser(c).UserID(),
+			At:   time.Now().UTC().Truncate(time.Millisecond),
+			Note: body.Note,
+		}
+		err = h.repo.Transition(c.UserContext(), id, change)
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperror.NotFound("enquiry not found")
+		}
+		if errors.Is(err, repository.ErrStatusConflict) {
+			return apperror.Conflict(err.Error())
+		}
+		if err != nil {
+			return err
+		}
+
+		// A cancelled enquiry gives back its booking
+		if enquiry.Booked() && t.To == models.StatusCancelled {
+			if err := h.calendar.Release(c.UserContext(), enquiry.TransportId, enquiry.DateOfDelivery, id); err != nil {
+				return err
+			}
+		}
+
+		enquiry.Status = t.To
+		enquiry.StatusHistory = append(enquiry.StatusHistory, change)
+
+		return c.Status(200).JSON(fiber.Map{"data": enquiry})
+	}
 }
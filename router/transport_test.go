@@ -0,0 +1,169 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+)
+
+func transportBody(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        name,
+		"phone":       "+91 9999999999",
+		"address":     "Pune",
+		"available":   true,
+		"minQuantity": 10,
+		"capacity":    500,
+		"price":       map[string]interface{}{"amount": 200, "currency": "INR"},
+	}
+}
+
+func TestCreateTransport(t *testing.T) {
+	a := newTestApp(t)
+	ownerId, transporter := a.user("t@x.io", models.RoleTransporter)
+	_, buyer := a.user("b@x.io", models.RoleBuyer)
+	_, admin := a.user("a@x.io", models.RoleAdmin)
+
+	tests := []struct {
+		name   string
+		token  string
+		body   map[string]interface{}
+		status int
+	}{
+		{"transporter", transporter, transportBody("truck"), 201},
+		{"admin", admin, transportBody("van"), 201},
+		{"buyer", buyer, transportBody("lorry"), 403},
+		{"anonymous", "", transportBody("lorry"), 401},
+		{"invalid body", transporter, map[string]interface{}{"name": "lorry"}, 422},
+		{"no price", transporter, withoutPrice(transportBody("lorry")), 422},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := a.do("POST", "/transports", tt.token, tt.body, nil); status != tt.status {
+				t.Errorf("got %d, want %d", status, tt.status)
+			}
+		})
+	}
+
+	var got data[models.Transport]
+	a.do("POST", "/transports", transporter, transportBody("bus"), &got)
+	if got.Data.OwnerId != ownerId {
+		t.Errorf("owner is %q, want %q", got.Data.OwnerId, ownerId)
+	}
+}
+
+func withoutPrice(body map[string]interface{}) map[string]interface{} {
+	delete(body, "price")
+	return body
+}
+
+func TestUpdateTransport(t *testing.T) {
+	a := newTestApp(t)
+	_, transporter := a.user("t@x.io", models.RoleTransporter)
+	_, other := a.user("o@x.io", models.RoleTransporter)
+	var created data[models.Transport]
+	a.do("POST", "/transports", transporter, transportBody("truck"), &created)
+	path := "/transports/" + created.Data.ID
+
+	if status := a.do("PUT", path, other, map[string]interface{}{"name": "mine"}, nil); status != 403 {
+		t.Errorf("update by another transporter: got %d, want 403", status)
+	}
+
+	// 0 and false are values to set, not missing fields
+	var updated data[models.Transport]
+	status := a.do("PUT", path, transporter, map[string]interface{}{"minQuantity": 0, "available": false}, &updated)
+	if status != 200 {
+		t.Fatalf("got %d, want 200", status)
+	}
+	if updated.Data.MinQuantity != 0 || updated.Data.Available || updated.Data.Capacity != 500 || updated.Data.Name != "truck" {
+		t.Errorf("got %+v", updated.Data)
+	}
+
+	var stored data[models.Transport]
+	a.do("GET", path, "", nil, &stored)
+	if stored.Data.MinQuantity != 0 || stored.Data.Available {
+		t.Errorf("stored %+v", stored.Data)
+	}
+}
+
+func TestListTransports(t *testing.T) {
+	a := newTestApp(t)
+	_, transporter := a.user("t@x.io", models.RoleTransporter)
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		body := transportBody(name)
+		body["capacity"] = 100 * (i%2 + 1)
+		body["available"] = i != 2
+		a.do("POST", "/transports", transporter, body, nil)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		want   []string
+	}{
+		{"sorted", "?sort=-capacity,name", 200, []string{"b", "d", "a", "c", "e"}},
+		{"filtered", "?available=false", 200, []string{"c"}},
+		{"range", "?capacity[gte]=200&sort=name", 200, []string{"b", "d"}},
+		{"in", "?name[in]=a,e&sort=name", 200, []string{"a", "e"}},
+		{"unknown field", "?colour=red", 400, nil},
+		{"unknown operator", "?capacity[like]=1", 400, nil},
+		{"bad number", "?capacity[gt]=many", 400, nil},
+		{"unknown sort", "?sort=colour", 400, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var page list[models.Transport]
+			status := a.do("GET", "/transports"+tt.query, "", nil, &page)
+			if status != tt.status {
+				t.Fatalf("got %d, want %d", status, tt.status)
+			}
+			if tt.want == nil {
+				return
+			}
+			if got := namesOf(page.Data); !equalStrings(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// follow the cursors two at a time
+	got := make([]string, 0)
+	path := "/transports?sort=-capacity,name&limit=2"
+	for {
+		var page list[models.Transport]
+		if status := a.do("GET", path, "", nil, &page); status != 200 {
+			t.Fatalf("got %d, want 200", status)
+		}
+		got = append(got, namesOf(page.Data)...)
+		if page.NextCursor == nil {
+			break
+		}
+		path = "/transports?sort=-capacity,name&limit=2&cursor=" + *page.NextCursor
+	}
+	if want := []string{"b", "d", "a", "c", "e"}; !equalStrings(got, want) {
+		t.Errorf("paged %v, want %v", got, want)
+	}
+}
+
+func namesOf(transports []models.Transport) []string {
+	names := make([]string, len(transports))
+	for i, t := range transports {
+		names[i] = t.Name
+	}
+	return names
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
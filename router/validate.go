@@ -0,0 +1,85 @@
+package router
+
+import (
+	"mime/multipart"
+	"reflect"
+	"strings"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	bodyKey  = "body"
+	queryKey = "query"
+)
+
+var fileHeaderType = reflect.TypeOf(&multipart.FileHeader{})
+
+// validateBody parses the request body into a new T and checks its validate tags before the handler runs,
+// the handler reads the result with parsedBody
+func validateBody[T any]() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		b := new(T)
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(b); err != nil {
+				return apperror.BadRequest("Invalid body")
+			}
+			bindFiles(c, b)
+		}
+
+		if violations := validation.Validate(b); len(violations) > 0 {
+			return apperror.Validation("Validation failed", violations)
+		}
+
+		c.Locals(bodyKey, b)
+		return c.Next()
+	}
+}
+
+// parsedBody returns the body validated by validateBody[T]
+func parsedBody[T any](c *fiber.Ctx) *T {
+	return c.Locals(bodyKey).(*T)
+}
+
+// validateQuery is validateBody for the query string, the handler reads the result with parsedQuery
+func validateQuery[T any]() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		q := new(T)
+		if err := c.QueryParser(q); err != nil {
+			return apperror.BadRequest("Invalid query")
+		}
+
+		if violations := validation.Validate(q); len(violations) > 0 {
+			return apperror.Validation("Validation failed", violations)
+		}
+
+		c.Locals(queryKey, q)
+		return c.Next()
+	}
+}
+
+// parsedQuery returns the query validated by validateQuery[T]
+func parsedQuery[T any](c *fiber.Ctx) *T {
+	return c.Locals(queryKey).(*T)
+}
+
+// bindFiles fills the *multipart.FileHeader fields of b from the uploaded files, BodyParser only reads values
+func bindFiles(c *fiber.Ctx, b interface{}) {
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		return
+	}
+
+	val := reflect.ValueOf(b).Elem()
+	for i := 0; i < val.NumField(); i++ {
+		sf := val.Type().Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("form"), ",")
+		if sf.Type != fileHeaderType || name == "" {
+			continue
+		}
+		if file, err := c.FormFile(name); err == nil {
+			val.Field(i).Set(reflect.ValueOf(file))
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package search
+
+import (
+	"html"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Scores of a query term against a word, before the weight of the field
+const (
+	exactScore  = 1.0
+	prefixScore = 0.8
+	typoScore   = 0.6
+)
+
+// Terms splits a query into lowercase words, keeping the first MaxTerms
+func Terms(text string) []string {
+	terms := make([]string, 0)
+	for _, w := range wordsOf(text) {
+		terms = append(terms, strings.ToLower(text[w.start:w.end]))
+		if len(terms) == MaxTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// Match scores doc against terms, every term has to match a word of a field:
+//   - exactly
+//   - as the start of the word, so "tom" finds "tomato"
+//   - with a typo, one edit for terms of 4 to 7 letters and two from 8 letters, either against the whole word
+//     or its start, so "tomatoe" and "tamat" both find "tomatoes"
+//
+// The best match of each term is multiplied by the weight of its field and the results are added up.
+// Highlights holds every field with a matched word.
+func Match(doc Document, terms []string) (float64, map[string]string, bool) {
+	highlights := map[string]string{}
+	if len(terms) == 0 {
+		return 0, highlights, false
+	}
+
+	matched := make([]map[int]bool, len(doc.Fields))
+	total := 0.0
+	for _, term := range terms {
+		best := 0.0
+		for i, f := range doc.Fields {
+			for j, w := range wordsOf(f.Text) {
+				s := matchWord(term, strings.ToLower(f.Text[w.start:w.end]))
+				if s == 0 {
+					continue
+				}
+				if matched[i] == nil {
+					matched[i] = map[int]bool{}
+				}
+				matched[i][j] = true
+				best = math.Max(best, s*f.Weight)
+			}
+		}
+		if best == 0 {
+			return 0, highlights, false
+		}
+		total += best
+	}
+
+	for i, f := range doc.Fields {
+		if len(matched[i]) > 0 {
+			highlights[f.Name] = highlight(f.Text, matched[i])
+		}
+	}
+	return total, highlights, true
+}
+
+func matchWord(term string, word string) float64 {
+	if term == word {
+		return exactScore
+	}
+	t, w := []rune(term), []rune(word)
+	if len(t) >= 2 && strings.HasPrefix(word, term) {
+		return prefixScore
+	}
+
+	edits := maxEdits(len(t))
+	if edits == 0 {
+		return 0
+	}
+	if d := distance(t, w); d <= edits {
+		return typoScore - 0.1*float64(d-1)
+	}
+	if len(w) > len(t) {
+		if d := distance(t, w[:len(t)]); d <= edits {
+			return typoScore - 0.1*float64(d)
+		}
+	}
+	return 0
+}
+
+func maxEdits(length int) int {
+	switch {
+	case length >= 8:
+		return 2
+	case length >= 4:
+		return 1
+	}
+	return 0
+}
+
+// distance is the Levenshtein distance between a and b
+func distance(a []rune, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+type span struct {
+	start int
+	end   int
+}
+
+// wordsOf returns where the words of s are, a word is a run of letters and digits
+func wordsOf(s string) []span {
+	words := make([]span, 0)
+	start := -1
+	for i, r := range s {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			words = append(words, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, span{start, len(s)})
+	}
+	return words
+}
+
+// highlight wraps the matched words of text in <em>, the rest of text is escaped so it is safe to show as HTML
+func highlight(text string, matched map[int]bool) string {
+	var b strings.Builder
+	last := 0
+	for i, w := range wordsOf(text) {
+		if !matched[i] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[last:w.start]))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(text[w.start:w.end]))
+		b.WriteString("</em>")
+		last = w.end
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+// round keeps 4 decimals so scores are readable
+func round(f float64) float64 {
+	return math.Round(f*10000) / 10000
+}
@@ -0,0 +1,139 @@
+package search
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+)
+
+// Memory is an in-memory index, documents have to be given to it with Index, e.g. by wrapping the repositories
+// with IndexProducts and IndexTransports
+type Memory struct {
+	mu   sync.RWMutex
+	docs map[Kind]map[string]Document
+}
+
+func NewMemory() *Memory {
+	return &Memory{docs: map[Kind]map[string]Document{}}
+}
+
+func (m *Memory) Index(ctx context.Context, doc Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.docs[doc.Kind] == nil {
+		m.docs[doc.Kind] = map[string]Document{}
+	}
+	m.docs[doc.Kind][doc.ID] = doc
+	return nil
+}
+
+func (m *Memory) Remove(ctx context.Context, kind Kind, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.docs[kind], id)
+	return nil
+}
+
+// Search scores every document of the index
+func (m *Memory) Search(ctx context.Context, q Query) (Result, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	candidates := make([]candidate, 0)
+	for _, docs := range m.docs {
+		for _, doc := range docs {
+			candidates = append(candidates, candidate{doc: doc})
+		}
+	}
+	return collect(q, candidates), nil
+}
+
+// IndexProducts keeps index up to date with the products created, updated and deleted through repo
+func IndexProducts(repo repository.ProductRepository, index Indexer) repository.ProductRepository {
+	return &indexedProducts{ProductRepository: repo, index: index}
+}
+
+type indexedProducts struct {
+	repository.ProductRepository
+	index Indexer
+}
+
+func (r *indexedProducts) Create(ctx context.Context, product *models.Product) error {
+	if err := r.ProductRepository.Create(ctx, product); err != nil {
+		return err
+	}
+	return r.index.Index(ctx, ProductDocument(*product))
+}
+
+func (r *indexedProducts) Update(ctx context.Context, id string, update *models.UpdatePTO) (models.Product, error) {
+	product, err := r.ProductRepository.Update(ctx, id, update)
+	if err != nil {
+		return product, err
+	}
+	return product, r.index.Index(ctx, ProductDocument(product))
+}
+
+func (r *indexedProducts) SetRating(ctx context.Context, id string, summary models.RatingSummary) error {
+	if err := r.ProductRepository.SetRating(ctx, id, summary); err != nil {
+		return err
+	}
+	product, err := r.ProductRepository.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return r.index.Index(ctx, ProductDocument(product))
+}
+
+func (r *indexedProducts) Delete(ctx context.Context, id string) error {
+	if err := r.ProductRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	return r.index.Remove(ctx, KindProduct, id)
+}
+
+// IndexTransports keeps index up to date with the transports created, updated and deleted through repo
+func IndexTransports(repo repository.TransportRepository, index Indexer) repository.TransportRepository {
+	return &indexedTransports{TransportRepository: repo, index: index}
+}
+
+type indexedTransports struct {
+	repository.TransportRepository
+	index Indexer
+}
+
+func (r *indexedTransports) Create(ctx context.Context, transport *models.Transport) error {
+	if err := r.TransportRepository.Create(ctx, transport); err != nil {
+		return err
+	}
+	return r.index.Index(ctx, TransportDocument(*transport))
+}
+
+func (r *indexedTransports) Update(ctx context.Context, id string, update *models.TransportUpdate) (models.Transport, error) {
+	transport, err := r.TransportRepository.Update(ctx, id, update)
+	if err != nil {
+		return transport, err
+	}
+	return transport, r.index.Index(ctx, TransportDocument(transport))
+}
+
+func (r *indexedTransports) SetRating(ctx context.Context, id string, summary models.RatingSummary) error {
+	if err := r.TransportRepository.SetRating(ctx, id, summary); err != nil {
+		return err
+	}
+	transport, err := r.TransportRepository.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return r.index.Index(ctx, TransportDocument(transport))
+}
+
+func (r *indexedTransports) Delete(ctx context.Context, id string) error {
+	if err := r.TransportRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	return r.index.Remove(ctx, KindTransport, id)
+}
@@ -0,0 +1,171 @@
+package search
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/requestid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// candidateLimit is how many documents of a collection each MongoDB query brings back to be scored beyond the
+// requested page
+const candidateLimit = 200
+
+// prefixLength is how much of a term has to be typed right for MongoDB to find a document with a typo in it
+const prefixLength = 3
+
+// Mongo searches the products and transports collections with the text indexes of migration 2.
+// The text search finds whole and stemmed words and scores them, names starting with the first letters of a term are
+// fetched as well so Match can find prefixes and typos. Hits are scored by Match plus the text score of MongoDB.
+// Only the best candidates of each collection are scored, when there are more the result is Partial.
+type Mongo struct {
+	products   *mongo.Collection
+	transports *mongo.Collection
+}
+
+func NewMongo(db *mongo.Database) *Mongo {
+	return &Mongo{products: db.Collection("products"), transports: db.Collection("transports")}
+}
+
+// collection is how a kind of document is stored, facets maps the facets of the kind to their fields
+type collection struct {
+	kind   Kind
+	coll   *mongo.Collection
+	facets map[string]string
+	decode func(bson.Raw) (Document, error)
+}
+
+func (m *Mongo) Search(ctx context.Context, q Query) (Result, error) {
+	terms := Terms(q.Text)
+	if len(terms) == 0 {
+		return collect(q, nil), nil
+	}
+
+	collections := []collection{
+		{KindProduct, m.products, map[string]string{FacetSeller: "sellerId"}, func(raw bson.Raw) (Document, error) {
+			var p models.Product
+			err := bson.Unmarshal(raw, &p)
+			return ProductDocument(p), err
+		}},
+		{KindTransport, m.transports, map[string]string{FacetServices: "services"}, func(raw bson.Raw) (Document, error) {
+			var t models.Transport
+			err := bson.Unmarshal(raw, &t)
+			return TransportDocument(t), err
+		}},
+	}
+
+	// enough candidates for the page and the ones after it
+	limit := int64(q.Offset + q.Limit + candidateLimit)
+	candidates := make([]candidate, 0)
+	partial := false
+	for _, c := range collections {
+		filter, ok := facetFilter(c, q)
+		if !ok || !searches(q, c.kind) {
+			continue
+		}
+		found, full, err := find(ctx, c, q.Text, terms, filter, limit)
+		if err != nil {
+			return Result{}, err
+		}
+		candidates = append(candidates, found...)
+		partial = partial || full
+	}
+
+	result := collect(q, candidates)
+	result.Partial = partial
+	return result, nil
+}
+
+// facetFilter matches the facet values of q, it isn't ok when q filters on a facet the kind doesn't have
+func facetFilter(c collection, q Query) (bson.M, bool) {
+	filter := bson.M{}
+	for name, value := range q.Facets {
+		field, ok := c.facets[name]
+		if !ok {
+			return nil, false
+		}
+		// facets match regardless of case, like wanted does
+		filter[field] = bson.M{"$regex": "^" + regexp.QuoteMeta(value) + "$", "$options": "i"}
+	}
+	return filter, true
+}
+
+// find runs the text search and the prefix search on the collection, documents found by both keep their text score.
+// full reports whether one of them brought back limit documents, so there can be more.
+func find(ctx context.Context, c collection, text string, terms []string, filter bson.M, limit int64) ([]candidate, bool, error) {
+	found := map[string]candidate{}
+	full := false
+	add := func(cursor *mongo.Cursor, scored bool) error {
+		n := int64(0)
+		for cursor.Next(ctx) {
+			doc, err := c.decode(cursor.Current)
+			if err != nil {
+				return err
+			}
+			cand := found[doc.ID]
+			cand.doc = doc
+			if scored {
+				cand.bonus, _ = cursor.Current.Lookup("score").DoubleOK()
+			}
+			found[doc.ID] = cand
+			n++
+		}
+		full = full || n == limit
+		return cursor.Err()
+	}
+
+	// whole and stemmed words, scored by MongoDB
+	textFilter := bson.M{"$text": bson.M{"$search": text}}
+	for field, cond := range filter {
+		textFilter[field] = cond
+	}
+	textOptions := options.Find().
+		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetLimit(limit).
+		SetComment(requestid.From(ctx))
+	cursor, err := c.coll.Find(ctx, textFilter, textOptions)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close(ctx)
+	if err := add(cursor, true); err != nil {
+		return nil, false, err
+	}
+
+	// names starting like one of the terms, the anchored and case sensitive patterns can use the name index
+	prefixes := bson.A{}
+	for _, term := range terms {
+		prefix := []rune(term)
+		if len(prefix) > prefixLength {
+			prefix = prefix[:prefixLength]
+		}
+		lower := string(prefix)
+		title := strings.ToUpper(string(prefix[:1])) + string(prefix[1:])
+		prefixes = append(prefixes, primitive.Regex{Pattern: "^" + regexp.QuoteMeta(lower)}, primitive.Regex{Pattern: "^" + regexp.QuoteMeta(title)})
+	}
+	prefixFilter := bson.M{"name": bson.M{"$in": prefixes}}
+	for field, cond := range filter {
+		prefixFilter[field] = cond
+	}
+	cursor, err = c.coll.Find(ctx, prefixFilter, options.Find().SetLimit(limit).SetComment(requestid.From(ctx)))
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close(ctx)
+	if err := add(cursor, false); err != nil {
+		return nil, false, err
+	}
+
+	candidates := make([]candidate, 0, len(found))
+	for _, cand := range found {
+		candidates = append(candidates, cand)
+	}
+	return candidates, full, nil
+}
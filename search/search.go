@@ -0,0 +1,223 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+)
+
+// Kind is the type of resource a document was made from
+type Kind string
+
+const (
+	KindProduct   Kind = "product"
+	KindTransport Kind = "transport"
+)
+
+// Weights of the searched fields, a match in a name counts more than one in a description
+const (
+	NameWeight        = 3
+	ServicesWeight    = 2
+	DescriptionWeight = 1
+	AddressWeight     = 1
+)
+
+// Facet names, they are also the query filters of the facet
+const (
+	FacetSeller   = "sellerId"
+	FacetServices = "services"
+)
+
+// MaxTerms is the number of words of a query that are searched, the rest is ignored
+const MaxTerms = 10
+
+// Query is a search, Kinds limits it to some kinds of documents and Facets to documents with the given facet values
+type Query struct {
+	Text   string
+	Kinds  []Kind
+	Facets map[string]string
+	Limit  int
+	Offset int
+}
+
+// Hit is a document that matched, Highlights holds the matched fields with the matched words wrapped in <em>
+type Hit struct {
+	Kind       Kind              `json:"kind"`
+	ID         string            `json:"id"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+	Document   interface{}       `json:"document"`
+}
+
+// FacetCount is how many matching documents have a facet value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Result is one page of hits, best first, Total and Facets count every hit. When Partial is set the backend only
+// scored its best candidates, there may be more hits than Total and the pages after it may end early.
+type Result struct {
+	Hits    []Hit                   `json:"hits"`
+	Total   int                     `json:"total"`
+	Facets  map[string][]FacetCount `json:"facets"`
+	Partial bool                    `json:"partial"`
+}
+
+// Searcher finds products and transports
+type Searcher interface {
+	Search(ctx context.Context, q Query) (Result, error)
+}
+
+// Indexer is implemented by backends that keep their own copy of the documents
+type Indexer interface {
+	Index(ctx context.Context, doc Document) error
+	Remove(ctx context.Context, kind Kind, id string) error
+}
+
+// Field is a searched text of a document
+type Field struct {
+	Name   string
+	Text   string
+	Weight float64
+}
+
+// Document is what gets searched, Source is the resource it was made from and is returned with the hits
+type Document struct {
+	Kind   Kind
+	ID     string
+	Fields []Field
+	Facets map[string][]string
+	Source interface{}
+}
+
+func ProductDocument(p models.Product) Document {
+	return Document{
+		Kind: KindProduct,
+		ID:   p.ID,
+		Fields: []Field{
+			{Name: "name", Text: p.Name, Weight: NameWeight},
+			{Name: "description", Text: p.Description, Weight: DescriptionWeight},
+		},
+		Facets: map[string][]string{FacetSeller: {p.SellerId}},
+		Source: p,
+	}
+}
+
+func TransportDocument(t models.Transport) Document {
+	return Document{
+		Kind: KindTransport,
+		ID:   t.ID,
+		Fields: []Field{
+			{Name: "name", Text: t.Name, Weight: NameWeight},
+			{Name: "services", Text: strings.Join(t.Sevices, ", "), Weight: ServicesWeight},
+			{Name: "address", Text: t.Address, Weight: AddressWeight},
+		},
+		Facets: map[string][]string{FacetServices: t.Sevices},
+		Source: t,
+	}
+}
+
+// candidate is a document a backend found, bonus is added to its score, e.g. the text score of MongoDB
+type candidate struct {
+	doc   Document
+	bonus float64
+}
+
+// collect scores the candidates against q and returns the requested page, candidates only found by the backend
+// (bonus above 0) are kept even when the words don't match, like a stemmed match from MongoDB
+func collect(q Query, candidates []candidate) Result {
+	terms := Terms(q.Text)
+	hits := make([]Hit, 0)
+	facets := map[string]map[string]int{FacetSeller: {}, FacetServices: {}}
+
+	for _, c := range candidates {
+		if !wanted(c.doc, q) {
+			continue
+		}
+		score, highlights, ok := Match(c.doc, terms)
+		if !ok && c.bonus <= 0 {
+			continue
+		}
+
+		hits = append(hits, Hit{Kind: c.doc.Kind, ID: c.doc.ID, Score: round(score + c.bonus), Highlights: highlights, Document: c.doc.Source})
+		for name, values := range c.doc.Facets {
+			for _, v := range values {
+				if v != "" {
+					facets[name][v]++
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	result := Result{Total: len(hits), Facets: map[string][]FacetCount{}}
+	for name, counts := range facets {
+		result.Facets[name] = sortedCounts(counts)
+	}
+
+	if q.Offset > len(hits) {
+		q.Offset = len(hits)
+	}
+	hits = hits[q.Offset:]
+	if q.Limit > 0 && len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+	result.Hits = hits
+
+	return result
+}
+
+// wanted reports whether doc is of one of the kinds and has the facet values of q
+func wanted(doc Document, q Query) bool {
+	if !searches(q, doc.Kind) {
+		return false
+	}
+
+	for name, value := range q.Facets {
+		found := false
+		for _, v := range doc.Facets[name] {
+			found = found || strings.EqualFold(v, value)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// searches reports whether q looks for documents of kind
+func searches(q Query, kind Kind) bool {
+	if len(q.Kinds) == 0 {
+		return true
+	}
+	for _, k := range q.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedCounts puts the most frequent values first
+func sortedCounts(counts map[string]int) []FacetCount {
+	sorted := make([]FacetCount, 0, len(counts))
+	for value, count := range counts {
+		sorted = append(sorted, FacetCount{Value: value, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Value < sorted[j].Value
+	})
+	return sorted
+}
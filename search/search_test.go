@@ -0,0 +1,234 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// index holds a few products and transports
+func index(t *testing.T) *Memory {
+	t.Helper()
+	m := NewMemory()
+	docs := []Document{
+		ProductDocument(models.Product{ID: "p1", Name: "Tomatoes", Description: "fresh from the farm", SellerId: "s1"}),
+		ProductDocument(models.Product{ID: "p2", Name: "Potatoes", Description: "good with tomatoes", SellerId: "s1"}),
+		ProductDocument(models.Product{ID: "p3", Name: "Tomato ketchup", Description: "sweet", SellerId: "s2"}),
+		ProductDocument(models.Product{ID: "p4", Name: "Onions", Description: "red <b>onions</b>", SellerId: "s2"}),
+		TransportDocument(models.Transport{ID: "t1", Name: "Fresh Freight", Sevices: []string{"cold storage", "express"}, Address: "Pune"}),
+		TransportDocument(models.Transport{ID: "t2", Name: "Ravi Trucks", Sevices: []string{"express"}, Address: "Tomato market, Nashik"}),
+	}
+	for _, doc := range docs {
+		if err := m.Index(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func ids(hits []Hit) []string {
+	got := make([]string, len(hits))
+	for i, h := range hits {
+		got[i] = h.ID
+	}
+	return got
+}
+
+func TestSearchRanking(t *testing.T) {
+	m := index(t)
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		// an exact word beats a prefix in the same field, and names count the most
+		{"exact before prefix", "tomato", []string{"p3", "p1", "t2", "p2"}},
+		// the typo of Potatoes in a name scores as much as Tomato, ties go by id
+		{"typos in names", "tomatoes", []string{"p1", "p2", "p3", "t2"}},
+		{"prefix", "tom", []string{"p1", "p3", "p2", "t2"}},
+		{"typo", "tomatoe", []string{"p1", "p3", "p2", "t2"}},
+		{"typo at the start", "tamat", []string{"p1", "p3", "p2", "t2"}},
+		{"every term has to match", "fresh tomatoes", []string{"p1"}},
+		{"services", "cold", []string{"t1"}},
+		{"case", "ONIONS", []string{"p4"}},
+		{"nothing", "bananas", []string{}},
+		{"no terms", "  ,. ", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := m.Search(context.Background(), Query{Text: tt.text})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(result.Hits); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if result.Total != len(tt.want) || result.Partial {
+				t.Errorf("total is %d (partial %v), want %d", result.Total, result.Partial, len(tt.want))
+			}
+			for i := 1; i < len(result.Hits); i++ {
+				if result.Hits[i].Score > result.Hits[i-1].Score {
+					t.Errorf("%s scores more than %s before it", result.Hits[i].ID, result.Hits[i-1].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchShortTermsNeedAPrefix(t *testing.T) {
+	m := index(t)
+
+	// one letter doesn't match as a prefix and terms under 4 letters allow no typo
+	for _, text := range []string{"t", "tmo"} {
+		result, err := m.Search(context.Background(), Query{Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Hits) != 0 {
+			t.Errorf("%q found %v", text, ids(result.Hits))
+		}
+	}
+}
+
+func TestSearchHighlights(t *testing.T) {
+	m := index(t)
+	result, err := m.Search(context.Background(), Query{Text: "onions"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"name": "<em>Onions</em>", "description": "red &lt;b&gt;<em>onions</em>&lt;/b&gt;"}
+	if len(result.Hits) != 1 || !reflect.DeepEqual(result.Hits[0].Highlights, want) {
+		t.Errorf("got %+v, want highlights %v", result.Hits, want)
+	}
+}
+
+func TestSearchFacets(t *testing.T) {
+	m := index(t)
+
+	result, err := m.Search(context.Background(), Query{Text: "tom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFacets := map[string][]FacetCount{
+		FacetSeller:   {{"s1", 2}, {"s2", 1}},
+		FacetServices: {{"express", 1}},
+	}
+	if !reflect.DeepEqual(result.Facets, wantFacets) {
+		t.Errorf("facets are %v, want %v", result.Facets, wantFacets)
+	}
+
+	tests := []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{"seller", Query{Text: "tom", Facets: map[string]string{FacetSeller: "s2"}}, []string{"p3"}},
+		{"service ignores case", Query{Text: "tom", Facets: map[string]string{FacetServices: "EXPRESS"}}, []string{"t2"}},
+		{"kind", Query{Text: "tom", Kinds: []Kind{KindTransport}}, []string{"t2"}},
+		{"facet of another kind", Query{Text: "tom", Kinds: []Kind{KindTransport}, Facets: map[string]string{FacetSeller: "s1"}}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := m.Search(context.Background(), tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(result.Hits); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchPages(t *testing.T) {
+	m := index(t)
+
+	tests := []struct {
+		limit, offset int
+		want          []string
+	}{
+		{2, 0, []string{"p1", "p3"}},
+		{2, 2, []string{"p2", "t2"}},
+		{2, 4, []string{}},
+		{0, 1, []string{"p3", "p2", "t2"}},
+		{2, 10, []string{}},
+	}
+	for _, tt := range tests {
+		result, err := m.Search(context.Background(), Query{Text: "tom", Limit: tt.limit, Offset: tt.offset})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(result.Hits); !reflect.DeepEqual(got, tt.want) || result.Total != 4 {
+			t.Errorf("limit %d offset %d: got %v of %d, want %v of 4", tt.limit, tt.offset, got, result.Total, tt.want)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	m := index(t)
+	if err := m.Remove(context.Background(), KindProduct, "p1"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := m.Search(context.Background(), Query{Text: "tomatoes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(result.Hits); !reflect.DeepEqual(got, []string{"p2", "p3", "t2"}) {
+		t.Errorf("got %v after removing p1", got)
+	}
+}
+
+func TestFacetFilter(t *testing.T) {
+	products := collection{kind: KindProduct, facets: map[string]string{FacetSeller: "sellerId"}}
+
+	filter, ok := facetFilter(products, Query{Facets: map[string]string{FacetSeller: "a.b"}})
+	want := bson.M{"sellerId": bson.M{"$regex": `^a\.b$`, "$options": "i"}}
+	if !ok || !reflect.DeepEqual(filter, want) {
+		t.Errorf("got %v, %v", filter, ok)
+	}
+
+	if _, ok := facetFilter(products, Query{Facets: map[string]string{FacetServices: "express"}}); ok {
+		t.Error("products were searched for a service")
+	}
+}
+
+func TestIndexProducts(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	products := IndexProducts(repository.NewMemory().Products, m)
+
+	product := models.Product{Name: "Tomatoes", SellerId: "s1"}
+	if err := products.Create(ctx, &product); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := products.Update(ctx, product.ID, &models.UpdatePTO{Name: "Onions"}); err != nil {
+		t.Fatal(err)
+	}
+
+	found := func(text string) []string {
+		result, err := m.Search(ctx, Query{Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ids(result.Hits)
+	}
+	if got := found("tomatoes"); len(got) != 0 {
+		t.Errorf("the old name still finds %v", got)
+	}
+	if got := found("onions"); !reflect.DeepEqual(got, []string{product.ID}) {
+		t.Errorf("the new name finds %v", got)
+	}
+
+	if err := products.Delete(ctx, product.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := found("onions"); len(got) != 0 {
+		t.Errorf("the deleted product is still found: %v", got)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"sort"
+
+	"github.com/bmdavis419/fiber-mongo-example/common"
+	"github.com/bmdavis419/fiber-mongo-example/config"
+	"github.com/bmdavis419/fiber-mongo-example/logging"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"github.com/bmdavis419/fiber-mongo-example/seed"
+	"golang.org/x/exp/slog"
+)
+
+// seedDB loads fixture files and random records into a database, e.g.
+//
+//	go run . seed -wipe fixtures/demo.yaml
+//	go run . seed -db go_qa -random 50 -seed 7 fixtures/
+func seedDB(args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	name := flags.String("db", "", "database to fill instead of MONGODB_DATABASE")
+	wipe := flags.Bool("wipe", false, "empty the seeded collections first")
+	random := flags.Int("random", 0, "random records to generate per collection after the fixtures")
+	randomSeed := flags.Int64("seed", 1, "seed of the random records, the same seed generates the same records")
+	flags.Parse(args)
+
+	// read the fixtures before touching the database, a typo shouldn't leave it wiped
+	set := seed.Set{}
+	if flags.NArg() > 0 {
+		var err error
+		set, err = seed.LoadFiles(flags.Args()...)
+		if err != nil {
+			return err
+		}
+	}
+
+	// init config
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if *name != "" {
+		cfg.Mongo.Database = *name
+	}
+
+	// init logging like the api does
+	err = logging.Setup(cfg.Logging)
+	if err != nil {
+		return err
+	}
+
+	// init db
+	err = common.InitDB(cfg.Mongo)
+	if err != nil {
+		return err
+	}
+	defer common.CloseDB()
+
+	// the unique indexes and validators apply to the seeded records as well
+	ctx := context.Background()
+	err = migrateOnStart(ctx, cfg.MigrateOnStart)
+	if err != nil {
+		return err
+	}
+
+	if *wipe {
+		if err := seed.Wipe(ctx, common.GetDB()); err != nil {
+			return err
+		}
+		slog.Info("wiped the database", "database", cfg.Mongo.Database)
+	}
+
+	seeder := seed.New(repository.NewMongo(common.GetDB()))
+	if err := seeder.Load(ctx, set); err != nil {
+		return err
+	}
+	if *random > 0 {
+		if err := seeder.Generate(ctx, *random, *randomSeed); err != nil {
+			return err
+		}
+	}
+
+	colls := make([]string, 0, len(seeder.Created))
+	for coll := range seeder.Created {
+		colls = append(colls, coll)
+	}
+	sort.Strings(colls)
+	for _, coll := range colls {
+		slog.Info("seeded", "collection", coll, "created", seeder.Created[coll])
+	}
+	return nil
+}
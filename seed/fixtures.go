@@ -0,0 +1,188 @@
+// Package seed fills a database with fixture sets and random records for demos and QA.
+package seed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Collections that fixtures can fill, in the order they are created so references point backwards
+var Collections = []string{"users", "books", "products", "transports", "enquiries", "queries"}
+
+// Record is one fixture, the fields are the ones of the JSON api plus:
+//   - ref names the record for references, it defaults to the name, title or email of the record
+//   - password, on users, is hashed into the password hash
+//   - price can also be written as "40.50 INR"
+//
+// A string value "$transports.Fast Freight" is replaced by the id of the transport named that way, "$$" escapes a "$".
+type Record map[string]interface{}
+
+// Set is the fixtures of every collection
+type Set map[string][]Record
+
+// LoadFiles reads the fixture files, .yaml, .yml or .json, a directory is read file by file in name order.
+// Records of the same collection found in several files are appended in that order.
+func LoadFiles(paths ...string) (Set, error) {
+	set := Set{}
+	for _, path := range paths {
+		files, err := fixtureFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			part, err := loadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			for coll, records := range part {
+				set[coll] = append(set[coll], records...)
+			}
+		}
+	}
+	return set, set.check()
+}
+
+func fixtureFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(entries))
+	for _, e := range entries {
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !e.IsDir() {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func loadFile(path string) (Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	set := Set{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &set)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &set)
+	default:
+		err = fmt.Errorf("unknown fixture format %q, expected .yaml, .yml or .json", filepath.Ext(path))
+	}
+	return set, err
+}
+
+// check rejects unknown collections, refs used twice in a collection and references to nothing, so a typo is found
+// before anything is written
+func (s Set) check() error {
+	for coll := range s {
+		if !known(coll) {
+			return fmt.Errorf("unknown fixture collection %q, expected one of %s", coll, strings.Join(Collections, ", "))
+		}
+	}
+
+	ids := map[string]map[string]string{}
+	for _, coll := range Collections {
+		ids[coll] = map[string]string{}
+		for i, r := range s[coll] {
+			if _, err := resolve(map[string]interface{}(r), ids); err != nil {
+				return fmt.Errorf("%s[%d]: %w", coll, i, err)
+			}
+			ref := r.ref(coll)
+			if ref == "" {
+				continue
+			}
+			if _, ok := ids[coll][ref]; ok {
+				return fmt.Errorf("%s[%d]: ref %q is used twice", coll, i, ref)
+			}
+			ids[coll][ref] = ref
+		}
+	}
+	return nil
+}
+
+func known(coll string) bool {
+	for _, c := range Collections {
+		if c == coll {
+			return true
+		}
+	}
+	return false
+}
+
+// ref is the name other fixtures use to point at the record
+func (r Record) ref(coll string) string {
+	keys := map[string]string{"users": "email", "books": "title", "products": "name", "transports": "name", "queries": "name"}
+	for _, key := range []string{"ref", keys[coll]} {
+		if s, ok := r[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// resolve replaces the references in value with the ids in ids, keyed by collection then ref
+func resolve(value interface{}, ids map[string]map[string]string) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "$$") {
+			return v[1:], nil
+		}
+		if !strings.HasPrefix(v, "$") {
+			return v, nil
+		}
+		coll, ref, ok := strings.Cut(v[1:], ".")
+		if !ok || !known(coll) {
+			return nil, fmt.Errorf("bad reference %q, expected $collection.ref", v)
+		}
+		id, ok := ids[coll][ref]
+		if !ok {
+			return nil, fmt.Errorf("reference %q points at no %s created before it", v, coll)
+		}
+		return id, nil
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			r, err := resolve(item, ids)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = r
+		}
+		return resolved, nil
+	case Record:
+		return resolve(map[string]interface{}(v), ids)
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, item := range v {
+			r, err := resolve(item, ids)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = r
+		}
+		return resolved, nil
+	default:
+		return v, nil
+	}
+}
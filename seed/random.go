@@ -0,0 +1,176 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/geo"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+)
+
+// RandomPassword is the password of every generated user, so QA can log in as any of them
+const RandomPassword = "seed-password"
+
+var (
+	firstNames = []string{"Asha", "Ravi", "Meera", "Arjun", "Priya", "Kiran", "Neha", "Vikram", "Lakshmi", "Sanjay"}
+	produce    = []string{"Tomatoes", "Onions", "Potatoes", "Carrots", "Spinach", "Cauliflower", "Okra", "Brinjal", "Cabbage", "Green Chillies"}
+	qualities  = []string{"Fresh", "Organic", "Farm", "Premium", "Hill", "Local"}
+	fleets     = []string{"Freight", "Logistics", "Carriers", "Movers", "Transport", "Haulage"}
+	services   = []string{"refrigerated", "bulk", "express", "door to door", "interstate", "last mile"}
+	cities     = []struct {
+		name     string
+		lat, lng float64
+	}{
+		{"Mumbai", 19.076, 72.8777}, {"Pune", 18.5204, 73.8567}, {"Nashik", 19.9975, 73.7898},
+		{"Bengaluru", 12.9716, 77.5946}, {"Hyderabad", 17.385, 78.4867}, {"Ahmedabad", 23.0225, 72.5714},
+	}
+	authors = []string{"R. K. Narayan", "Ruskin Bond", "Anita Desai", "Vikram Seth", "Kiran Desai", "Amitav Ghosh"}
+	topics  = []string{"Monsoon", "Harvest", "River", "Market", "Village", "Orchard", "Highway"}
+)
+
+// Generate creates n random records in every collection, and n users of every role but admin. The same seed gives the same records, the ids, creation
+// times and delivery dates, which count from today, aside. Products, transports and enquiries also pick the users,
+// products and transports loaded from fixtures before.
+func (s *Seeder) Generate(ctx context.Context, n int, seed int64) error {
+	r := rand.New(rand.NewSource(seed))
+
+	// n users of each role, the emails include the seed so several seeds can share a database
+	for _, role := range []models.Role{models.RoleSeller, models.RoleTransporter, models.RoleBuyer} {
+		for i := 0; i < n; i++ {
+			user := models.User{
+				Email: fmt.Sprintf("%s.%s.%d@seed%d.example.com", strings.ToLower(pick(r, firstNames)), role, i, seed),
+				Role:  role,
+			}
+			if _, err := s.createUser(ctx, user, RandomPassword); err != nil {
+				return fmt.Errorf("%s users[%d]: %w", role, i, err)
+			}
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		book := models.Book{
+			Title:  fmt.Sprintf("The %s %s", pick(r, qualities), pick(r, topics)),
+			Author: pick(r, authors),
+			Year:   fmt.Sprint(1950 + r.Intn(74)),
+		}
+		if err := s.repos.Books.Create(ctx, &book); err != nil {
+			return fmt.Errorf("books[%d]: %w", i, err)
+		}
+		s.Created["books"]++
+	}
+
+	for i := 0; i < n; i++ {
+		sellerId, ok := pickUser(r, s.users[models.RoleSeller])
+		if !ok {
+			return fmt.Errorf("products[%d]: there is no seller to sell them", i)
+		}
+		name := pick(r, produce)
+		product := models.Product{
+			Name:        pick(r, qualities) + " " + name,
+			Description: fmt.Sprintf("%s picked this week, sold by the crate.", name),
+			Price:       money.Money{Amount: int64(1000 + r.Intn(19000)), Currency: money.DefaultCurrency},
+			MinQuantity: 1 + r.Intn(10),
+			SellerId:    sellerId,
+		}
+		if err := s.createProduct(ctx, &product); err != nil {
+			return fmt.Errorf("products[%d]: %w", i, err)
+		}
+		s.Created["products"]++
+	}
+
+	for i := 0; i < n; i++ {
+		ownerId, ok := pickUser(r, s.users[models.RoleTransporter])
+		if !ok {
+			return fmt.Errorf("transports[%d]: there is no transporter to own them", i)
+		}
+		city := cities[r.Intn(len(cities))]
+		location := geo.NewPoint(city.lat+r.Float64()*0.2-0.1, city.lng+r.Float64()*0.2-0.1)
+		transport := models.Transport{
+			Name:          fmt.Sprintf("%s %s", city.name, pick(r, fleets)),
+			Phone:         fmt.Sprintf("+9198%08d", r.Intn(100000000)),
+			Sevices:       []string{pick(r, services), pick(r, services)},
+			Price:         money.Money{Amount: int64(50000 + r.Intn(450000)), Currency: money.DefaultCurrency},
+			MinQuantity:   1 + r.Intn(5),
+			Capacity:      50 + r.Intn(450),
+			DailyCapacity: 100 + r.Intn(900),
+			Address:       city.name,
+			Location:      &location,
+			ServiceAreas:  []geo.Area{{Name: city.name, Center: &location, RadiusKm: float64(50 + r.Intn(250))}},
+			Available:     r.Intn(5) > 0,
+			OwnerId:       ownerId,
+		}
+		if transport.Sevices[0] == transport.Sevices[1] {
+			transport.Sevices = transport.Sevices[:1]
+		}
+		if err := s.createTransport(ctx, &transport); err != nil {
+			return fmt.Errorf("transports[%d]: %w", i, err)
+		}
+		s.Created["transports"]++
+	}
+
+	today := time.Now().UTC()
+	for i := 0; i < n; i++ {
+		buyerId, ok := pickUser(r, s.users[models.RoleBuyer])
+		if !ok || len(s.products) == 0 || len(s.transports) == 0 {
+			return fmt.Errorf("enquiries[%d]: it needs a buyer, a product and a transport", i)
+		}
+		product := s.products[r.Intn(len(s.products))]
+		transport := s.transports[r.Intn(len(s.transports))]
+		city := cities[r.Intn(len(cities))]
+
+		quantity := product.MinQuantity
+		if transport.MinQuantity > quantity {
+			quantity = transport.MinQuantity
+		}
+		quantity += r.Intn(20)
+		if transport.Capacity > 0 && quantity > transport.Capacity {
+			quantity = transport.Capacity
+		}
+
+		location := geo.NewPoint(city.lat, city.lng)
+		enquiry := models.GenerateEnquiry{
+			BuyerId:          buyerId,
+			TransportId:      transport.ID,
+			ProductId:        product.ID,
+			Quantity:         quantity,
+			DeliveryAddress:  city.name,
+			DeliveryLocation: &location,
+			DateOfDelivery:   today.AddDate(0, 0, 1+r.Intn(60)).Format("2006-01-02"),
+		}
+		if err := s.createEnquiry(ctx, &enquiry); err != nil {
+			return fmt.Errorf("enquiries[%d]: %w", i, err)
+		}
+		s.Created["enquiries"]++
+	}
+
+	for i := 0; i < n; i++ {
+		first := pick(r, firstNames)
+		query := models.Query{
+			Name:    first,
+			Email:   fmt.Sprintf("%s.%d@seed%d.example.com", strings.ToLower(first), i, seed),
+			Phone:   fmt.Sprintf("+9197%08d", r.Intn(100000000)),
+			Message: fmt.Sprintf("Do you deliver %s to %s?", strings.ToLower(pick(r, produce)), cities[r.Intn(len(cities))].name),
+		}
+		if err := s.repos.Queries.Create(ctx, &query); err != nil {
+			return fmt.Errorf("queries[%d]: %w", i, err)
+		}
+		s.Created["queries"]++
+	}
+
+	return nil
+}
+
+func pick(r *rand.Rand, words []string) string {
+	return words[r.Intn(len(words))]
+}
+
+func pickUser(r *rand.Rand, ids []string) (string, bool) {
+	if len(ids) == 0 {
+		return "", false
+	}
+	return ids[r.Intn(len(ids))], true
+}
@@ -0,0 +1,235 @@
+package seed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/auth"
+	"github.com/bmdavis419/fiber-mongo-example/geo"
+	"github.com/bmdavis419/fiber-mongo-example/models"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+	"github.com/bmdavis419/fiber-mongo-example/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Seeder creates fixtures and random records through the repositories, so they are stored like the api stores them
+type Seeder struct {
+	repos *repository.Repositories
+	// ids of the created records by collection and ref, for references
+	ids map[string]map[string]string
+	// Created counts the records created by collection, users that already existed are not counted
+	Created map[string]int
+
+	// what Generate picks from, fixtures included
+	users      map[models.Role][]string
+	products   []models.Product
+	transports []models.Transport
+}
+
+func New(repos *repository.Repositories) *Seeder {
+	return &Seeder{
+		repos:   repos,
+		ids:     map[string]map[string]string{},
+		Created: map[string]int{},
+		users:   map[models.Role][]string{},
+	}
+}
+
+// Wipe empties the collections the seeder fills and the ones that depend on them, indexes and validators are kept
+func Wipe(ctx context.Context, db *mongo.Database) error {
+	for _, coll := range []string{"users", "books", "products", "transports", "enquiries", "query", "calendar", "reviews"} {
+		if _, err := db.Collection(coll).DeleteMany(ctx, bson.M{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load creates the fixtures of set collection by collection, a reference can only point at a record created before it
+func (s *Seeder) Load(ctx context.Context, set Set) error {
+	for _, coll := range Collections {
+		for i, record := range set[coll] {
+			if err := s.create(ctx, coll, record); err != nil {
+				return fmt.Errorf("%s[%d] %s: %w", coll, i, record.ref(coll), err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Seeder) create(ctx context.Context, coll string, record Record) error {
+	resolved, err := resolve(map[string]interface{}(record), s.ids)
+	if err != nil {
+		return err
+	}
+	fields := resolved.(map[string]interface{})
+	delete(fields, "ref")
+
+	if price, ok := fields["price"].(string); ok {
+		amount, currency, _ := strings.Cut(strings.TrimSpace(price), " ")
+		m, err := money.Parse(amount, currency)
+		if err != nil {
+			return fmt.Errorf("price %q: %w", price, err)
+		}
+		fields["price"] = m
+	}
+
+	var id string
+	switch coll {
+	case "users":
+		password, _ := fields["password"].(string)
+		delete(fields, "password")
+		var user models.User
+		if err := decode(fields, &user); err != nil {
+			return err
+		}
+		id, err = s.createUser(ctx, user, password)
+	case "books":
+		var book models.Book
+		if err := decode(fields, &book); err != nil {
+			return err
+		}
+		err = s.repos.Books.Create(ctx, &book)
+		id = book.ID
+	case "products":
+		var product models.Product
+		if err := decode(fields, &product); err != nil {
+			return err
+		}
+		err = s.createProduct(ctx, &product)
+		id = product.ID
+	case "transports":
+		var transport models.Transport
+		if err := decode(fields, &transport); err != nil {
+			return err
+		}
+		err = s.createTransport(ctx, &transport)
+		id = transport.ID
+	case "enquiries":
+		var enquiry models.GenerateEnquiry
+		if err := decode(fields, &enquiry); err != nil {
+			return err
+		}
+		err = s.createEnquiry(ctx, &enquiry)
+		id = enquiry.ID
+	case "queries":
+		var query models.Query
+		if err := decode(fields, &query); err != nil {
+			return err
+		}
+		err = s.repos.Queries.Create(ctx, &query)
+		id = query.ID
+	}
+	if err != nil {
+		return err
+	}
+
+	if ref := record.ref(coll); ref != "" {
+		if s.ids[coll] == nil {
+			s.ids[coll] = map[string]string{}
+		}
+		s.ids[coll][ref] = id
+	}
+	if coll != "users" {
+		s.Created[coll]++
+	}
+	return nil
+}
+
+// decode fills v from the fields like a request body would, unknown fields are most likely typos and rejected
+func decode(fields map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// createUser creates the user, a user with the same email is reused so fixtures can be loaded again without -wipe
+func (s *Seeder) createUser(ctx context.Context, user models.User, password string) (string, error) {
+	if user.Role == "" {
+		user.Role = models.RoleBuyer
+	}
+	if len(password) < 8 {
+		return "", errors.New("password must be at least 8 characters")
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return "", err
+	}
+	user.PasswordHash = hash
+	user.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+
+	err = s.repos.Users.Create(ctx, &user)
+	if errors.Is(err, repository.ErrDuplicate) {
+		existing, err := s.repos.Users.GetByEmail(ctx, user.Email)
+		if err != nil {
+			return "", err
+		}
+		user = existing
+	} else if err != nil {
+		return "", err
+	} else {
+		s.Created["users"]++
+	}
+
+	s.users[user.Role] = append(s.users[user.Role], user.ID)
+	return user.ID, nil
+}
+
+func (s *Seeder) createProduct(ctx context.Context, product *models.Product) error {
+	if err := s.repos.Products.Create(ctx, product); err != nil {
+		return err
+	}
+	s.products = append(s.products, *product)
+	return nil
+}
+
+func (s *Seeder) createTransport(ctx context.Context, transport *models.Transport) error {
+	if transport.Sevices == nil {
+		transport.Sevices = []string{}
+	}
+	if transport.ServiceAreas == nil {
+		transport.ServiceAreas = []geo.Area{}
+	}
+	if err := s.repos.Transports.Create(ctx, transport); err != nil {
+		return err
+	}
+	s.transports = append(s.transports, *transport)
+	return nil
+}
+
+// createEnquiry fills in what the api sets when an enquiry is made, enquiries from accepted on are booked on the
+// calendar of their transport without checking its capacity, the fixtures are taken as they are
+func (s *Seeder) createEnquiry(ctx context.Context, enquiry *models.GenerateEnquiry) error {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if enquiry.Status == "" {
+		enquiry.Status = models.StatusRequested
+	}
+	if enquiry.StatusHistory == nil {
+		enquiry.StatusHistory = []models.StatusChange{{To: enquiry.Status, By: enquiry.BuyerId, At: now}}
+	}
+	if enquiry.Quotes == nil {
+		enquiry.Quotes = []models.Quote{}
+	}
+	if enquiry.Booked() && enquiry.DateOfDelivery == "" {
+		return fmt.Errorf("a %s enquiry needs a dateOfDelivery to be booked", enquiry.Status)
+	}
+
+	if err := s.repos.Enquiries.Create(ctx, enquiry); err != nil {
+		return err
+	}
+	if !enquiry.Booked() {
+		return nil
+	}
+	booking := models.Booking{EnquiryId: enquiry.ID, Quantity: enquiry.Quantity, At: now}
+	return s.repos.Calendar.Reserve(ctx, enquiry.TransportId, enquiry.DateOfDelivery, booking, 0, 0)
+}
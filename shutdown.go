@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/bmdavis419/fiber-mongo-example/background"
+	"github.com/bmdavis419/fiber-mongo-example/health"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/exp/slog"
+)
+
+// shutdown reports not ready, waits delay for the load balancer to notice, stops accepting connections, then waits
+// for the requests in flight and after them the background tasks. timeout bounds the wait after delay, what is
+// still running after it is abandoned, the process is stopping anyway. The spans of the requests and tasks are
+// flushed last with what is left of timeout.
+func shutdown(app *fiber.App, tasks *background.Tasks, checks *health.Health, stopTracing func(context.Context) error, delay time.Duration, timeout time.Duration) error {
+	checks.Drain()
+	if delay > 0 {
+		slog.Info("not ready, waiting before closing the listener", "delay", delay)
+		time.Sleep(delay)
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Shutdown closes the listeners and returns once every connection is idle
+	drained := make(chan error, 1)
+	go func() {
+		drained <- app.Shutdown()
+	}()
+	select {
+	case err := <-drained:
+		if err != nil {
+			return err
+		}
+		slog.Info("requests drained", "duration", time.Since(start).Round(time.Millisecond))
+	case <-ctx.Done():
+		slog.Warn("requests still running, stopping without them", "timeout", timeout)
+	}
+
+	if err := tasks.Wait(ctx); err != nil {
+		slog.Warn("background tasks still running were cancelled", "timeout", timeout)
+	} else {
+		slog.Info("background tasks done", "duration", time.Since(start).Round(time.Millisecond))
+	}
+
+	if err := stopTracing(ctx); err != nil {
+		slog.Error("flushing the spans failed", "err", err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type S3Options struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Bucket          string
+	// Prefix is prepended to every key, e.g. "uploads/"
+	Prefix string
+	// BaseURL is where the /media route that serves the objects is, e.g. "/media"
+	BaseURL string
+}
+
+// S3 stores objects in a private S3 bucket, the /media route serves them like the files of Local
+type S3 struct {
+	client   *s3.Client
+	uploader *manager.Uploader
+	bucket   string
+	prefix   string
+	baseURL  string
+}
+
+func NewS3(ctx context.Context, opts S3Options) (*S3, error) {
+	if opts.Region == "" {
+		return nil, errors.New("AWS_REGION environment variable is not set")
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(opts.Region),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(opts.AccessKeyID, opts.SecretAccessKey, "")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS S3 config: %w", err)
+	}
+
+	client := s3.NewFromConfig(cfg)
+	uploader := manager.NewUploader(client, func(u *manager.Uploader) {
+		u.PartSize = 6 * 1024 * 1024 // Override the PartSize to 6 MiB
+	})
+
+	return &S3{
+		client:   client,
+		uploader: uploader,
+		bucket:   opts.Bucket,
+		prefix:   opts.Prefix,
+		baseURL:  strings.TrimSuffix(opts.BaseURL, "/"),
+	}, nil
+}
+
+func (s *S3) key(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return s.prefix + key, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	key, err := s.key(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:             aws.String(s.bucket),
+		Key:                aws.String(key),
+		Body:               body,
+		ContentType:        aws.String(contentType),
+		ContentDisposition: aws.String("inline"), // Set to "inline" to display in the browser
+	})
+
+	return err
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	key, err := s.key(key)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	key, err := s.key(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+
+	return err
+}
+
+func (s *S3) URL(key string) string {
+	return s.baseURL + "/" + strings.TrimPrefix(key, "/")
+}
+
+// Ping writes and deletes a small object, so it fails when the bucket is gone or the credentials can't write to it
+func (s *S3) Ping(ctx context.Context) error {
+	key := aws.String(s.prefix + ".ping")
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    key,
+		Body:   strings.NewReader("ping"),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: key})
+	return err
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/bmdavis419/fiber-mongo-example/config"
+)
+
+// ErrNotFound is returned by Get when there is no object stored under the key
+var ErrNotFound = apperror.New(apperror.KindNotFound, "object not found")
+
+// ObjectStore saves uploaded files, keys are slash separated paths like "products/abc.png"
+type ObjectStore interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL returns the address clients can download the object from
+	URL(key string) string
+	// Ping checks that the store can be reached and written to
+	Ping(ctx context.Context) error
+}
+
+// New creates the object store selected by cfg.Backend, "s3" or "local"
+func New(ctx context.Context, cfg config.ObjectStore) (ObjectStore, error) {
+	switch cfg.Backend {
+	case "s3":
+		return NewS3(ctx, S3Options{
+			Region:          cfg.S3.Region,
+			AccessKeyID:     cfg.S3.AccessKeyID,
+			SecretAccessKey: cfg.S3.SecretAccessKey,
+			Bucket:          cfg.S3.Bucket,
+			Prefix:          cfg.S3.Prefix,
+			BaseURL:         cfg.MediaBaseURL,
+		})
+	case "local":
+		return NewLocal(cfg.MediaDir, cfg.MediaBaseURL)
+	default:
+		return nil, fmt.Errorf("unknown OBJECT_STORE '%s', use 's3' or 'local'", cfg.Backend)
+	}
+}
+
+// KeyOf returns the key of the object store serves at url, ok is false for a url it doesn't serve
+func KeyOf(store ObjectStore, url string) (key string, ok bool) {
+	base := store.URL("")
+	if url == "" || !strings.HasPrefix(url, base) {
+		return "", false
+	}
+	key = strings.TrimPrefix(url, base)
+	if _, err := cleanKey(key); err != nil {
+		return "", false
+	}
+	return key, true
+}
+
+// ContentType guesses the Content-Type of a file from its extension
+func ContentType(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".pdf":
+		return "application/pdf"
+	case ".png":
+		return "image/png"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// IsImage reports whether filename is a raster image that is safe to serve inline, SVG is refused as it can run scripts
+func IsImage(filename string) bool {
+	return strings.HasPrefix(ContentType(filename), "image/")
+}
+
+// cleanKey rejects keys that are empty or would escape the storage root
+func cleanKey(key string) (string, error) {
+	cleaned := filepath.ToSlash(filepath.Clean("/" + key))[1:]
+	if cleaned == "" || cleaned != strings.TrimPrefix(key, "/") {
+		return "", fmt.Errorf("invalid key '%s'", key)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"github.com/bmdavis419/fiber-mongo-example/apperror"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware starts a server span for every request, a child of the traceparent header when the caller sent one.
+// The context of the span is the user context of the request, the handlers pass it on so the MongoDB commands and
+// uploads they make are children of it. It goes before apperror.Middleware so the span ends with the status sent.
+func Middleware() fiber.Handler {
+	tracer := otel.Tracer(name)
+	return func(c *fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{&c.Request().Header})
+
+		// the method and path point into buffers fiber reuses, the span keeps copies
+		method := utils.CopyString(c.Method())
+		ctx, span := tracer.Start(ctx, "HTTP "+method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(method),
+				semconv.HTTPTargetKey.String(string(c.Request().RequestURI())),
+				semconv.HTTPSchemeKey.String(utils.CopyString(c.Protocol())),
+				semconv.NetPeerIPKey.String(c.IP()),
+			),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+		if written := apperror.Written(c); written != nil {
+			span.RecordError(written)
+		}
+
+		// the route is known once a handler matched, requests no route matched keep the method only
+		if route := c.Route().Path; route != "" && route != "/" {
+			span.SetName(method + " " + route)
+			span.SetAttributes(semconv.HTTPRouteKey.String(route))
+		}
+		status := c.Response().StatusCode()
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, fasthttp.StatusMessage(status))
+		}
+		return err
+	}
+}
+
+// headerCarrier reads and writes the trace context in the headers of a request
+type headerCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+func (h headerCarrier) Get(key string) string {
+	return string(h.header.Peek(key))
+}
+
+func (h headerCarrier) Set(key string, value string) {
+	h.header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0)
+	h.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
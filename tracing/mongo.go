@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+)
+
+// CommandMonitor starts a client span for every command sent to MongoDB, a child of the span in the context the
+// command was made with. The client takes a single monitor, the others given are called after it. The commands
+// themselves are left out of the spans, they hold password hashes and personal data.
+func CommandMonitor(others ...*event.CommandMonitor) *event.CommandMonitor {
+	monitors := append([]*event.CommandMonitor{otelmongo.NewMonitor(otelmongo.WithCommandAttributeDisabled(true))}, others...)
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+	"io"
+
+	"github.com/bmdavis419/fiber-mongo-example/storage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ObjectStore wraps store so its reads, writes and deletes are client spans, backend names the store in them
+func ObjectStore(store storage.ObjectStore, backend string) storage.ObjectStore {
+	return &tracedStore{ObjectStore: store, backend: backend, tracer: otel.Tracer(name)}
+}
+
+type tracedStore struct {
+	storage.ObjectStore
+	backend string
+	tracer  trace.Tracer
+}
+
+func (s *tracedStore) Put(ctx context.Context, key string, body io.Reader, contentType string) (err error) {
+	ctx, span := s.start(ctx, "Put", key)
+	defer func() { end(span, err) }()
+	span.SetAttributes(attribute.String("storage.content_type", contentType))
+	return s.ObjectStore.Put(ctx, key, body, contentType)
+}
+
+func (s *tracedStore) Get(ctx context.Context, key string) (body io.ReadCloser, err error) {
+	ctx, span := s.start(ctx, "Get", key)
+	defer func() { end(span, err) }()
+	return s.ObjectStore.Get(ctx, key)
+}
+
+func (s *tracedStore) Delete(ctx context.Context, key string) (err error) {
+	ctx, span := s.start(ctx, "Delete", key)
+	defer func() { end(span, err) }()
+	return s.ObjectStore.Delete(ctx, key)
+}
+
+func (s *tracedStore) start(ctx context.Context, op string, key string) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, s.backend+" "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("storage.backend", s.backend),
+			attribute.String("storage.key", key),
+		),
+	)
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,78 @@
+// Package tracing records OpenTelemetry spans of the requests, the MongoDB commands and the uploads, and sends them
+// to the exporter of the config.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/bmdavis419/fiber-mongo-example/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+)
+
+// name is the instrumentation name of the spans started here
+const name = "github.com/bmdavis419/fiber-mongo-example"
+
+// Setup installs the tracer provider of cfg and the W3C trace context propagator. The returned function flushes the
+// spans not exported yet, call it before the process exits. With the "none" exporter the spans are not recorded,
+// the trace context of incoming requests is still passed on.
+func Setup(ctx context.Context, cfg config.Tracing) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "otlp":
+		exporter, err = otlpExporter(ctx, cfg.Endpoint)
+	default:
+		return nil, fmt.Errorf("unknown TRACING_EXPORTER '%s', use 'none', 'stdout' or 'otlp'", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tracing exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceNameKey.String(cfg.ServiceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// requests that come with a trace context keep the sampling decision of their caller
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// otlpExporter posts the spans to endpoint/v1/traces, like the exporter does with OTEL_EXPORTER_OTLP_ENDPOINT
+func otlpExporter(ctx context.Context, endpoint string) (sdktrace.SpanExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithURLPath(path.Join("/", u.Path, "v1/traces")),
+	}
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(ctx, opts...)
+}
@@ -0,0 +1,168 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bmdavis419/fiber-mongo-example/geo"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+)
+
+// checkFunc returns an error message when value breaks the rule, or an empty string
+type checkFunc func(value reflect.Value, param string) string
+
+var (
+	phonePattern    = regexp.MustCompile(`^\+?[0-9][0-9 ()-]{5,18}[0-9]$`)
+	pricePattern    = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,3})?$`)
+	digitsPattern   = regexp.MustCompile(`^[0-9]+$`)
+	objectIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{24}$`)
+)
+
+var rules = map[string]checkFunc{
+	"min":      checkMin,
+	"max":      checkMax,
+	"len":      checkLen,
+	"oneof":    checkOneOf,
+	"email":    checkString(isEmail, "must be a valid email address"),
+	"phone":    checkString(phonePattern.MatchString, "must be a valid phone number"),
+	"price":    checkString(pricePattern.MatchString, "must be a positive amount with at most 3 decimals"),
+	"currency": checkString(money.Known, "must be a supported ISO 4217 currency code"),
+	"money":    checkMoney,
+	"point":    checkPoint,
+	"areas":    checkAreas,
+	"digits":   checkString(digitsPattern.MatchString, "must only contain digits"),
+	"objectid": checkString(objectIDPattern.MatchString, "must be a valid id"),
+	"date":     checkString(isDate, "must be a date formatted as YYYY-MM-DD"),
+	"readonly": checkReadOnly,
+}
+
+// checkReadOnly refuses any value, it is for fields clients may see but not send
+func checkReadOnly(value reflect.Value, param string) string {
+	return "is read-only"
+}
+
+func isEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s && strings.Contains(s[strings.LastIndex(s, "@"):], ".")
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+// checkMoney accepts money.Money and *money.Money values
+func checkMoney(value reflect.Value, param string) string {
+	m, ok := reflect.Indirect(value).Interface().(money.Money)
+	if !ok {
+		return "must be an amount with a currency"
+	}
+	if !money.Known(m.Currency) {
+		return "must have a supported ISO 4217 currency code"
+	}
+	if m.Amount < 0 {
+		return "must not be negative"
+	}
+	return ""
+}
+
+// checkPoint accepts geo.Point and *geo.Point values
+func checkPoint(value reflect.Value, param string) string {
+	p, ok := reflect.Indirect(value).Interface().(geo.Point)
+	if !ok {
+		return "must be a GeoJSON point"
+	}
+	if err := p.Validate(); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// checkAreas accepts a []geo.Area and reports the first area that isn't valid
+func checkAreas(value reflect.Value, param string) string {
+	areas, ok := value.Interface().([]geo.Area)
+	if !ok {
+		return "must be a list of areas"
+	}
+	for i, a := range areas {
+		if err := a.Validate(); err != nil {
+			return fmt.Sprintf("area %d %s", i, err)
+		}
+	}
+	return ""
+}
+
+func checkString(ok func(string) bool, message string) checkFunc {
+	return func(value reflect.Value, param string) string {
+		if value.Kind() != reflect.String || !ok(value.String()) {
+			return message
+		}
+		return ""
+	}
+}
+
+// size is the number for numbers and the length for strings, slices and maps, pointers are followed
+func size(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		return size(value.Elem())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), " items"
+	}
+	return 0, ""
+}
+
+func checkMin(value reflect.Value, param string) string {
+	n, unit := size(value)
+	if min, _ := strconv.ParseFloat(param, 64); n < min {
+		if unit == "" {
+			return fmt.Sprintf("must be at least %s", param)
+		}
+		return fmt.Sprintf("must have at least %s%s", param, unit)
+	}
+	return ""
+}
+
+func checkMax(value reflect.Value, param string) string {
+	n, unit := size(value)
+	if max, _ := strconv.ParseFloat(param, 64); n > max {
+		if unit == "" {
+			return fmt.Sprintf("must be at most %s", param)
+		}
+		return fmt.Sprintf("must have at most %s%s", param, unit)
+	}
+	return ""
+}
+
+func checkLen(value reflect.Value, param string) string {
+	n, unit := size(value)
+	if l, _ := strconv.ParseFloat(param, 64); n != l {
+		return fmt.Sprintf("must have exactly %s%s", param, unit)
+	}
+	return ""
+}
+
+// checkOneOf takes the allowed values separated by spaces, e.g. oneof=buyer seller
+func checkOneOf(value reflect.Value, param string) string {
+	s := fmt.Sprint(value.Interface())
+	for _, allowed := range strings.Fields(param) {
+		if s == allowed {
+			return ""
+		}
+	}
+	return fmt.Sprintf("must be one of %s", strings.Join(strings.Fields(param), ", "))
+}
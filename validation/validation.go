@@ -0,0 +1,123 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Violation is one broken rule, Field is the name the client sent it as
+type Violation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Validate checks v, a pointer to a struct, against the rules in its `validate` tags, e.g.
+//
+//	Email string `json:"email" validate:"required,email"`
+//
+// Rules other than required are skipped for zero values, so optional fields of update DTOs only
+// get checked when they are sent. dive also checks every struct of a slice, e.g. items[0].name.
+func Validate(v interface{}) []Violation {
+	violations := make([]Violation, 0)
+
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return violations
+	}
+
+	for _, f := range fieldsOf(val.Type()) {
+		value := val.Field(f.index)
+		if value.IsZero() {
+			if f.required {
+				violations = append(violations, Violation{f.name, "required", "is required"})
+			}
+			continue
+		}
+
+		for _, r := range f.rules {
+			if msg := r.check(value, r.param); msg != "" {
+				violations = append(violations, Violation{f.name, r.name, msg})
+				break
+			}
+		}
+
+		if f.dive && value.Kind() == reflect.Slice {
+			for i := 0; i < value.Len(); i++ {
+				for _, v := range Validate(value.Index(i).Interface()) {
+					v.Field = fmt.Sprintf("%s[%d].%s", f.name, i, v.Field)
+					violations = append(violations, v)
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+type boundRule struct {
+	name  string
+	param string
+	check checkFunc
+}
+
+type field struct {
+	index    int
+	name     string
+	required bool
+	dive     bool
+	rules    []boundRule
+}
+
+var cache sync.Map
+
+// fieldsOf parses the tags of t once and caches the result
+func fieldsOf(t reflect.Type) []field {
+	if cached, ok := cache.Load(t); ok {
+		return cached.([]field)
+	}
+
+	fields := make([]field, 0)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		f := field{index: i, name: fieldName(sf)}
+		for _, part := range strings.Split(tag, ",") {
+			name, param, _ := strings.Cut(part, "=")
+			if name == "required" {
+				f.required = true
+				continue
+			}
+			if name == "dive" {
+				f.dive = true
+				continue
+			}
+			check, ok := rules[name]
+			if !ok {
+				panic(fmt.Sprintf("validation: unknown rule '%s' on %s.%s", name, t.Name(), sf.Name))
+			}
+			f.rules = append(f.rules, boundRule{name: name, param: param, check: check})
+		}
+		fields = append(fields, f)
+	}
+
+	cache.Store(t, fields)
+	return fields
+}
+
+// fieldName returns the json, form or query name of the field, which is what clients know it as
+func fieldName(sf reflect.StructField) string {
+	for _, key := range []string{"json", "form", "query"} {
+		name, _, _ := strings.Cut(sf.Tag.Get(key), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
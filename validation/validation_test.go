@@ -0,0 +1,211 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmdavis419/fiber-mongo-example/geo"
+	"github.com/bmdavis419/fiber-mongo-example/money"
+)
+
+// broken lists the violations as field:rule
+func broken(violations []Violation) []string {
+	got := make([]string, len(violations))
+	for i, v := range violations {
+		got[i] = v.Field + ":" + v.Rule
+	}
+	return got
+}
+
+func TestRules(t *testing.T) {
+	type text struct {
+		Value string `json:"value" validate:"min=2,max=4"`
+	}
+	type number struct {
+		Value int `json:"value" validate:"min=1,max=10"`
+	}
+	type pointer struct {
+		Value *int `json:"value" validate:"min=0"`
+	}
+	type list struct {
+		Value []string `json:"value" validate:"max=2"`
+	}
+	type length struct {
+		Value string `json:"value" validate:"len=3"`
+	}
+	type oneOf struct {
+		Value string `json:"value" validate:"oneof=buyer seller"`
+	}
+	type pattern struct {
+		Email    string `json:"email" validate:"email"`
+		Phone    string `json:"phone" validate:"phone"`
+		Price    string `form:"price" validate:"price"`
+		Currency string `json:"currency" validate:"currency"`
+		Digits   string `query:"digits" validate:"digits"`
+		ID       string `json:"id" validate:"objectid"`
+		Date     string `json:"date" validate:"date"`
+	}
+	type typed struct {
+		Price *money.Money `json:"price" validate:"money"`
+		Point *geo.Point   `json:"point" validate:"point"`
+		Areas []geo.Area   `json:"areas" validate:"areas"`
+	}
+	type readOnly struct {
+		Rating float64 `json:"rating" validate:"readonly"`
+	}
+
+	minusOne, zero := -1, 0
+	inside, outside := geo.NewPoint(19.07, 72.87), geo.NewPoint(95, 72.87)
+	price, negative, unknown := money.Money{Amount: 1050, Currency: "INR"}, money.Money{Amount: -1, Currency: "INR"}, money.Money{Amount: 1, Currency: "XYZ"}
+
+	tests := []struct {
+		name string
+		v    interface{}
+		want []string
+	}{
+		{"string in range", &text{"abc"}, []string{}},
+		{"string too short", &text{"a"}, []string{"value:min"}},
+		{"string too long", &text{"abcde"}, []string{"value:max"}},
+		{"string counts runes", &text{"éééé"}, []string{}},
+		{"number in range", &number{10}, []string{}},
+		{"number too small", &number{-1}, []string{"value:min"}},
+		{"number too big", &number{11}, []string{"value:max"}},
+		{"pointer is followed", &pointer{&minusOne}, []string{"value:min"}},
+		{"pointer to zero is checked", &pointer{&zero}, []string{}},
+		{"slice", &list{[]string{"a", "b", "c"}}, []string{"value:max"}},
+		{"len", &length{"ab"}, []string{"value:len"}},
+		{"len matches", &length{"abc"}, []string{}},
+		{"oneof", &oneOf{"admin"}, []string{"value:oneof"}},
+		{"oneof matches", &oneOf{"seller"}, []string{}},
+		{"valid patterns", &pattern{"a@b.io", "+91 99999 99999", "12.50", "INR", "0042", "6ad463259c00b552a94e80ed", "2026-11-02"}, []string{}},
+		{
+			"broken patterns",
+			&pattern{"a@b", "12", "-1", "RUPEES", "4a", "123", "2026-02-30"},
+			[]string{"email:email", "phone:phone", "price:price", "currency:currency", "digits:digits", "id:objectid", "date:date"},
+		},
+		{"valid types", &typed{&price, &inside, []geo.Area{{Name: "Mumbai"}}}, []string{}},
+		{"negative money", &typed{Price: &negative}, []string{"price:money"}},
+		{"unknown currency", &typed{Price: &unknown}, []string{"price:money"}},
+		{"point out of range", &typed{Point: &outside}, []string{"point:point"}},
+		{"empty area", &typed{Areas: []geo.Area{{Name: "Pune"}, {}}}, []string{"areas:areas"}},
+		{"readonly sent", &readOnly{4.5}, []string{"rating:readonly"}},
+		{"readonly not sent", &readOnly{}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := broken(Validate(tt.v)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequired(t *testing.T) {
+	type body struct {
+		Name     string       `json:"name" validate:"required,max=5"`
+		Quantity int          `json:"quantity" validate:"required,min=1"`
+		Price    *money.Money `json:"price" validate:"required,money"`
+		Note     string       `json:"note" validate:"max=5"`
+	}
+	price := money.Money{Amount: 100, Currency: "INR"}
+
+	tests := []struct {
+		name string
+		v    body
+		want []string
+	}{
+		{"everything missing", body{}, []string{"name:required", "quantity:required", "price:required"}},
+		{"everything sent", body{"rice", 2, &price, "ok"}, []string{}},
+		{"the other rules run once sent", body{"basmati", -1, &price, ""}, []string{"name:max", "quantity:min"}},
+		{"zero values skip the other rules", body{Name: "rice", Quantity: 1, Price: &price, Note: ""}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := broken(Validate(&tt.v)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstBrokenRuleOnly(t *testing.T) {
+	type body struct {
+		Code string `json:"code" validate:"digits,len=4"`
+	}
+
+	got := Validate(&body{"abcdef"})
+	if len(got) != 1 || got[0].Rule != "digits" || got[0].Message != "must only contain digits" {
+		t.Errorf("got %+v, want only the digits violation", got)
+	}
+}
+
+func TestDive(t *testing.T) {
+	type item struct {
+		Name   string `json:"name" validate:"required,max=5"`
+		Amount int    `json:"amount" validate:"min=0"`
+	}
+	type body struct {
+		Items []item `json:"items" validate:"required,max=3,dive"`
+	}
+
+	tests := []struct {
+		name string
+		v    body
+		want []string
+	}{
+		{"valid items", body{[]item{{"a", 1}, {"b", 0}}}, []string{}},
+		{"no items", body{}, []string{"items:required"}},
+		{"items are named by index", body{[]item{{"a", 1}, {"", -1}, {"toolong", 0}}}, []string{"items[1].name:required", "items[1].amount:min", "items[2].name:max"}},
+		{"the slice is checked too", body{[]item{{"a", 0}, {"b", 0}, {"c", 0}, {"", 0}}}, []string{"items:max", "items[3].name:required"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := broken(Validate(&tt.v)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldNames(t *testing.T) {
+	type body struct {
+		JSON  string `json:"jsonName,omitempty" validate:"required"`
+		Form  string `form:"formName" validate:"required"`
+		Query string `query:"queryName" validate:"required"`
+		Plain string `validate:"required"`
+		Skip  string `json:"-" form:"skipped" validate:"required"`
+	}
+
+	want := []string{"jsonName:required", "formName:required", "queryName:required", "Plain:required", "skipped:required"}
+	if got := broken(Validate(&body{})); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestValidateNonStruct(t *testing.T) {
+	n := 5
+	if got := Validate(&n); len(got) != 0 {
+		t.Errorf("got %v for a non struct", got)
+	}
+}
+
+func TestUnknownRulePanics(t *testing.T) {
+	type body struct {
+		Name string `json:"name" validate:"required,shiny"`
+	}
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("Validate didn't panic on an unknown rule")
+		}
+		if msg, _ := r.(string); !strings.Contains(msg, "unknown rule 'shiny'") || !strings.Contains(msg, "body.Name") {
+			t.Errorf("panicked with %v", r)
+		}
+	}()
+	Validate(&body{Name: "x"})
+}